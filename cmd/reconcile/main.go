@@ -0,0 +1,80 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	accountInfra "cryptoproject/internal/account/infrastructure"
+	authInfra "cryptoproject/internal/auth/infrastructure"
+	tradingApp "cryptoproject/internal/trading/application"
+	tradingDomain "cryptoproject/internal/trading/domain"
+	tradingInfra "cryptoproject/internal/trading/infrastructure"
+	"cryptoproject/pkg/config"
+	"cryptoproject/pkg/database"
+	"cryptoproject/pkg/logger"
+
+	"github.com/google/uuid"
+)
+
+// reconcile reconstruye las tenencias desde el log de transacciones y reporta cualquier drift
+// contra la tabla holdings. Con -fix reescribe las tenencias de los usuarios afectados.
+//
+//	go run ./cmd/reconcile            # solo reporta
+//	go run ./cmd/reconcile -fix       # reporta y corrige
+//	go run ./cmd/reconcile -user <id> # un solo usuario
+func main() {
+	fix := flag.Bool("fix", false, "reescribe las tenencias con drift usando el historial de transacciones")
+	userFlag := flag.String("user", "", "reconcilia solo este usuario (UUID)")
+	flag.Parse()
+
+	config.LoadConfig()
+	logger.InitLogger()
+
+	db := accountInfra.ConnectDatabase()
+	if err := db.AutoMigrate(&tradingDomain.Holding{}); err != nil {
+		logger.Error("Error ejecutando migraciones:", err)
+		os.Exit(1)
+	}
+
+	reconciler := tradingApp.NewHoldingsReconciler(
+		database.NewUnitOfWork(db),
+		tradingInfra.NewTransactionRepository(db),
+		tradingInfra.NewHoldingRepository(db),
+		authInfra.NewUserRepository(db),
+	)
+
+	var (
+		drifts []tradingApp.HoldingDrift
+		err    error
+	)
+	if *userFlag != "" {
+		userID, parseErr := uuid.Parse(*userFlag)
+		if parseErr != nil {
+			logger.Error("ID de usuario inválido:", parseErr)
+			os.Exit(1)
+		}
+		drifts, err = reconciler.ReconcileUser(userID, *fix)
+	} else {
+		drifts, err = reconciler.ReconcileAll(*fix)
+	}
+
+	for _, drift := range drifts {
+		fmt.Printf("%s\t%s\tguardado=%s\tesperado=%s\tdiferencia=%s\n",
+			drift.UserID, drift.Coin, drift.Stored, drift.Expected, drift.Difference())
+	}
+	if err != nil {
+		logger.Error("Error durante la reconciliación:", err)
+		os.Exit(1)
+	}
+
+	switch {
+	case len(drifts) == 0:
+		logger.Info("Sin drift: las tenencias coinciden con el historial de transacciones")
+	case *fix:
+		logger.Info(fmt.Sprintf("Se corrigieron %d diferencias", len(drifts)))
+	default:
+		logger.Info(fmt.Sprintf("Se encontraron %d diferencias; ejecuta con -fix para corregirlas", len(drifts)))
+		os.Exit(2)
+	}
+}
@@ -1,22 +1,38 @@
 package main
 
 import (
+	"context"
 	accountApp "cryptoproject/internal/account/application"
 	accountInfra "cryptoproject/internal/account/infrastructure"
 	"cryptoproject/internal/auth/application"
 	"cryptoproject/internal/auth/domain"
 	"cryptoproject/internal/auth/infrastructure"
+	idempotencyDomain "cryptoproject/internal/idempotency/domain"
+	idempotencyInfra "cryptoproject/internal/idempotency/infrastructure"
+	ledgerApp "cryptoproject/internal/ledger/application"
+	ledgerDomain "cryptoproject/internal/ledger/domain"
+	ledgerInfra "cryptoproject/internal/ledger/infrastructure"
 	marketApp "cryptoproject/internal/market/application"
 	marketInfra "cryptoproject/internal/market/infrastructure"
+	portfolioApp "cryptoproject/internal/portfolio/application"
+	portfolioDomain "cryptoproject/internal/portfolio/domain"
+	portfolioInfra "cryptoproject/internal/portfolio/infrastructure"
+	reportsApp "cryptoproject/internal/reports/application"
 	"cryptoproject/internal/server"
 	tradingApp "cryptoproject/internal/trading/application"
 	tradingDomain "cryptoproject/internal/trading/domain"
 	tradingInfra "cryptoproject/internal/trading/infrastructure"
 	"cryptoproject/pkg/config"
+	"cryptoproject/pkg/database"
 	"cryptoproject/pkg/logger"
+	"errors"
+	"net/http"
 	"os"
+	"os/signal"
+	"syscall"
 	"time"
 
+	"github.com/shopspring/decimal"
 	"gorm.io/gorm"
 )
 
@@ -34,24 +50,66 @@ func main() {
 
 	jwtService := infrastructure.NewJWTService(os.Getenv("JWT_SECRET"), 24*time.Hour)
 	jwtMiddleware := infrastructure.NewJWTMiddleware(jwtService)
+	idempotencyMiddleware := initializeIdempotencyMiddleware(db)
 
 	authController := initializeAuthController(db, jwtService)
 	registerController := initializeRegisterController(db)
 	marketController := initializeMarketController()
-	tradingController := initializeTradingController(db)
-	accountController := initializeAccountController(db)
+	ledger := initializeLedger(db)
+	feeSchedule, err := initializeFeeSchedule()
+	if err != nil {
+		logger.Error("Error en la configuración de comisiones:", err)
+		return
+	}
+	tradeExecutor := initializeTradeExecutor(db, ledger, feeSchedule)
+	slippageModel := initializeSlippageModel()
+	tradingController := initializeTradingController(db, tradeExecutor, slippageModel)
+	accountController := initializeAccountController(db, ledger)
+	statementController := initializeStatementController(db, ledger)
+	gainsController := initializeGainsController(db)
+	portfolioController := initializePortfolioController(db, ledger)
+	quoteController := initializeQuoteController(db, tradeExecutor, slippageModel)
+	orderMatcher := initializeOrderMatcher(db, tradeExecutor)
+	orderController := initializeOrderController(db, orderMatcher)
+	exitRuleMonitor := initializeExitRuleMonitor(db, tradeExecutor, slippageModel)
+	exitRuleController := initializeExitRuleController(db)
+	recurringScheduler := initializeRecurringScheduler(db, tradeExecutor, slippageModel)
+	recurringController := initializeRecurringController(db)
+	snapshotJob := initializeSnapshotJob(db)
 
-	router := server.SetupRouter(authController, marketController, registerController, tradingController, accountController, jwtMiddleware)
+	// Los workers en segundo plano se detienen cuando el proceso recibe una señal de apagado.
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	go orderMatcher.Start(ctx)
+	go exitRuleMonitor.Start(ctx)
+	go recurringScheduler.Start(ctx)
+	go snapshotJob.Start(ctx)
+	go idempotencyMiddleware.Start(ctx, time.Hour)
+
+	router := server.SetupRouter(authController, marketController, registerController, tradingController, quoteController, orderController, exitRuleController, recurringController, accountController, statementController, gainsController, portfolioController, jwtMiddleware, idempotencyMiddleware)
 
 	port := os.Getenv("SERVER_PORT")
 	if port == "" {
 		port = "8080"
 	}
 
-	logger.Info("Servidor iniciado en el puerto:", port)
-	if err := router.Run(":" + port); err != nil {
-		logger.Error("Error al iniciar el servidor:", err)
+	srv := &http.Server{Addr: ":" + port, Handler: router}
+	go func() {
+		logger.Info("Servidor iniciado en el puerto:", port)
+		if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			logger.Error("Error al iniciar el servidor:", err)
+			stop()
+		}
+	}()
+
+	// Esperamos la señal de apagado y le damos unos segundos a las solicitudes en curso.
+	<-ctx.Done()
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		logger.Error("Error al apagar el servidor:", err)
 	}
+	logger.Info("Servidor detenido")
 }
 
 // Inicializa la conexión a la base de datos. Asegúrate de configurar bien tu conexión.
@@ -75,7 +133,22 @@ func closeDatabase(db *gorm.DB) {
 func runMigrations(db *gorm.DB) error {
 	logger.Info("Ejecutando migraciones...")
 	// Esta lógica depende de la base de datos que estés usando. Asegúrate de que esté configurada correctamente.
-	return db.AutoMigrate(&domain.User{}, &tradingDomain.Transaction{})
+	return db.AutoMigrate(
+		&domain.User{},
+		&tradingDomain.Transaction{},
+		&tradingDomain.Holding{},
+		&tradingDomain.Order{},
+		&tradingDomain.Quote{},
+		&tradingDomain.ExitRule{},
+		&tradingDomain.ExitRuleEvent{},
+		&tradingDomain.RecurringOrder{},
+		&tradingDomain.RecurringRun{},
+		&ledgerDomain.Account{},
+		&ledgerDomain.Journal{},
+		&ledgerDomain.Entry{},
+		&idempotencyDomain.IdempotencyKey{},
+		&portfolioDomain.PortfolioSnapshot{},
+	)
 }
 
 // Configura el controlador de autenticación.
@@ -84,6 +157,12 @@ func initializeAuthController(db *gorm.DB, jwtService infrastructure.JWTServiceI
 	return application.NewAuthController(jwtService, userRepo)
 }
 
+// Configura el middleware de Idempotency-Key para los endpoints que mueven saldo.
+func initializeIdempotencyMiddleware(db *gorm.DB) *idempotencyInfra.IdempotencyMiddleware {
+	ttl := config.GetDuration("IDEMPOTENCY_KEY_TTL", 24*time.Hour)
+	return idempotencyInfra.NewIdempotencyMiddleware(idempotencyInfra.NewIdempotencyKeyRepository(db), ttl)
+}
+
 // Configura el controlador de registro.
 func initializeRegisterController(db *gorm.DB) *application.RegisterController {
 	userRepo := infrastructure.NewUserRepository(db)
@@ -92,20 +171,138 @@ func initializeRegisterController(db *gorm.DB) *application.RegisterController {
 
 // Configura el controlador de mercado.
 func initializeMarketController() *marketApp.MarketController {
-	coingeckoService := marketInfra.NewCoingeckoService()
-	return marketApp.NewMarketController(coingeckoService)
+	priceProvider := marketInfra.NewPriceProvider()
+	return marketApp.NewMarketController(priceProvider)
+}
+
+// Configura el libro mayor que registra cada cambio de saldo.
+func initializeLedger(db *gorm.DB) *ledgerApp.Ledger {
+	return ledgerApp.NewLedger(ledgerInfra.NewLedgerRepository(db), ledgerInfra.NewBalanceProjection())
+}
+
+// Configura la tabla de comisiones. Cada entorno la ajusta con FEE_TIERS y FEE_FLAT.
+func initializeFeeSchedule() (tradingDomain.FeeSchedule, error) {
+	tiers := config.GetEnv("FEE_TIERS", "0:0.10:0.20,50000:0.08:0.16,250000:0.05:0.10")
+	flat := config.GetEnv("FEE_FLAT", "0")
+	return tradingDomain.ParseFeeSchedule(tiers, flat)
+}
+
+// Configura el ejecutor de operaciones que comparten el controlador de trading y los workers.
+func initializeTradeExecutor(db *gorm.DB, ledger *ledgerApp.Ledger, fees tradingDomain.FeeSchedule) *tradingApp.TradeExecutor {
+	uow := database.NewUnitOfWork(db)
+	transactionRepo := tradingInfra.NewTransactionRepository(db)
+	holdingRepo := tradingInfra.NewHoldingRepository(db)
+	userRepo := infrastructure.NewUserRepository(db)
+	return tradingApp.NewTradeExecutor(uow, transactionRepo, holdingRepo, userRepo, ledger, fees)
+}
+
+// Configura el modelo de deslizamiento de las órdenes a mercado. SLIPPAGE_IMPACT_FACTOR=0 lo apaga.
+func initializeSlippageModel() tradingDomain.SlippageModel {
+	return tradingDomain.SlippageModel{
+		ImpactFactor: decimal.NewFromFloat(config.GetFloat("SLIPPAGE_IMPACT_FACTOR", 0.1)),
+		MaxImpact:    decimal.NewFromFloat(config.GetFloat("SLIPPAGE_MAX_IMPACT", 0.1)),
+	}
 }
 
 // Configura el controlador de trading.
-func initializeTradingController(db *gorm.DB) *tradingApp.TradingController {
+func initializeTradingController(db *gorm.DB, executor *tradingApp.TradeExecutor, slippage tradingDomain.SlippageModel) *tradingApp.TradingController {
 	transactionRepo := tradingInfra.NewTransactionRepository(db)
+	holdingRepo := tradingInfra.NewHoldingRepository(db)
 	userRepo := infrastructure.NewUserRepository(db)
-	coingeckoService := marketInfra.NewCoingeckoService()
-	return tradingApp.NewTradingController(transactionRepo, userRepo, coingeckoService)
+	priceProvider := marketInfra.NewPriceProvider()
+	return tradingApp.NewTradingController(transactionRepo, holdingRepo, userRepo, priceProvider, executor, slippage)
+}
+
+// Configura el controlador de cotizaciones.
+func initializeQuoteController(db *gorm.DB, executor *tradingApp.TradeExecutor, slippage tradingDomain.SlippageModel) *tradingApp.QuoteController {
+	uow := database.NewUnitOfWork(db)
+	quoteRepo := tradingInfra.NewQuoteRepository(db)
+	priceProvider := marketInfra.NewPriceProvider()
+	ttl := config.GetDuration("QUOTE_TTL", 10*time.Second)
+	return tradingApp.NewQuoteController(uow, quoteRepo, executor, priceProvider, slippage, ttl)
+}
+
+// Configura el worker que llena las órdenes límite.
+func initializeOrderMatcher(db *gorm.DB, executor *tradingApp.TradeExecutor) *tradingApp.OrderMatcher {
+	uow := database.NewUnitOfWork(db)
+	orderRepo := tradingInfra.NewOrderRepository(db)
+	priceProvider := marketInfra.NewPriceProvider()
+	interval := config.GetDuration("ORDER_MATCHER_INTERVAL", 15*time.Second)
+	return tradingApp.NewOrderMatcher(uow, orderRepo, executor, priceProvider, interval)
+}
+
+// Configura el controlador de órdenes límite.
+func initializeOrderController(db *gorm.DB, matcher *tradingApp.OrderMatcher) *tradingApp.OrderController {
+	uow := database.NewUnitOfWork(db)
+	orderRepo := tradingInfra.NewOrderRepository(db)
+	priceProvider := marketInfra.NewPriceProvider()
+	return tradingApp.NewOrderController(uow, orderRepo, matcher, priceProvider)
+}
+
+// Configura el worker que dispara los stop-loss y take-profit.
+func initializeExitRuleMonitor(db *gorm.DB, executor *tradingApp.TradeExecutor, slippage tradingDomain.SlippageModel) *tradingApp.ExitRuleMonitor {
+	uow := database.NewUnitOfWork(db)
+	ruleRepo := tradingInfra.NewExitRuleRepository(db)
+	priceProvider := marketInfra.NewPriceProvider()
+	interval := config.GetDuration("EXIT_RULE_MONITOR_INTERVAL", 15*time.Second)
+	return tradingApp.NewExitRuleMonitor(uow, ruleRepo, executor, priceProvider, slippage, interval)
+}
+
+// Configura el controlador de reglas de salida.
+func initializeExitRuleController(db *gorm.DB) *tradingApp.ExitRuleController {
+	uow := database.NewUnitOfWork(db)
+	return tradingApp.NewExitRuleController(uow, tradingInfra.NewExitRuleRepository(db))
+}
+
+// Configura el worker que ejecuta las compras recurrentes.
+func initializeRecurringScheduler(db *gorm.DB, executor *tradingApp.TradeExecutor, slippage tradingDomain.SlippageModel) *tradingApp.RecurringScheduler {
+	uow := database.NewUnitOfWork(db)
+	recurringRepo := tradingInfra.NewRecurringOrderRepository(db)
+	priceProvider := marketInfra.NewPriceProvider()
+	interval := config.GetDuration("RECURRING_SCHEDULER_INTERVAL", time.Minute)
+	return tradingApp.NewRecurringScheduler(uow, recurringRepo, executor, priceProvider, slippage, interval)
+}
+
+// Configura el controlador de compras recurrentes.
+func initializeRecurringController(db *gorm.DB) *tradingApp.RecurringController {
+	return tradingApp.NewRecurringController(database.NewUnitOfWork(db), tradingInfra.NewRecurringOrderRepository(db))
 }
 
 // Configura el controlador de cuentas.
-func initializeAccountController(db *gorm.DB) *accountApp.AccountController {
+func initializeAccountController(db *gorm.DB, ledger *ledgerApp.Ledger) *accountApp.AccountController {
+	uow := database.NewUnitOfWork(db)
+	userRepo := infrastructure.NewUserRepository(db)
+	return accountApp.NewAccountController(uow, userRepo, ledger)
+}
+
+// Configura el controlador del estado de cuenta.
+func initializeStatementController(db *gorm.DB, ledger *ledgerApp.Ledger) *ledgerApp.StatementController {
+	return ledgerApp.NewStatementController(database.NewUnitOfWork(db), ledger)
+}
+
+// Configura el controlador del reporte de ganancias de capital.
+func initializeGainsController(db *gorm.DB) *reportsApp.GainsController {
+	transactionRepo := tradingInfra.NewTransactionRepository(db)
+	priceProvider := marketInfra.NewPriceProvider()
+	return reportsApp.NewGainsController(transactionRepo, priceProvider)
+}
+
+// Configura el controlador de la valuación de cartera y su historial.
+func initializePortfolioController(db *gorm.DB, ledger *ledgerApp.Ledger) *portfolioApp.PortfolioController {
+	userRepo := infrastructure.NewUserRepository(db)
+	holdingRepo := tradingInfra.NewHoldingRepository(db)
+	transactionRepo := tradingInfra.NewTransactionRepository(db)
+	snapshotRepo := portfolioInfra.NewSnapshotRepository(db)
+	priceProvider := marketInfra.NewPriceProvider()
+	return portfolioApp.NewPortfolioController(userRepo, holdingRepo, transactionRepo, snapshotRepo, ledger, priceProvider)
+}
+
+// Configura el worker que guarda las fotos de cartera para el historial de patrimonio.
+func initializeSnapshotJob(db *gorm.DB) *portfolioApp.SnapshotJob {
 	userRepo := infrastructure.NewUserRepository(db)
-	return accountApp.NewAccountController(userRepo)
+	holdingRepo := tradingInfra.NewHoldingRepository(db)
+	snapshotRepo := portfolioInfra.NewSnapshotRepository(db)
+	priceProvider := marketInfra.NewPriceProvider()
+	interval := config.GetDuration("PORTFOLIO_SNAPSHOT_INTERVAL", time.Hour)
+	return portfolioApp.NewSnapshotJob(database.NewUnitOfWork(db), userRepo, holdingRepo, snapshotRepo, priceProvider, interval)
 }
@@ -2,26 +2,42 @@ package application
 
 import (
 	"cryptoproject/internal/auth/domain"
+	ledgerApp "cryptoproject/internal/ledger/application"
+	ledgerDomain "cryptoproject/internal/ledger/domain"
+	tradingDomain "cryptoproject/internal/trading/domain"
+	"cryptoproject/pkg/database"
+	"errors"
 	"net/http"
 
 	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
 )
 
+// validationError envuelve errores de reglas de negocio que deben responderse como 400.
+type validationError struct {
+	err error
+}
+
+func (e *validationError) Error() string { return e.err.Error() }
+
 // AccountController maneja las operaciones relacionadas con el saldo del usuario.
 type AccountController struct {
+	uow      database.UnitOfWork
 	userRepo domain.UserRepository
+	ledger   *ledgerApp.Ledger
 }
 
 // NewAccountController crea una nueva instancia de AccountController.
-// Este constructor inicializa el controlador con el repositorio de usuarios.
-func NewAccountController(userRepo domain.UserRepository) *AccountController {
-	return &AccountController{userRepo: userRepo}
+// Este constructor inicializa el controlador con la unidad de trabajo, el repositorio de usuarios y el libro mayor.
+func NewAccountController(uow database.UnitOfWork, userRepo domain.UserRepository, ledger *ledgerApp.Ledger) *AccountController {
+	return &AccountController{uow: uow, userRepo: userRepo, ledger: ledger}
 }
 
 /*
 HandleAddBalance es el endpoint que se encarga de añadir saldo al usuario.
 Aquí validamos que la solicitud sea válida, recuperamos al usuario, ajustamos el saldo y
-actualizamos la información en la base de datos. Esto podría mejorar agregando auditorías en el futuro.
+registramos el depósito en el libro mayor, que es quien actualiza users.balance.
 */
 
 // HandleAddBalance godoc
@@ -53,26 +69,49 @@ func (ac *AccountController) HandleAddBalance(c *gin.Context) {
 		return
 	}
 
-	// Aquí buscamos al usuario en la base de datos. Si no existe, no podemos continuar.
-	user, err := ac.userRepo.FindByID(userID)
-	if err != nil {
+	/*
+		Todo pasa dentro de una unidad de trabajo: si algo falla a mitad de camino,
+		no queda ningún cambio a medias en la base de datos.
+	*/
+	var user *domain.User
+	err := ac.uow.Do(func(tx *gorm.DB) error {
+		users := ac.userRepo.WithTx(tx)
+
+		// Aquí buscamos y bloqueamos al usuario. Si no existe, no podemos continuar.
+		// El bloqueo evita pisar una compra que esté modificando el saldo al mismo tiempo.
+		// Solo domain.ErrUserNotFound es un 404; cualquier otra falla de la base termina en 500.
+		var err error
+		user, err = users.FindByIDForUpdate(userID)
+		if err != nil {
+			return err
+		}
+
+		// Añadir el saldo. Pendiente: ¿Y si en el futuro necesitamos límites máximos o mínimos?
+		if err := user.AddBalance(request.Amount); err != nil {
+			/*
+				Puede ser interesante registrar más detalles aquí para auditoría.
+				Por ejemplo, quién intentó añadir saldo, desde qué IP, etc.
+			*/
+			return &validationError{err}
+		}
+
+		// El depósito queda como asiento en el libro mayor; el saldo del usuario se proyecta desde ahí.
+		userUUID, err := uuid.Parse(user.ID)
+		if err != nil {
+			return err
+		}
+		return ac.ledger.Post(tx, ledgerDomain.DepositJournal(userUUID, tradingDomain.QuoteAsset, request.Amount))
+	})
+
+	var validationErr *validationError
+	switch {
+	case errors.Is(err, domain.ErrUserNotFound):
 		c.JSON(http.StatusNotFound, gin.H{"error": "Usuario no encontrado"})
 		return
-	}
-
-	// Añadir el saldo. Pendiente: ¿Y si en el futuro necesitamos límites máximos o mínimos?
-	err = user.AddBalance(request.Amount)
-	if err != nil {
-		/*
-			Puede ser interesante registrar más detalles aquí para auditoría.
-			Por ejemplo, quién intentó añadir saldo, desde qué IP, etc.
-		*/
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.As(err, &validationErr):
+		c.JSON(http.StatusBadRequest, gin.H{"error": validationErr.Error()})
 		return
-	}
-
-	// Guardar los cambios en la base de datos.
-	if err := ac.userRepo.Update(user); err != nil {
+	case err != nil:
 		// Un error aquí es crítico. Tal vez deberíamos enviar una alerta en un sistema real.
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error al actualizar el usuario"})
 		return
@@ -1,5 +1,8 @@
 package application
 
+import "github.com/shopspring/decimal"
+
+// AddBalanceRequest acepta el monto como número o como string ("100.50"); se valida en User.AddBalance.
 type AddBalanceRequest struct {
-	Amount float64 `json:"amount" binding:"required,gt=0"`
+	Amount decimal.Decimal `json:"amount" binding:"required" swaggertype:"string" example:"100.00"`
 }
@@ -7,21 +7,26 @@ import (
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
 	"golang.org/x/crypto/bcrypt"
 	"gorm.io/gorm"
 )
 
 // User representa un usuario en el sistema. Nota: el campo CryptoHoldings no se persiste en la base de datos.
+// Los montos son decimal.Decimal (no float64) para que no se acumulen errores de redondeo en cada operación.
 type User struct {
-	ID             string             `gorm:"type:uuid;primaryKey"`
-	Username       string             `gorm:"type:varchar(100);unique;not null"`
-	PasswordHash   string             `gorm:"type:text;not null"`
-	Balance        float64            `gorm:"type:numeric(15,2);default:1000.00"`
-	CryptoHoldings map[string]float64 `gorm:"-"` // Aquí usamos `gorm:"-"` para evitar persistir este campo.
-	CreatedAt      time.Time          `gorm:"type:timestamp;autoCreateTime"`
-	UpdatedAt      time.Time          `gorm:"type:timestamp;autoUpdateTime"`
+	ID             string                     `gorm:"type:uuid;primaryKey"`
+	Username       string                     `gorm:"type:varchar(100);unique;not null"`
+	PasswordHash   string                     `gorm:"type:text;not null"`
+	Balance        decimal.Decimal            `gorm:"type:numeric(15,2);default:1000.00;check:chk_users_balance_non_negative,balance >= 0"`
+	CryptoHoldings map[string]decimal.Decimal `gorm:"-"` // Aquí usamos `gorm:"-"` para evitar persistir este campo.
+	CreatedAt      time.Time                  `gorm:"type:timestamp;autoCreateTime"`
+	UpdatedAt      time.Time                  `gorm:"type:timestamp;autoUpdateTime"`
 }
 
+// balancePlaces son los decimales del saldo en USD, igual que la columna numeric(15,2).
+const balancePlaces int32 = 2
+
 // NewUser crea una nueva instancia de usuario con una contraseña encriptada.
 // Nota: el saldo inicial y las criptomonedas pueden ajustarse si cambian las reglas de negocio.
 func NewUser(username, password string) (*User, error) {
@@ -39,11 +44,11 @@ func NewUser(username, password string) (*User, error) {
 		ID:           id,
 		Username:     username,
 		PasswordHash: string(passwordHash),
-		Balance:      1000.00,
-		CryptoHoldings: map[string]float64{
-			"btc":  0.0,
-			"sol":  0.0,
-			"doge": 0.0, // Ejemplo inicial, pero esto puede configurarse de forma dinámica.
+		Balance:      decimal.NewFromInt(1000),
+		CryptoHoldings: map[string]decimal.Decimal{
+			"btc":  decimal.Zero,
+			"sol":  decimal.Zero,
+			"doge": decimal.Zero, // Ejemplo inicial, pero esto puede configurarse de forma dinámica.
 		},
 	}, nil
 }
@@ -56,50 +61,55 @@ func (u *User) VerifyPassword(password string) error {
 
 // AdjustBalance ajusta el saldo del usuario en USD.
 // Ojo: Si el monto es negativo y el balance no alcanza, retorna un error.
-func (u *User) AdjustBalance(amount float64) error {
-	if u.Balance+amount < 0 {
+// El monto ya tiene que venir redondeado a centavos; no redondeamos aquí para no esconder errores de cálculo.
+// Solo cambia el valor en memoria: lo que queda guardado en users.balance lo escribe el libro mayor.
+func (u *User) AdjustBalance(amount decimal.Decimal) error {
+	if !amount.Equal(amount.Truncate(balancePlaces)) {
+		return fmt.Errorf("el ajuste de saldo admite como máximo %d decimales: %s", balancePlaces, amount)
+	}
+	if u.Balance.Add(amount).IsNegative() {
 		return errors.New("saldo insuficiente en USD")
 	}
-	u.Balance += amount
-	logger.Info(fmt.Sprintf("Saldo ajustado. Nuevo balance: %.2f USD", u.Balance))
+	u.Balance = u.Balance.Add(amount)
+	logger.Info(fmt.Sprintf("Saldo ajustado. Nuevo balance: %s USD", u.Balance.StringFixed(balancePlaces)))
 	return nil
 }
 
 // AdjustCryptoBalance ajusta o inicializa el balance de una criptomoneda.
 // Pendiente: esto podría registrar más detalles, por ejemplo, la operación que generó el cambio.
-func (u *User) AdjustCryptoBalance(crypto string, amount float64) error {
+func (u *User) AdjustCryptoBalance(crypto string, amount decimal.Decimal) error {
 	if currentBalance, exists := u.CryptoHoldings[crypto]; exists {
 		// Si la criptomoneda ya existe, ajustamos el balance.
-		if currentBalance+amount < 0 {
-			return fmt.Errorf("balance insuficiente para %s: actual %.2f, ajuste %.2f", crypto, currentBalance, amount)
+		if currentBalance.Add(amount).IsNegative() {
+			return fmt.Errorf("balance insuficiente para %s: actual %s, ajuste %s", crypto, currentBalance, amount)
 		}
-		u.CryptoHoldings[crypto] += amount
-		logger.Info(fmt.Sprintf("Balance ajustado para %s. Nuevo balance: %.2f", crypto, u.CryptoHoldings[crypto]))
+		u.CryptoHoldings[crypto] = currentBalance.Add(amount)
+		logger.Info(fmt.Sprintf("Balance ajustado para %s. Nuevo balance: %s", crypto, u.CryptoHoldings[crypto]))
 	} else {
 		// Si la criptomoneda no existe, la inicializamos.
-		if amount < 0 {
+		if amount.IsNegative() {
 			return fmt.Errorf("no se puede inicializar balance negativo para %s", crypto)
 		}
 		u.CryptoHoldings[crypto] = amount
-		logger.Info(fmt.Sprintf("Nueva criptomoneda añadida: %s con balance %.2f", crypto, amount))
+		logger.Info(fmt.Sprintf("Nueva criptomoneda añadida: %s con balance %s", crypto, amount))
 	}
 	return nil
 }
 
 // GetCryptoBalance devuelve el balance de una criptomoneda específica.
 // Ojo: Si no existe en el mapa, se retorna un error.
-func (u *User) GetCryptoBalance(crypto string) (float64, error) {
+func (u *User) GetCryptoBalance(crypto string) (decimal.Decimal, error) {
 	if balance, exists := u.CryptoHoldings[crypto]; exists {
 		return balance, nil
 	}
 	logger.Warn(fmt.Sprintf("Intento de acceso a criptomoneda no existente: %s", crypto))
-	return 0, fmt.Errorf("la criptomoneda %s no está en los holdings", crypto)
+	return decimal.Zero, fmt.Errorf("la criptomoneda %s no está en los holdings", crypto)
 }
 
 // IsBalanceSufficient verifica si el usuario tiene saldo suficiente en USD.
 // Simple, pero cumple con lo necesario por ahora.
-func (u *User) IsBalanceSufficient(amount float64) bool {
-	return u.Balance >= amount
+func (u *User) IsBalanceSufficient(amount decimal.Decimal) bool {
+	return u.Balance.GreaterThanOrEqual(amount)
 }
 
 // BeforeCreate es un hook de GORM para generar un UUID antes de insertar un nuevo usuario.
@@ -112,20 +122,34 @@ func (u *User) BeforeCreate(tx *gorm.DB) (err error) {
 }
 
 // AddBalance suma saldo al balance en USD del usuario.
-// Nota: esto podría incluir un registro más detallado para auditorías.
-func (u *User) AddBalance(amount float64) error {
-	if amount <= 0 {
+// Igual que AdjustBalance, solo valida y cambia el valor en memoria; el depósito se registra en el libro mayor.
+func (u *User) AddBalance(amount decimal.Decimal) error {
+	if !amount.IsPositive() {
 		return errors.New("el monto a añadir debe ser positivo")
 	}
-	u.Balance += amount
-	logger.Info(fmt.Sprintf("Se añadió %.2f USD al balance. Nuevo balance: %.2f USD", amount, u.Balance))
+	if !amount.Equal(amount.Truncate(balancePlaces)) {
+		return fmt.Errorf("el monto a añadir admite como máximo %d decimales", balancePlaces)
+	}
+	u.Balance = u.Balance.Add(amount)
+	logger.Info(fmt.Sprintf("Se añadió %s USD al balance. Nuevo balance: %s USD", amount.StringFixed(balancePlaces), u.Balance.StringFixed(balancePlaces)))
 	return nil
 }
 
+// ErrUserNotFound lo devuelve el repositorio cuando no existe la fila del usuario, para distinguirlo de una
+// falla de la base de datos.
+var ErrUserNotFound = errors.New("usuario no encontrado")
+
 // UserRepository define las operaciones básicas para trabajar con usuarios.
 // Esto es bastante estándar, pero se pueden agregar más métodos según sea necesario.
 type UserRepository interface {
 	FindByID(id string) (*User, error)
+	// FindByIDForUpdate bloquea la fila del usuario (SELECT ... FOR UPDATE) hasta que termine la transacción.
+	// Solo tiene sentido dentro de una unidad de trabajo, si no el bloqueo se libera de inmediato.
+	FindByIDForUpdate(id string) (*User, error)
 	FindByUsername(username string) (*User, error)
+	// FindIDs devuelve el ID de todos los usuarios, para los procesos que recorren a cada uno.
+	FindIDs() ([]string, error)
 	Update(user *User) error
+	// WithTx devuelve el mismo repositorio trabajando dentro de la transacción dada (ver database.UnitOfWork).
+	WithTx(tx *gorm.DB) UserRepository
 }
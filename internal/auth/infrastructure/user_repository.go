@@ -4,7 +4,9 @@ import (
 	"cryptoproject/internal/auth/domain"
 	"errors"
 
+	"github.com/shopspring/decimal"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 // GormUserRepository implementa UserRepository utilizando GORM.
@@ -33,7 +35,7 @@ func (r *GormUserRepository) FindByID(id string) (*domain.User, error) {
 	if err := r.DB.First(&user, "id = ?", id).Error; err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			// No encontramos el usuario, no es grave pero devolvemos este error.
-			return nil, errors.New("usuario no encontrado")
+			return nil, domain.ErrUserNotFound
 		}
 		// Aquí algo más grave pasó, devolvemos el error original.
 		return nil, err
@@ -44,7 +46,28 @@ func (r *GormUserRepository) FindByID(id string) (*domain.User, error) {
 		Por si acaso, si no hay nada en los holdings de criptos, lo inicializamos para evitar problemas después.
 	*/
 	if user.CryptoHoldings == nil {
-		user.CryptoHoldings = make(map[string]float64)
+		user.CryptoHoldings = make(map[string]decimal.Decimal)
+	}
+
+	return &user, nil
+}
+
+// FindByIDForUpdate busca un usuario por su ID y bloquea su fila hasta el final de la transacción.
+/*
+Esto es lo que evita el doble gasto: si dos compras del mismo usuario llegan al mismo tiempo,
+la segunda se queda esperando aquí hasta que la primera confirme, y entonces ve el saldo ya descontado.
+*/
+func (r *GormUserRepository) FindByIDForUpdate(id string) (*domain.User, error) {
+	var user domain.User
+	if err := r.DB.Clauses(clause.Locking{Strength: "UPDATE"}).First(&user, "id = ?", id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, domain.ErrUserNotFound
+		}
+		return nil, err
+	}
+
+	if user.CryptoHoldings == nil {
+		user.CryptoHoldings = make(map[string]decimal.Decimal)
 	}
 
 	return &user, nil
@@ -59,7 +82,7 @@ func (r *GormUserRepository) FindByUsername(username string) (*domain.User, erro
 	if err := r.DB.First(&user, "username = ?", username).Error; err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			// Lo mismo, si no lo encuentra devolvemos un error bien bonito.
-			return nil, errors.New("usuario no encontrado")
+			return nil, domain.ErrUserNotFound
 		}
 		// Algo pasó, devolvemos el error para que lo maneje quien llame esta función.
 		return nil, err
@@ -70,12 +93,24 @@ func (r *GormUserRepository) FindByUsername(username string) (*domain.User, erro
 		Otra vez lo mismo, asegurarnos de que no esté vacío. Ojo, esto podría ser optimizado más adelante.
 	*/
 	if user.CryptoHoldings == nil {
-		user.CryptoHoldings = make(map[string]float64)
+		user.CryptoHoldings = make(map[string]decimal.Decimal)
 	}
 
 	return &user, nil
 }
 
+// FindIDs devuelve el ID de todos los usuarios.
+/*
+Solo traemos la columna id; quien recorra los usuarios carga cada uno cuando lo necesite.
+*/
+func (r *GormUserRepository) FindIDs() ([]string, error) {
+	var ids []string
+	if err := r.DB.Model(&domain.User{}).Order("id").Pluck("id", &ids).Error; err != nil {
+		return nil, err
+	}
+	return ids, nil
+}
+
 // Update actualiza un usuario en la base de datos.
 /*
 Aquí actualizamos la información del usuario. Si algo falla, devolvemos el error.
@@ -87,3 +122,11 @@ func (r *GormUserRepository) Update(user *domain.User) error {
 	}
 	return nil
 }
+
+// WithTx devuelve una copia del repositorio que trabaja dentro de la transacción dada.
+/*
+Así el repositorio se une a una unidad de trabajo y sus escrituras se confirman o revierten junto con las demás.
+*/
+func (r *GormUserRepository) WithTx(tx *gorm.DB) domain.UserRepository {
+	return &GormUserRepository{DB: tx}
+}
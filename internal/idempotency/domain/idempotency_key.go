@@ -0,0 +1,88 @@
+package domain
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// MaxKeyLength es el largo máximo aceptado para el header Idempotency-Key.
+const MaxKeyLength = 255
+
+// IdempotencyKey guarda la respuesta de una solicitud para poder devolverla tal cual si el cliente la reintenta.
+// Mientras la solicitud original se está procesando, StatusCode es 0.
+type IdempotencyKey struct {
+	UserID       uuid.UUID `gorm:"type:uuid;primaryKey"`
+	Key          string    `gorm:"type:varchar(255);primaryKey"`
+	Method       string    `gorm:"type:varchar(10);not null"`
+	Path         string    `gorm:"type:text;not null"`
+	RequestHash  string    `gorm:"type:char(64);not null"`
+	StatusCode   int       `gorm:"not null;default:0"`
+	ContentType  string    `gorm:"type:text"`
+	ResponseBody []byte    `gorm:"type:bytea"`
+	CreatedAt    time.Time `gorm:"autoCreateTime"`
+	ExpiresAt    time.Time `gorm:"not null;index"`
+}
+
+// NewIdempotencyKey reserva una clave para una solicitud que todavía no tiene respuesta.
+func NewIdempotencyKey(userID uuid.UUID, key, method, path string, body []byte, ttl time.Duration) *IdempotencyKey {
+	now := time.Now()
+	return &IdempotencyKey{
+		UserID:      userID,
+		Key:         key,
+		Method:      method,
+		Path:        path,
+		RequestHash: HashRequest(method, path, body),
+		CreatedAt:   now,
+		ExpiresAt:   now.Add(ttl),
+	}
+}
+
+// HashRequest resume método, ruta y cuerpo; dos solicitudes con la misma clave deben tener el mismo hash.
+func HashRequest(method, path string, body []byte) string {
+	h := sha256.New()
+	h.Write([]byte(method))
+	h.Write([]byte{0})
+	h.Write([]byte(path))
+	h.Write([]byte{0})
+	h.Write(body)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// Matches indica si la solicitud es la misma que reservó la clave.
+func (k *IdempotencyKey) Matches(method, path string, body []byte) bool {
+	return k.RequestHash == HashRequest(method, path, body)
+}
+
+// IsCompleted indica si ya hay una respuesta guardada.
+func (k *IdempotencyKey) IsCompleted() bool {
+	return k.StatusCode != 0
+}
+
+// IsExpired indica si la clave ya venció y puede reutilizarse.
+func (k *IdempotencyKey) IsExpired(now time.Time) bool {
+	return !now.Before(k.ExpiresAt)
+}
+
+// Complete guarda la respuesta que se devolverá en los reintentos.
+func (k *IdempotencyKey) Complete(statusCode int, contentType string, body []byte) {
+	k.StatusCode = statusCode
+	k.ContentType = contentType
+	k.ResponseBody = body
+}
+
+// IdempotencyKeyRepository define el acceso a las claves de idempotencia.
+type IdempotencyKeyRepository interface {
+	// Reserve inserta la clave si no existe. Devuelve false si otra solicitud ya la tiene.
+	Reserve(key *IdempotencyKey) (bool, error)
+	// Find devuelve nil, nil si la clave no existe.
+	Find(userID uuid.UUID, key string) (*IdempotencyKey, error)
+	Complete(key *IdempotencyKey) error
+	Delete(userID uuid.UUID, key string) error
+	// DeleteExpired borra las claves vencidas y devuelve cuántas borró.
+	DeleteExpired(now time.Time) (int64, error)
+	WithTx(tx *gorm.DB) IdempotencyKeyRepository
+}
@@ -0,0 +1,68 @@
+package infrastructure
+
+import (
+	"cryptoproject/internal/idempotency/domain"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// GormIdempotencyKeyRepository implementa la interfaz IdempotencyKeyRepository usando GORM.
+type GormIdempotencyKeyRepository struct {
+	DB *gorm.DB
+}
+
+// NewIdempotencyKeyRepository crea una nueva instancia de GormIdempotencyKeyRepository.
+func NewIdempotencyKeyRepository(db *gorm.DB) domain.IdempotencyKeyRepository {
+	return &GormIdempotencyKeyRepository{DB: db}
+}
+
+// Reserve inserta la clave. Si otra solicitud la insertó antes, no hace nada y devuelve false.
+func (r *GormIdempotencyKeyRepository) Reserve(key *domain.IdempotencyKey) (bool, error) {
+	result := r.DB.Clauses(clause.OnConflict{DoNothing: true}).Create(key)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected == 1, nil
+}
+
+// Find busca una clave de un usuario.
+func (r *GormIdempotencyKeyRepository) Find(userID uuid.UUID, key string) (*domain.IdempotencyKey, error) {
+	var stored domain.IdempotencyKey
+	err := r.DB.First(&stored, "user_id = ? AND key = ?", userID, key).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &stored, nil
+}
+
+// Complete guarda la respuesta de la solicitud original.
+func (r *GormIdempotencyKeyRepository) Complete(key *domain.IdempotencyKey) error {
+	return r.DB.Model(key).Updates(map[string]interface{}{
+		"status_code":   key.StatusCode,
+		"content_type":  key.ContentType,
+		"response_body": key.ResponseBody,
+	}).Error
+}
+
+// Delete libera una clave.
+func (r *GormIdempotencyKeyRepository) Delete(userID uuid.UUID, key string) error {
+	return r.DB.Where("user_id = ? AND key = ?", userID, key).Delete(&domain.IdempotencyKey{}).Error
+}
+
+// DeleteExpired borra las claves vencidas.
+func (r *GormIdempotencyKeyRepository) DeleteExpired(now time.Time) (int64, error) {
+	result := r.DB.Where("expires_at <= ?", now).Delete(&domain.IdempotencyKey{})
+	return result.RowsAffected, result.Error
+}
+
+// WithTx devuelve una copia del repositorio que trabaja dentro de la transacción dada.
+func (r *GormIdempotencyKeyRepository) WithTx(tx *gorm.DB) domain.IdempotencyKeyRepository {
+	return &GormIdempotencyKeyRepository{DB: tx}
+}
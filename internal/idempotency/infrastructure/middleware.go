@@ -0,0 +1,189 @@
+package infrastructure
+
+import (
+	"bytes"
+	"context"
+	"cryptoproject/internal/idempotency/domain"
+	"cryptoproject/pkg/logger"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// HeaderIdempotencyKey es el header con el que el cliente identifica una solicitud que puede reintentar.
+const HeaderIdempotencyKey = "Idempotency-Key"
+
+// HeaderIdempotentReplayed se agrega a las respuestas que salen de una clave ya guardada.
+const HeaderIdempotentReplayed = "Idempotent-Replayed"
+
+// IdempotencyMiddleware hace que reintentar una solicitud con el mismo Idempotency-Key no la ejecute dos veces.
+// La primera solicitud reserva la clave y guarda su respuesta; las siguientes reciben esa misma respuesta.
+// Va después del middleware JWT porque las claves son por usuario.
+type IdempotencyMiddleware struct {
+	repo domain.IdempotencyKeyRepository
+	ttl  time.Duration
+}
+
+// NewIdempotencyMiddleware crea una nueva instancia de IdempotencyMiddleware.
+func NewIdempotencyMiddleware(repo domain.IdempotencyKeyRepository, ttl time.Duration) *IdempotencyMiddleware {
+	return &IdempotencyMiddleware{repo: repo, ttl: ttl}
+}
+
+// capturingWriter copia en memoria lo que el controlador escribe, para guardarlo junto a la clave.
+type capturingWriter struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (w *capturingWriter) Write(data []byte) (int, error) {
+	w.body.Write(data)
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *capturingWriter) WriteString(s string) (int, error) {
+	w.body.WriteString(s)
+	return w.ResponseWriter.WriteString(s)
+}
+
+// Middleware aplica la idempotencia. Si la solicitud no trae el header, pasa sin cambios.
+func (m *IdempotencyMiddleware) Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		key := c.GetHeader(HeaderIdempotencyKey)
+		if key == "" {
+			c.Next()
+			return
+		}
+		if len(key) > domain.MaxKeyLength {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "El Idempotency-Key es demasiado largo"})
+			return
+		}
+
+		userUUID, err := uuid.Parse(c.GetString("user_id"))
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "ID de usuario inválido"})
+			return
+		}
+
+		// Leemos el cuerpo para el hash y lo dejamos de nuevo en su lugar para el controlador.
+		body, err := io.ReadAll(c.Request.Body)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "No se pudo leer la solicitud"})
+			return
+		}
+		c.Request.Body = io.NopCloser(bytes.NewReader(body))
+
+		method, path := c.Request.Method, c.FullPath()
+		reserved := domain.NewIdempotencyKey(userUUID, key, method, path, body, m.ttl)
+		ok, err := m.reserve(reserved)
+		if err != nil {
+			logger.Error("Error al reservar la clave de idempotencia:", err)
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "No se pudo procesar la solicitud"})
+			return
+		}
+		if !ok {
+			m.replay(c, userUUID, key, method, path, body)
+			return
+		}
+
+		// Solo si el controlador falla con 5xx (o entra en pánico) liberamos la clave para que el cliente pueda reintentar.
+		release := true
+		defer func() {
+			if !release {
+				return
+			}
+			if err := m.repo.Delete(userUUID, key); err != nil {
+				logger.Error("Error al liberar la clave de idempotencia:", err)
+			}
+		}()
+
+		writer := &capturingWriter{ResponseWriter: c.Writer}
+		c.Writer = writer
+		c.Next()
+
+		status := writer.Status()
+		if status >= http.StatusInternalServerError {
+			return
+		}
+		// Con menos de 500 la operación pudo haberse confirmado: la clave no se libera aunque no se pueda guardar
+		// la respuesta. Los reintentos reciben 409 hasta que venza, que es mejor que ejecutar la compra dos veces.
+		release = false
+		reserved.Complete(status, writer.Header().Get("Content-Type"), writer.body.Bytes())
+		if err := m.repo.Complete(reserved); err != nil {
+			logger.Error("Error al guardar la respuesta idempotente, la clave queda reservada hasta que venza:", key, err)
+		}
+	}
+}
+
+// reserve intenta quedarse con la clave. Si la que existe ya venció, la borra y vuelve a intentar una vez.
+func (m *IdempotencyMiddleware) reserve(key *domain.IdempotencyKey) (bool, error) {
+	ok, err := m.repo.Reserve(key)
+	if err != nil || ok {
+		return ok, err
+	}
+
+	existing, err := m.repo.Find(key.UserID, key.Key)
+	if err != nil {
+		return false, err
+	}
+	if existing != nil && !existing.IsExpired(time.Now()) {
+		return false, nil
+	}
+	if existing != nil {
+		if err := m.repo.Delete(key.UserID, key.Key); err != nil {
+			return false, err
+		}
+	}
+	return m.repo.Reserve(key)
+}
+
+// replay responde a un reintento con la respuesta guardada, o con 409 si la clave no corresponde a esta solicitud.
+func (m *IdempotencyMiddleware) replay(c *gin.Context, userID uuid.UUID, key, method, path string, body []byte) {
+	stored, err := m.repo.Find(userID, key)
+	if err != nil {
+		logger.Error("Error al obtener la clave de idempotencia:", err)
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "No se pudo procesar la solicitud"})
+		return
+	}
+	if stored == nil {
+		// La solicitud original terminó con error y liberó la clave justo ahora; el cliente puede reintentar.
+		c.AbortWithStatusJSON(http.StatusConflict, gin.H{"error": "La solicitud original con esta clave falló; se puede reintentar"})
+		return
+	}
+	if !stored.Matches(method, path, body) {
+		c.AbortWithStatusJSON(http.StatusConflict, gin.H{"error": "El Idempotency-Key ya se usó con una solicitud distinta"})
+		return
+	}
+	if !stored.IsCompleted() {
+		c.AbortWithStatusJSON(http.StatusConflict, gin.H{"error": "La solicitud original con esta clave todavía está en curso"})
+		return
+	}
+
+	c.Header(HeaderIdempotentReplayed, "true")
+	c.Data(stored.StatusCode, stored.ContentType, stored.ResponseBody)
+	c.Abort()
+}
+
+// Start borra periódicamente las claves vencidas hasta que se cancele el contexto.
+func (m *IdempotencyMiddleware) Start(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			deleted, err := m.repo.DeleteExpired(time.Now())
+			if err != nil {
+				logger.Error("Error al borrar claves de idempotencia vencidas:", err)
+				continue
+			}
+			if deleted > 0 {
+				logger.Info("Claves de idempotencia vencidas borradas:", deleted)
+			}
+		}
+	}
+}
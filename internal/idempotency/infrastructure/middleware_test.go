@@ -0,0 +1,147 @@
+package infrastructure
+
+import (
+	"cryptoproject/internal/idempotency/domain"
+	"cryptoproject/pkg/logger"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// memoryKeys guarda las claves en memoria. failComplete simula que no se puede guardar la respuesta.
+type memoryKeys struct {
+	mu           sync.Mutex
+	keys         map[string]domain.IdempotencyKey
+	failComplete bool
+}
+
+func newMemoryKeys() *memoryKeys {
+	return &memoryKeys{keys: make(map[string]domain.IdempotencyKey)}
+}
+
+func (m *memoryKeys) Reserve(key *domain.IdempotencyKey) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.keys[key.UserID.String()+key.Key]; ok {
+		return false, nil
+	}
+	m.keys[key.UserID.String()+key.Key] = *key
+	return true, nil
+}
+func (m *memoryKeys) Find(userID uuid.UUID, key string) (*domain.IdempotencyKey, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	stored, ok := m.keys[userID.String()+key]
+	if !ok {
+		return nil, nil
+	}
+	return &stored, nil
+}
+func (m *memoryKeys) Complete(key *domain.IdempotencyKey) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.failComplete {
+		return errors.New("conexión perdida")
+	}
+	m.keys[key.UserID.String()+key.Key] = *key
+	return nil
+}
+func (m *memoryKeys) Delete(userID uuid.UUID, key string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.keys, userID.String()+key)
+	return nil
+}
+func (m *memoryKeys) DeleteExpired(now time.Time) (int64, error)         { return 0, nil }
+func (m *memoryKeys) WithTx(tx *gorm.DB) domain.IdempotencyKeyRepository { return m }
+
+// idempotentRouter arma un POST /trade detrás del middleware; handler decide qué responde y cuenta las ejecuciones.
+func idempotentRouter(repo domain.IdempotencyKeyRepository, userID uuid.UUID, handler gin.HandlerFunc) *gin.Engine {
+	logger.InitLogger()
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(gin.Recovery(), func(c *gin.Context) { c.Set("user_id", userID.String()) })
+	router.POST("/trade", NewIdempotencyMiddleware(repo, time.Hour).Middleware(), handler)
+	return router
+}
+
+func post(router *gin.Engine, key string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, "/trade", strings.NewReader("coin=bitcoin&amount=1"))
+	req.Header.Set(HeaderIdempotencyKey, key)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestIdempotencyMiddleware(t *testing.T) {
+	tests := []struct {
+		name         string
+		status       int  // Lo que responde el controlador la primera vez.
+		panics       bool // El controlador entra en pánico la primera vez.
+		failComplete bool
+		retryStatus  int
+		runs         int // Cuántas veces se ejecuta el controlador entre el original y el reintento.
+	}{
+		{name: "respuesta guardada", status: http.StatusCreated, retryStatus: http.StatusCreated, runs: 1},
+		{name: "error de negocio guardado", status: http.StatusBadRequest, retryStatus: http.StatusBadRequest, runs: 1},
+		{name: "no se pudo guardar la respuesta", status: http.StatusCreated, failComplete: true, retryStatus: http.StatusConflict, runs: 1},
+		{name: "5xx libera la clave", status: http.StatusInternalServerError, retryStatus: http.StatusCreated, runs: 2},
+		{name: "pánico libera la clave", panics: true, retryStatus: http.StatusCreated, runs: 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := newMemoryKeys()
+			repo.failComplete = tt.failComplete
+			runs := 0
+			router := idempotentRouter(repo, uuid.New(), func(c *gin.Context) {
+				runs++
+				if runs == 1 {
+					if tt.panics {
+						panic("falla inesperada")
+					}
+					c.JSON(tt.status, gin.H{"run": runs})
+					return
+				}
+				c.JSON(http.StatusCreated, gin.H{"run": runs})
+			})
+
+			first := post(router, "k-1")
+			retry := post(router, "k-1")
+			if retry.Code != tt.retryStatus {
+				t.Fatalf("reintento = %d %s, se esperaba %d", retry.Code, retry.Body, tt.retryStatus)
+			}
+			if runs != tt.runs {
+				t.Fatalf("el controlador corrió %d veces, se esperaban %d", runs, tt.runs)
+			}
+			if tt.runs == 1 && !tt.failComplete {
+				if retry.Body.String() != first.Body.String() || retry.Header().Get(HeaderIdempotentReplayed) != "true" {
+					t.Fatalf("el reintento no repitió la respuesta: %s vs %s", retry.Body, first.Body)
+				}
+			}
+		})
+	}
+}
+
+// Si la clave desaparece entre Reserve y Find (la original falló y la liberó), el cliente recibe un 409 reintentable.
+func TestIdempotencyReleasedBetweenReserveAndFind(t *testing.T) {
+	repo := &releasedKeys{memoryKeys: newMemoryKeys()}
+	router := idempotentRouter(repo, uuid.New(), func(c *gin.Context) { c.Status(http.StatusCreated) })
+
+	w := post(router, "k-1")
+	if w.Code != http.StatusConflict || !strings.Contains(w.Body.String(), "se puede reintentar") {
+		t.Fatalf("respuesta = %d %s, se esperaba un 409 reintentable", w.Code, w.Body)
+	}
+}
+
+// releasedKeys dice que la clave está tomada pero al buscarla ya no está.
+type releasedKeys struct{ *memoryKeys }
+
+func (r *releasedKeys) Reserve(key *domain.IdempotencyKey) (bool, error) { return false, nil }
@@ -0,0 +1,217 @@
+package application
+
+import (
+	"cryptoproject/internal/ledger/domain"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+)
+
+// Ledger registra asientos de partida doble y mantiene al día las vistas que dependen de ellos.
+// Todo corre dentro de la transacción de quien llama, así el asiento y la operación que lo origina
+// se confirman juntos o no se confirma nada.
+type Ledger struct {
+	repo       domain.LedgerRepository
+	projection domain.Projection
+}
+
+// NewLedger crea una nueva instancia de Ledger.
+func NewLedger(repo domain.LedgerRepository, projection domain.Projection) *Ledger {
+	return &Ledger{repo: repo, projection: projection}
+}
+
+// StatementPage es una página del estado de cuenta de un usuario para un activo.
+type StatementPage struct {
+	Account *domain.Account
+	Entries []domain.Entry
+	Total   int64
+}
+
+// Post valida y registra un asiento. Las cuentas de usuario se bloquean, se les actualiza el saldo corrido
+// y se proyecta el nuevo saldo en users.balance u holdings. Si alguna quedaría en negativo devuelve ErrInsufficientFunds.
+func (l *Ledger) Post(tx *gorm.DB, draft domain.JournalDraft) error {
+	if err := draft.Validate(); err != nil {
+		return err
+	}
+	repo := l.repo.WithTx(tx)
+
+	accounts, err := l.lockAccounts(tx, repo, draft.Postings)
+	if err != nil {
+		return err
+	}
+
+	journal := draft.NewJournal()
+	entries := make([]domain.Entry, 0, len(draft.Postings))
+	for _, posting := range draft.Postings {
+		account := accounts[accountKey(posting.Account, posting.Asset)]
+		entry := domain.Entry{JournalID: journal.ID, AccountID: account.ID, Asset: posting.Asset, Amount: posting.Amount}
+		if posting.Account.TracksBalance() {
+			account.Balance = account.Balance.Add(posting.Amount)
+			if account.Balance.IsNegative() {
+				return domain.ErrInsufficientFunds
+			}
+			entry.BalanceAfter = decimal.NewNullDecimal(account.Balance)
+		}
+		entries = append(entries, entry)
+	}
+
+	if err := repo.SaveJournal(journal, entries); err != nil {
+		return fmt.Errorf("error al registrar el asiento: %w", err)
+	}
+
+	for _, account := range accounts {
+		if !account.Ref().TracksBalance() {
+			continue
+		}
+		if err := repo.UpdateAccount(account); err != nil {
+			return fmt.Errorf("error al actualizar la cuenta: %w", err)
+		}
+		if err := l.projection.Project(tx, account.OwnerID, account.Asset, account.Balance); err != nil {
+			return fmt.Errorf("error al proyectar el saldo: %w", err)
+		}
+	}
+	return nil
+}
+
+// Statement devuelve una página del estado de cuenta, del movimiento más nuevo al más viejo.
+// page empieza en 1. Si la cuenta todavía no existe se abre con el saldo actual del usuario.
+func (l *Ledger) Statement(tx *gorm.DB, userID uuid.UUID, asset string, page, pageSize int) (*StatementPage, error) {
+	repo := l.repo.WithTx(tx)
+	account, err := l.openAccount(tx, repo, domain.UserAccount(userID), asset)
+	if err != nil {
+		return nil, err
+	}
+
+	entries, total, err := repo.FindEntries(account.ID, (page-1)*pageSize, pageSize)
+	if err != nil {
+		return nil, fmt.Errorf("error al obtener los movimientos: %w", err)
+	}
+	return &StatementPage{Account: account, Entries: entries, Total: total}, nil
+}
+
+// History devuelve los movimientos de la cuenta del usuario anteriores a before, del más viejo al más nuevo.
+// Es solo lectura: si la cuenta todavía no existe devuelve nil, y el saldo nunca pasó por el libro mayor.
+func (l *Ledger) History(userID uuid.UUID, asset string, before time.Time) ([]domain.Entry, error) {
+	account, err := l.repo.FindAccount(domain.UserAccount(userID), asset)
+	if err != nil {
+		return nil, fmt.Errorf("error al obtener la cuenta: %w", err)
+	}
+	if account == nil {
+		return nil, nil
+	}
+	entries, err := l.repo.FindEntriesBefore(account.ID, before)
+	if err != nil {
+		return nil, fmt.Errorf("error al obtener los movimientos: %w", err)
+	}
+	return entries, nil
+}
+
+// lockAccounts abre si hace falta cada cuenta que toca el asiento y bloquea las de usuario.
+// Las cuentas se recorren en orden fijo para que dos asientos concurrentes no se bloqueen mutuamente.
+func (l *Ledger) lockAccounts(tx *gorm.DB, repo domain.LedgerRepository, postings []domain.Posting) (map[string]*domain.Account, error) {
+	refs := make(map[string]domain.Posting)
+	for _, posting := range postings {
+		refs[accountKey(posting.Account, posting.Asset)] = posting
+	}
+	keys := make([]string, 0, len(refs))
+	for key := range refs {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	accounts := make(map[string]*domain.Account, len(keys))
+	for _, key := range keys {
+		posting := refs[key]
+		account, err := l.openAccount(tx, repo, posting.Account, posting.Asset)
+		if err != nil {
+			return nil, err
+		}
+		accounts[key] = account
+	}
+	return accounts, nil
+}
+
+// openAccount busca y bloquea la cuenta. Si no existe la crea, y si es de un usuario registra
+// un asiento de apertura con lo que el usuario ya tenía antes del libro mayor (ej. el saldo inicial de 1000 USD).
+// Las cuentas del sistema no llevan saldo, así que no se bloquean: todas las operaciones de todos los usuarios
+// pasan por ellas y se encolarían detrás de la misma fila.
+func (l *Ledger) openAccount(tx *gorm.DB, repo domain.LedgerRepository, ref domain.AccountRef, asset string) (*domain.Account, error) {
+	if !ref.TracksBalance() {
+		return l.openSystemAccount(repo, ref, asset)
+	}
+
+	account, err := repo.FindAccountForUpdate(ref, asset)
+	if err != nil {
+		return nil, fmt.Errorf("error al obtener la cuenta: %w", err)
+	}
+	if account != nil {
+		return account, nil
+	}
+
+	if err := repo.CreateAccount(&domain.Account{Kind: ref.Kind, OwnerID: ref.OwnerID, Asset: asset}); err != nil {
+		return nil, fmt.Errorf("error al crear la cuenta: %w", err)
+	}
+	account, err = repo.FindAccountForUpdate(ref, asset)
+	if err != nil || account == nil {
+		return nil, fmt.Errorf("error al obtener la cuenta: %v", err)
+	}
+	if !account.Balance.IsZero() {
+		return account, nil
+	}
+
+	opening, err := l.projection.Current(tx, ref.OwnerID, asset)
+	if err != nil {
+		return nil, fmt.Errorf("error al leer el saldo inicial: %w", err)
+	}
+	if opening.IsZero() {
+		return account, nil
+	}
+
+	// El asiento de apertura no pasa por Post para no volver a bloquear ni proyectar lo que ya está proyectado.
+	journal := domain.JournalDraft{Type: domain.JournalOpeningBalance, Description: "saldo de apertura"}.NewJournal()
+	external, err := l.openAccount(tx, repo, domain.SystemAccount(domain.AccountExternal), asset)
+	if err != nil {
+		return nil, err
+	}
+	account.Balance = opening
+	entries := []domain.Entry{
+		{JournalID: journal.ID, AccountID: external.ID, Asset: asset, Amount: opening.Neg()},
+		{JournalID: journal.ID, AccountID: account.ID, Asset: asset, Amount: opening, BalanceAfter: decimal.NewNullDecimal(opening)},
+	}
+	if err := repo.SaveJournal(journal, entries); err != nil {
+		return nil, fmt.Errorf("error al registrar el saldo de apertura: %w", err)
+	}
+	if err := repo.UpdateAccount(account); err != nil {
+		return nil, fmt.Errorf("error al actualizar la cuenta: %w", err)
+	}
+	return account, nil
+}
+
+// openSystemAccount busca la cuenta del sistema sin bloquearla y la crea si todavía no existe.
+// CreateAccount no hace nada si otra transacción la creó primero, así que se puede llamar siempre.
+func (l *Ledger) openSystemAccount(repo domain.LedgerRepository, ref domain.AccountRef, asset string) (*domain.Account, error) {
+	account, err := repo.FindAccount(ref, asset)
+	if err != nil {
+		return nil, fmt.Errorf("error al obtener la cuenta: %w", err)
+	}
+	if account != nil {
+		return account, nil
+	}
+
+	if err := repo.CreateAccount(&domain.Account{Kind: ref.Kind, OwnerID: ref.OwnerID, Asset: asset}); err != nil {
+		return nil, fmt.Errorf("error al crear la cuenta: %w", err)
+	}
+	account, err = repo.FindAccount(ref, asset)
+	if err != nil || account == nil {
+		return nil, fmt.Errorf("error al obtener la cuenta: %v", err)
+	}
+	return account, nil
+}
+
+func accountKey(ref domain.AccountRef, asset string) string {
+	return ref.Kind + "/" + ref.OwnerID.String() + "/" + asset
+}
@@ -0,0 +1,128 @@
+package application
+
+import (
+	"cryptoproject/internal/ledger/domain"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+)
+
+// memoryLedger guarda cuentas y movimientos en memoria y anota qué cuentas se bloquearon.
+type memoryLedger struct {
+	accounts map[string]*domain.Account
+	entries  []domain.Entry
+	locked   []string
+}
+
+func newMemoryLedger() *memoryLedger {
+	return &memoryLedger{accounts: make(map[string]*domain.Account)}
+}
+
+func (m *memoryLedger) FindAccountForUpdate(ref domain.AccountRef, asset string) (*domain.Account, error) {
+	m.locked = append(m.locked, accountKey(ref, asset))
+	return m.FindAccount(ref, asset)
+}
+func (m *memoryLedger) FindAccount(ref domain.AccountRef, asset string) (*domain.Account, error) {
+	account, ok := m.accounts[accountKey(ref, asset)]
+	if !ok {
+		return nil, nil
+	}
+	copied := *account
+	return &copied, nil
+}
+func (m *memoryLedger) CreateAccount(account *domain.Account) error {
+	key := accountKey(account.Ref(), account.Asset)
+	if _, ok := m.accounts[key]; !ok {
+		account.ID = uuid.New()
+		m.accounts[key] = account
+	}
+	return nil
+}
+func (m *memoryLedger) UpdateAccount(account *domain.Account) error {
+	m.accounts[accountKey(account.Ref(), account.Asset)].Balance = account.Balance
+	return nil
+}
+func (m *memoryLedger) SaveJournal(journal *domain.Journal, entries []domain.Entry) error {
+	m.entries = append(m.entries, entries...)
+	return nil
+}
+func (m *memoryLedger) FindEntries(accountID uuid.UUID, offset, limit int) ([]domain.Entry, int64, error) {
+	return nil, 0, nil
+}
+func (m *memoryLedger) FindEntriesBefore(accountID uuid.UUID, before time.Time) ([]domain.Entry, error) {
+	return nil, nil
+}
+func (m *memoryLedger) WithTx(tx *gorm.DB) domain.LedgerRepository { return m }
+
+// memoryProjection es la vista de saldos: arranca con opening y guarda lo proyectado.
+type memoryProjection struct {
+	opening   map[string]decimal.Decimal
+	projected map[string]decimal.Decimal
+}
+
+func (p *memoryProjection) Current(tx *gorm.DB, userID uuid.UUID, asset string) (decimal.Decimal, error) {
+	return p.opening[asset], nil
+}
+func (p *memoryProjection) Project(tx *gorm.DB, userID uuid.UUID, asset string, balance decimal.Decimal) error {
+	p.projected[asset] = balance
+	return nil
+}
+
+func TestPostLocksOnlyUserAccounts(t *testing.T) {
+	repo := newMemoryLedger()
+	projection := &memoryProjection{
+		opening:   map[string]decimal.Decimal{"usd": decimal.NewFromInt(1000)},
+		projected: make(map[string]decimal.Decimal),
+	}
+	ledger := NewLedger(repo, projection)
+	userID := uuid.New()
+
+	draft := domain.TradeJournal(userID, true, "bitcoin", decimal.NewFromInt(2), "usd", decimal.NewFromInt(200), decimal.NewFromInt(1), "tx-1")
+	if err := ledger.Post(nil, draft); err != nil {
+		t.Fatalf("Post: %v", err)
+	}
+
+	for _, key := range repo.locked {
+		if key != accountKey(domain.UserAccount(userID), "usd") && key != accountKey(domain.UserAccount(userID), "bitcoin") {
+			t.Fatalf("se bloqueó una cuenta del sistema: %s", key)
+		}
+	}
+	for _, kind := range []string{domain.AccountExchange, domain.AccountFees, domain.AccountExternal} {
+		if account, _ := repo.FindAccount(domain.SystemAccount(kind), "usd"); account == nil {
+			t.Fatalf("no se creó la cuenta del sistema %s/usd", kind)
+		}
+	}
+	if !projection.projected["usd"].Equal(decimal.NewFromInt(799)) || !projection.projected["bitcoin"].Equal(decimal.NewFromInt(2)) {
+		t.Fatalf("saldos proyectados = %v, se esperaba usd 799 y bitcoin 2", projection.projected)
+	}
+
+	// Una segunda operación reusa las cuentas del sistema que ya existen.
+	before := len(repo.accounts)
+	if err := ledger.Post(nil, domain.TradeJournal(userID, false, "bitcoin", decimal.NewFromInt(1), "usd", decimal.NewFromInt(110), decimal.Zero, "tx-2")); err != nil {
+		t.Fatalf("Post: %v", err)
+	}
+	if len(repo.accounts) != before {
+		t.Fatalf("se crearon %d cuentas de más", len(repo.accounts)-before)
+	}
+	if !projection.projected["usd"].Equal(decimal.NewFromInt(909)) {
+		t.Fatalf("saldo usd = %s, se esperaba 909", projection.projected["usd"])
+	}
+}
+
+func TestPostRejectsOverdraft(t *testing.T) {
+	repo := newMemoryLedger()
+	projection := &memoryProjection{opening: map[string]decimal.Decimal{}, projected: make(map[string]decimal.Decimal)}
+	ledger := NewLedger(repo, projection)
+
+	draft := domain.TradeJournal(uuid.New(), false, "bitcoin", decimal.NewFromInt(1), "usd", decimal.NewFromInt(100), decimal.Zero, "tx-1")
+	if err := ledger.Post(nil, draft); !errors.Is(err, domain.ErrInsufficientFunds) {
+		t.Fatalf("error = %v, se esperaba ErrInsufficientFunds", err)
+	}
+	if len(projection.projected) != 0 {
+		t.Fatalf("se proyectaron saldos de un asiento rechazado: %v", projection.projected)
+	}
+}
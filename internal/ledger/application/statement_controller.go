@@ -0,0 +1,120 @@
+package application
+
+import (
+	"cryptoproject/pkg/database"
+	"cryptoproject/pkg/logger"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+)
+
+const (
+	defaultPageSize = 20
+	maxPageSize     = 100
+)
+
+// StatementController expone el estado de cuenta que sale del libro mayor.
+type StatementController struct {
+	uow    database.UnitOfWork
+	ledger *Ledger
+}
+
+// NewStatementController crea una nueva instancia de StatementController.
+func NewStatementController(uow database.UnitOfWork, ledger *Ledger) *StatementController {
+	return &StatementController{uow: uow, ledger: ledger}
+}
+
+// statementLine es una línea del estado de cuenta: débito y crédito por separado y el saldo después del movimiento.
+type statementLine struct {
+	ID          uint64          `json:"id"`
+	Date        time.Time       `json:"date"`
+	Type        string          `json:"type"`
+	Description string          `json:"description"`
+	Reference   string          `json:"reference,omitempty"`
+	Debit       decimal.Decimal `json:"debit"`
+	Credit      decimal.Decimal `json:"credit"`
+	Balance     decimal.Decimal `json:"balance"`
+}
+
+// HandleStatement godoc
+// @Summary Estado de cuenta
+// @Description Devuelve los movimientos del libro mayor del usuario para un activo, del más nuevo al más viejo, con el saldo corrido.
+// @Tags Account
+// @Produce json
+// @Param Authorization header string true "Token JWT" default(Bearer <token>)
+// @Param asset query string false "Activo (usd por defecto, o el id de la moneda)"
+// @Param page query int false "Página, empieza en 1"
+// @Param page_size query int false "Movimientos por página (máx. 100)"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /account/statement [get]
+func (sc *StatementController) HandleStatement(c *gin.Context) {
+	userUUID, err := uuid.Parse(c.GetString("user_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID de usuario inválido"})
+		return
+	}
+
+	asset := strings.ToLower(c.DefaultQuery("asset", "usd"))
+	page, err := strconv.Atoi(c.DefaultQuery("page", "1"))
+	if err != nil || page < 1 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "La página es inválida"})
+		return
+	}
+	pageSize, err := strconv.Atoi(c.DefaultQuery("page_size", strconv.Itoa(defaultPageSize)))
+	if err != nil || pageSize < 1 || pageSize > maxPageSize {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "El tamaño de página es inválido"})
+		return
+	}
+
+	// Corre en una unidad de trabajo porque la primera consulta puede abrir la cuenta con su saldo inicial.
+	var statement *StatementPage
+	err = sc.uow.Do(func(tx *gorm.DB) error {
+		var err error
+		statement, err = sc.ledger.Statement(tx, userUUID, asset, page, pageSize)
+		return err
+	})
+	if err != nil {
+		logger.Error("Error al obtener el estado de cuenta:", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error al obtener el estado de cuenta"})
+		return
+	}
+
+	lines := make([]statementLine, 0, len(statement.Entries))
+	for _, entry := range statement.Entries {
+		line := statementLine{
+			ID:      entry.ID,
+			Date:    entry.CreatedAt,
+			Debit:   decimal.Zero,
+			Credit:  decimal.Zero,
+			Balance: entry.BalanceAfter.Decimal,
+		}
+		if entry.Journal != nil {
+			line.Type = entry.Journal.Type
+			line.Description = entry.Journal.Description
+			line.Reference = entry.Journal.Reference
+		}
+		if entry.Amount.IsPositive() {
+			line.Debit = entry.Amount
+		} else {
+			line.Credit = entry.Amount.Neg()
+		}
+		lines = append(lines, line)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"asset":     asset,
+		"balance":   statement.Account.Balance,
+		"page":      page,
+		"page_size": pageSize,
+		"total":     statement.Total,
+		"entries":   lines,
+	})
+}
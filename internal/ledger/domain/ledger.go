@@ -0,0 +1,221 @@
+package domain
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+)
+
+// Tipos de cuenta del libro mayor.
+const (
+	AccountUser     = "user"     // Cuenta de un usuario; su saldo es el que ve el usuario.
+	AccountExchange = "exchange" // Contraparte de las operaciones (la "casa" del simulador).
+	AccountExternal = "external" // Origen del dinero que entra al sistema (depósitos, saldos iniciales).
+	AccountFees     = "fees"     // Comisiones cobradas.
+)
+
+// Tipos de asiento.
+const (
+	JournalOpeningBalance = "opening_balance" // Saldo previo al libro mayor (usuarios nuevos o históricos).
+	JournalDeposit        = "deposit"
+	JournalTrade          = "trade"
+)
+
+// ErrInsufficientFunds se devuelve cuando un asiento dejaría en negativo la cuenta de un usuario.
+var ErrInsufficientFunds = errors.New("fondos insuficientes en el libro mayor")
+
+// AccountRef identifica una cuenta sin necesidad de tenerla cargada. OwnerID es uuid.Nil en las cuentas del sistema.
+type AccountRef struct {
+	Kind    string
+	OwnerID uuid.UUID
+}
+
+// UserAccount devuelve la referencia a la cuenta de un usuario.
+func UserAccount(userID uuid.UUID) AccountRef {
+	return AccountRef{Kind: AccountUser, OwnerID: userID}
+}
+
+// SystemAccount devuelve la referencia a una cuenta del sistema.
+func SystemAccount(kind string) AccountRef {
+	return AccountRef{Kind: kind}
+}
+
+// TracksBalance indica si la cuenta lleva saldo corrido. Las del sistema no, para no bloquear
+// la misma fila en cada operación de cada usuario; su saldo se puede sumar desde los movimientos.
+func (r AccountRef) TracksBalance() bool {
+	return r.Kind == AccountUser
+}
+
+// Account es una cuenta del libro mayor para un activo (usd, bitcoin, ...).
+type Account struct {
+	ID        uuid.UUID       `gorm:"type:uuid;primaryKey"`
+	Kind      string          `gorm:"type:varchar(20);not null;uniqueIndex:idx_ledger_accounts_owner_asset"`
+	OwnerID   uuid.UUID       `gorm:"type:uuid;not null;uniqueIndex:idx_ledger_accounts_owner_asset"`
+	Asset     string          `gorm:"type:text;not null;uniqueIndex:idx_ledger_accounts_owner_asset"`
+	Balance   decimal.Decimal `gorm:"type:numeric;not null;default:0"`
+	CreatedAt time.Time       `gorm:"autoCreateTime"`
+	UpdatedAt time.Time       `gorm:"autoUpdateTime"`
+}
+
+// TableName fija el nombre de la tabla para no chocar con otros "accounts".
+func (Account) TableName() string { return "ledger_accounts" }
+
+// Ref devuelve la referencia de la cuenta.
+func (a *Account) Ref() AccountRef {
+	return AccountRef{Kind: a.Kind, OwnerID: a.OwnerID}
+}
+
+// Journal es un asiento: el porqué de un grupo de movimientos que siempre suma cero por activo.
+type Journal struct {
+	ID          uuid.UUID `gorm:"type:uuid;primaryKey"`
+	Type        string    `gorm:"type:varchar(30);not null"`
+	Reference   string    `gorm:"type:text;index"` // Ej. el ID de la transacción de trading que lo originó.
+	Description string    `gorm:"type:text"`
+	CreatedAt   time.Time `gorm:"autoCreateTime"`
+}
+
+// TableName fija el nombre de la tabla.
+func (Journal) TableName() string { return "ledger_journals" }
+
+// Entry es un movimiento sobre una cuenta. Amount positivo aumenta el saldo de la cuenta (débito en una cuenta de activo)
+// y negativo lo disminuye (crédito). BalanceAfter solo se llena en cuentas que llevan saldo corrido.
+type Entry struct {
+	ID           uint64              `gorm:"primaryKey;autoIncrement"`
+	JournalID    uuid.UUID           `gorm:"type:uuid;not null;index"`
+	AccountID    uuid.UUID           `gorm:"type:uuid;not null;index:idx_ledger_entries_account_id_id"`
+	Asset        string              `gorm:"type:text;not null"`
+	Amount       decimal.Decimal     `gorm:"type:numeric;not null"`
+	BalanceAfter decimal.NullDecimal `gorm:"type:numeric"`
+	CreatedAt    time.Time           `gorm:"autoCreateTime"`
+	Journal      *Journal            `gorm:"foreignKey:JournalID"`
+}
+
+// TableName fija el nombre de la tabla.
+func (Entry) TableName() string { return "ledger_entries" }
+
+// Posting es una línea de un asiento todavía no registrado.
+type Posting struct {
+	Account AccountRef
+	Asset   string
+	Amount  decimal.Decimal
+}
+
+// JournalDraft es un asiento por registrar. Validate garantiza la partida doble antes de tocar la base.
+type JournalDraft struct {
+	Type        string
+	Reference   string
+	Description string
+	Postings    []Posting
+}
+
+// Validate revisa que el asiento tenga movimientos distintos de cero y que cada activo sume exactamente cero.
+func (d JournalDraft) Validate() error {
+	if len(d.Postings) < 2 {
+		return errors.New("un asiento necesita al menos dos movimientos")
+	}
+
+	sums := make(map[string]decimal.Decimal)
+	for _, posting := range d.Postings {
+		if posting.Amount.IsZero() {
+			return fmt.Errorf("movimiento en cero para %s/%s", posting.Account.Kind, posting.Asset)
+		}
+		sums[posting.Asset] = sums[posting.Asset].Add(posting.Amount)
+	}
+
+	assets := make([]string, 0, len(sums))
+	for asset := range sums {
+		assets = append(assets, asset)
+	}
+	sort.Strings(assets)
+	for _, asset := range assets {
+		if !sums[asset].IsZero() {
+			return fmt.Errorf("asiento descuadrado en %s: suma %s", asset, sums[asset])
+		}
+	}
+	return nil
+}
+
+// Transfer agrega al asiento el par de movimientos que mueven amount de un activo desde una cuenta a otra.
+func (d *JournalDraft) Transfer(from, to AccountRef, asset string, amount decimal.Decimal) {
+	asset = strings.ToLower(asset)
+	d.Postings = append(d.Postings,
+		Posting{Account: from, Asset: asset, Amount: amount.Neg()},
+		Posting{Account: to, Asset: asset, Amount: amount},
+	)
+}
+
+// NewJournal crea el registro del asiento a partir del borrador.
+func (d JournalDraft) NewJournal() *Journal {
+	return &Journal{
+		ID:          uuid.New(),
+		Type:        d.Type,
+		Reference:   d.Reference,
+		Description: d.Description,
+		CreatedAt:   time.Now(),
+	}
+}
+
+// DepositJournal arma el asiento de un depósito en USD: entra desde la cuenta externa a la del usuario.
+func DepositJournal(userID uuid.UUID, asset string, amount decimal.Decimal) JournalDraft {
+	draft := JournalDraft{Type: JournalDeposit, Description: "depósito de saldo"}
+	draft.Transfer(SystemAccount(AccountExternal), UserAccount(userID), asset, amount)
+	return draft
+}
+
+// TradeJournal arma el asiento de una compra o venta contra la cuenta del exchange.
+// Compra: USD del usuario al exchange y cripto del exchange al usuario. Venta: al revés.
+// La comisión, si la hay, va aparte del usuario a la cuenta de comisiones.
+func TradeJournal(userID uuid.UUID, buy bool, coin string, quantity decimal.Decimal, quoteAsset string, total, fee decimal.Decimal, reference string) JournalDraft {
+	user, exchange := UserAccount(userID), SystemAccount(AccountExchange)
+	draft := JournalDraft{Type: JournalTrade, Reference: reference}
+	if buy {
+		draft.Description = fmt.Sprintf("compra de %s %s", quantity, coin)
+		draft.Transfer(user, exchange, quoteAsset, total)
+		draft.Transfer(exchange, user, coin, quantity)
+	} else {
+		draft.Description = fmt.Sprintf("venta de %s %s", quantity, coin)
+		draft.Transfer(exchange, user, quoteAsset, total)
+		draft.Transfer(user, exchange, coin, quantity)
+	}
+	if fee.IsPositive() {
+		draft.Transfer(user, SystemAccount(AccountFees), quoteAsset, fee)
+	}
+	return draft
+}
+
+// BeforeCreate es un hook de GORM que genera el ID si no viene.
+func (a *Account) BeforeCreate(tx *gorm.DB) (err error) {
+	if a.ID == uuid.Nil {
+		a.ID = uuid.New()
+	}
+	return
+}
+
+// LedgerRepository define el acceso a cuentas, asientos y movimientos.
+type LedgerRepository interface {
+	// FindAccountForUpdate busca y bloquea una cuenta. Devuelve nil, nil si no existe.
+	FindAccountForUpdate(ref AccountRef, asset string) (*Account, error)
+	FindAccount(ref AccountRef, asset string) (*Account, error)
+	// CreateAccount crea la cuenta si no existe; si ya existe no hace nada.
+	CreateAccount(account *Account) error
+	UpdateAccount(account *Account) error
+	SaveJournal(journal *Journal, entries []Entry) error
+	// FindEntries devuelve los movimientos de una cuenta del más nuevo al más viejo, con su asiento.
+	FindEntries(accountID uuid.UUID, offset, limit int) ([]Entry, int64, error)
+	// FindEntriesBefore devuelve los movimientos de una cuenta anteriores a before, del más viejo al más nuevo, con su asiento.
+	FindEntriesBefore(accountID uuid.UUID, before time.Time) ([]Entry, error)
+	WithTx(tx *gorm.DB) LedgerRepository
+}
+
+// Projection mantiene las vistas que se derivan del libro mayor (users.balance, holdings.amount).
+// Current se usa para abrir la cuenta con el saldo que ya existía antes del libro mayor.
+type Projection interface {
+	Current(tx *gorm.DB, userID uuid.UUID, asset string) (decimal.Decimal, error)
+	Project(tx *gorm.DB, userID uuid.UUID, asset string, balance decimal.Decimal) error
+}
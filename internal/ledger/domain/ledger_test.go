@@ -0,0 +1,93 @@
+package domain
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+)
+
+func TestJournalDraftValidate(t *testing.T) {
+	user, exchange := UserAccount(uuid.New()), SystemAccount(AccountExchange)
+	d := decimal.RequireFromString
+	tests := []struct {
+		name     string
+		postings []Posting
+		err      string // Parte del mensaje esperado; vacío si el asiento es válido.
+	}{
+		{name: "cuadrado", postings: []Posting{
+			{Account: user, Asset: "usd", Amount: d("-10.5")},
+			{Account: exchange, Asset: "usd", Amount: d("10.5")},
+		}},
+		{name: "cuadrado por activo", postings: []Posting{
+			{Account: user, Asset: "usd", Amount: d("-100")},
+			{Account: exchange, Asset: "usd", Amount: d("100")},
+			{Account: exchange, Asset: "bitcoin", Amount: d("-0.001")},
+			{Account: user, Asset: "bitcoin", Amount: d("0.001")},
+		}},
+		{name: "un solo movimiento", postings: []Posting{
+			{Account: user, Asset: "usd", Amount: d("10")},
+		}, err: "al menos dos"},
+		{name: "movimiento en cero", postings: []Posting{
+			{Account: user, Asset: "usd", Amount: d("0")},
+			{Account: exchange, Asset: "usd", Amount: d("0")},
+		}, err: "en cero"},
+		{name: "descuadrado por un centavo", postings: []Posting{
+			{Account: user, Asset: "usd", Amount: d("-10")},
+			{Account: exchange, Asset: "usd", Amount: d("10.01")},
+		}, err: "descuadrado en usd"},
+		{name: "suma cero entre activos distintos", postings: []Posting{
+			{Account: user, Asset: "usd", Amount: d("-1")},
+			{Account: exchange, Asset: "bitcoin", Amount: d("1")},
+		}, err: "descuadrado en bitcoin"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := JournalDraft{Postings: tt.postings}.Validate()
+			if tt.err == "" {
+				if err != nil {
+					t.Fatalf("Validate: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Fatalf("error = %v, se esperaba uno con %q", err, tt.err)
+			}
+		})
+	}
+}
+
+func TestTradeJournal(t *testing.T) {
+	userID := uuid.New()
+	d := decimal.RequireFromString
+	tests := []struct {
+		name string
+		buy  bool
+		fee  string
+		usd  string // Cuánto cambia el USD del usuario.
+		coin string // Cuánto cambia el bitcoin del usuario.
+	}{
+		{name: "compra", buy: true, fee: "0", usd: "-200", coin: "2"},
+		{name: "compra con comisión", buy: true, fee: "1.5", usd: "-201.5", coin: "2"},
+		{name: "venta con comisión", buy: false, fee: "1.5", usd: "198.5", coin: "-2"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			draft := TradeJournal(userID, tt.buy, "Bitcoin", d("2"), "usd", d("200"), d(tt.fee), "tx-1")
+			if err := draft.Validate(); err != nil {
+				t.Fatalf("el asiento no cuadra: %v", err)
+			}
+
+			net := make(map[string]decimal.Decimal)
+			for _, posting := range draft.Postings {
+				if posting.Account == UserAccount(userID) {
+					net[posting.Asset] = net[posting.Asset].Add(posting.Amount)
+				}
+			}
+			if !net["usd"].Equal(d(tt.usd)) || !net["bitcoin"].Equal(d(tt.coin)) {
+				t.Fatalf("el usuario cambia usd %s y bitcoin %s; se esperaba %s y %s", net["usd"], net["bitcoin"], tt.usd, tt.coin)
+			}
+		})
+	}
+}
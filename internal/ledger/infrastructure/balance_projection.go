@@ -0,0 +1,54 @@
+package infrastructure
+
+import (
+	authDomain "cryptoproject/internal/auth/domain"
+	tradingDomain "cryptoproject/internal/trading/domain"
+	"errors"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// BalanceProjection mantiene users.balance (USD) y holdings.amount (cripto) como proyección del libro mayor.
+// Cada vez que se registra un asiento, el saldo de la cuenta del usuario se copia a la vista correspondiente.
+type BalanceProjection struct{}
+
+// NewBalanceProjection crea una nueva instancia de BalanceProjection.
+func NewBalanceProjection() *BalanceProjection {
+	return &BalanceProjection{}
+}
+
+// Current devuelve lo que la vista tiene hoy, para abrir la cuenta del libro mayor con ese saldo.
+func (p *BalanceProjection) Current(tx *gorm.DB, userID uuid.UUID, asset string) (decimal.Decimal, error) {
+	if asset == tradingDomain.QuoteAsset {
+		var user authDomain.User
+		if err := tx.Select("balance").First(&user, "id = ?", userID.String()).Error; err != nil {
+			return decimal.Zero, err
+		}
+		return user.Balance, nil
+	}
+
+	var holding tradingDomain.Holding
+	err := tx.Select("amount").First(&holding, "user_id = ? AND coin = ?", userID, asset).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return decimal.Zero, nil
+	}
+	if err != nil {
+		return decimal.Zero, err
+	}
+	return holding.Amount, nil
+}
+
+// Project escribe el saldo de la cuenta del libro mayor en la vista. El costo base de holdings no se toca.
+func (p *BalanceProjection) Project(tx *gorm.DB, userID uuid.UUID, asset string, balance decimal.Decimal) error {
+	if asset == tradingDomain.QuoteAsset {
+		return tx.Model(&authDomain.User{}).Where("id = ?", userID.String()).Update("balance", balance).Error
+	}
+
+	return tx.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "user_id"}, {Name: "coin"}},
+		DoUpdates: clause.AssignmentColumns([]string{"amount", "updated_at"}),
+	}).Create(&tradingDomain.Holding{UserID: userID, Coin: asset, Amount: balance}).Error
+}
@@ -0,0 +1,100 @@
+package infrastructure
+
+import (
+	"cryptoproject/internal/ledger/domain"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// GormLedgerRepository implementa la interfaz LedgerRepository usando GORM.
+type GormLedgerRepository struct {
+	DB *gorm.DB
+}
+
+// NewLedgerRepository crea una nueva instancia de GormLedgerRepository.
+func NewLedgerRepository(db *gorm.DB) domain.LedgerRepository {
+	return &GormLedgerRepository{DB: db}
+}
+
+// FindAccountForUpdate busca una cuenta y bloquea su fila hasta el final de la transacción.
+func (r *GormLedgerRepository) FindAccountForUpdate(ref domain.AccountRef, asset string) (*domain.Account, error) {
+	return r.findAccount(r.DB.Clauses(clause.Locking{Strength: "UPDATE"}), ref, asset)
+}
+
+// FindAccount busca una cuenta sin bloquearla.
+func (r *GormLedgerRepository) FindAccount(ref domain.AccountRef, asset string) (*domain.Account, error) {
+	return r.findAccount(r.DB, ref, asset)
+}
+
+func (r *GormLedgerRepository) findAccount(db *gorm.DB, ref domain.AccountRef, asset string) (*domain.Account, error) {
+	var account domain.Account
+	err := db.First(&account, "kind = ? AND owner_id = ? AND asset = ?", ref.Kind, ref.OwnerID, asset).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &account, nil
+}
+
+// CreateAccount crea una cuenta nueva. Si otra transacción la creó primero no hace nada;
+// quien llama vuelve a buscarla (con bloqueo si es de usuario).
+func (r *GormLedgerRepository) CreateAccount(account *domain.Account) error {
+	return r.DB.Clauses(clause.OnConflict{DoNothing: true}).Create(account).Error
+}
+
+// UpdateAccount persiste el saldo corrido de una cuenta.
+func (r *GormLedgerRepository) UpdateAccount(account *domain.Account) error {
+	return r.DB.Model(account).Update("balance", account.Balance).Error
+}
+
+// SaveJournal guarda el asiento y sus movimientos.
+func (r *GormLedgerRepository) SaveJournal(journal *domain.Journal, entries []domain.Entry) error {
+	if err := r.DB.Create(journal).Error; err != nil {
+		return err
+	}
+	return r.DB.Create(&entries).Error
+}
+
+// FindEntries devuelve una página de movimientos de una cuenta (más nuevos primero) y el total.
+func (r *GormLedgerRepository) FindEntries(accountID uuid.UUID, offset, limit int) ([]domain.Entry, int64, error) {
+	var total int64
+	if err := r.DB.Model(&domain.Entry{}).Where("account_id = ?", accountID).Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var entries []domain.Entry
+	err := r.DB.Preload("Journal").
+		Where("account_id = ?", accountID).
+		Order("id DESC").
+		Offset(offset).
+		Limit(limit).
+		Find(&entries).Error
+	if err != nil {
+		return nil, 0, err
+	}
+	return entries, total, nil
+}
+
+// FindEntriesBefore devuelve los movimientos de una cuenta anteriores a before, en el orden en que se registraron.
+func (r *GormLedgerRepository) FindEntriesBefore(accountID uuid.UUID, before time.Time) ([]domain.Entry, error) {
+	var entries []domain.Entry
+	err := r.DB.Preload("Journal").
+		Where("account_id = ? AND created_at < ?", accountID, before).
+		Order("id ASC").
+		Find(&entries).Error
+	if err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+// WithTx devuelve una copia del repositorio que trabaja dentro de la transacción dada.
+func (r *GormLedgerRepository) WithTx(tx *gorm.DB) domain.LedgerRepository {
+	return &GormLedgerRepository{DB: tx}
+}
@@ -2,26 +2,28 @@ package application
 
 import (
 	"fmt"
+	"math"
 	"net/http"
 	"time"
 
-	"cryptoproject/internal/market/infrastructure"
+	"cryptoproject/internal/market/domain"
+	"cryptoproject/pkg/dates"
 	"cryptoproject/pkg/logger"
 
 	"github.com/gin-gonic/gin"
 )
 
 // MarketController maneja todo lo que tiene que ver con datos de mercado.
-// Ojo, aquí se usan los métodos del servicio CoinGecko para no inventar la rueda.
+// Ojo, aquí se usa el proveedor de precios configurado (CoinGecko, Binance o la cadena de ambos) para no inventar la rueda.
 type MarketController struct {
-	coingeckoService infrastructure.CoingeckoServiceInterface
+	provider domain.PriceProvider
 }
 
 // NewMarketController inicializa el controlador de mercado.
-// Pana, este es el constructor, aquí simplemente conectamos con el servicio.
-func NewMarketController(service infrastructure.CoingeckoServiceInterface) *MarketController {
+// Pana, este es el constructor, aquí simplemente conectamos con el proveedor.
+func NewMarketController(provider domain.PriceProvider) *MarketController {
 	return &MarketController{
-		coingeckoService: service,
+		provider: provider,
 	}
 }
 
@@ -31,19 +33,23 @@ func (mc *MarketController) GetCurrentPriceHandler(c *gin.Context) {
 	crypto := c.Param("id")                       // Esto es el ID de la cripto, tipo "bitcoin" o "ethereum".
 	currency := c.DefaultQuery("currency", "usd") // Por defecto trabajamos con USD, pero se puede cambiar.
 
-	price, err := mc.coingeckoService.GetCurrentPrice(crypto, currency)
-	if err != nil {
+	// El precio puede venir del caché; si CoinGecko está caído se sirve el último conocido y se avisa con "stale".
+	quote, err := mc.provider.GetQuoteContext(c.Request.Context(), crypto, currency)
+	if err != nil && !quote.Stale {
 		logger.Error("Error al obtener el precio actual:", err)
-		// Pendiente aquí: si falla CoinGecko, devolvemos error, pero quizá podríamos poner un cache para no depender tanto.
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "No se pudo obtener el precio actual"})
 		return
 	}
 
-	// Aquí devolvemos la info al cliente.
+	// Aquí devolvemos la info al cliente, con la antigüedad del precio.
 	c.JSON(http.StatusOK, gin.H{
-		"crypto":   crypto,
-		"currency": currency,
-		"price":    price,
+		"crypto":      crypto,
+		"currency":    currency,
+		"price":       quote.Price,
+		"fetched_at":  quote.FetchedAt.UTC(),
+		"age_seconds": math.Round(quote.Age(time.Now()).Seconds()*1000) / 1000,
+		"stale":       quote.Stale,
+		"provider":    quote.Provider,
 	})
 }
 
@@ -69,7 +75,7 @@ func (mc *MarketController) GetHistoricalPricesHandler(c *gin.Context) {
 	}
 
 	// Pedimos los datos históricos al servicio. Esto podría demorar si son muchos días.
-	historicalData, err := mc.coingeckoService.GetHistoricalPrices(cryptoID, fmt.Sprintf("%d", startUnix), fmt.Sprintf("%d", endUnix))
+	historicalData, err := mc.provider.GetHistoricalPricesContext(c.Request.Context(), cryptoID, fmt.Sprintf("%d", startUnix), fmt.Sprintf("%d", endUnix))
 	if err != nil {
 		// Ojo: Si hay problemas aquí, seguro es un tema con la API de CoinGecko o con los datos enviados.
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
@@ -83,18 +89,10 @@ func (mc *MarketController) GetHistoricalPricesHandler(c *gin.Context) {
 // parseDateToUnix convierte una fecha (texto) en un UNIX timestamp.
 // ¡Pendiente! Si alguien manda mal el formato, esto devuelve error de una.
 func parseDateToUnix(date string) (int64, error) {
-	// Intentamos convertir con el formato dd-mm-yyyy.
-	parsedDate, err := time.Parse("02-01-2006", date)
-	if err == nil {
-		return parsedDate.Unix(), nil
-	}
-
-	// Si el primer intento falla, probamos con el formato RFC3339 (ISO).
-	parsedDate, err = time.Parse(time.RFC3339, date)
-	if err == nil {
-		return parsedDate.Unix(), nil
+	// Acepta dd-mm-yyyy o RFC3339 (ISO); los mismos formatos que usa el filtro del historial.
+	parsedDate, err := dates.Parse(date)
+	if err != nil {
+		return 0, err
 	}
-
-	// Aquí no hay más nada que hacer si fallan los dos formatos.
-	return 0, fmt.Errorf("la fecha no cumple con los formatos válidos: dd-mm-yyyy o RFC3339")
+	return parsedDate.Unix(), nil
 }
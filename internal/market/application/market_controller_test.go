@@ -0,0 +1,102 @@
+package application_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"cryptoproject/internal/market/application"
+	"cryptoproject/internal/market/infrastructure"
+	"cryptoproject/pkg/logger"
+
+	"github.com/gin-gonic/gin"
+)
+
+// TestMain apunta CoinGecko a las cassettes del paquete infrastructure antes de que se arme el singleton,
+// así los handlers responden con datos grabados y sin salir a la red.
+func TestMain(m *testing.M) {
+	logger.InitLogger()
+	gin.SetMode(gin.TestMode)
+	os.Setenv("COINGECKO_CASSETTE_MODE", "replay")
+	os.Setenv("COINGECKO_CASSETTE_DIR", "../infrastructure/testdata/cassettes/coingecko")
+	os.Exit(m.Run())
+}
+
+// serve arma el router con las mismas rutas que el servidor y hace el pedido.
+func serve(t *testing.T, target string) *httptest.ResponseRecorder {
+	t.Helper()
+	mc := application.NewMarketController(infrastructure.NewCoingeckoService())
+	router := gin.New()
+	router.GET("/market/:id/price", mc.GetCurrentPriceHandler)
+	router.GET("/market/:id/history", mc.GetHistoricalPricesHandler)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, target, nil))
+	return w
+}
+
+func TestGetCurrentPriceHandler(t *testing.T) {
+	w := serve(t, "/market/bitcoin/price")
+	if w.Code != http.StatusOK {
+		t.Fatalf("estado = %d, se esperaba 200: %s", w.Code, w.Body)
+	}
+	var body struct {
+		Crypto   string  `json:"crypto"`
+		Currency string  `json:"currency"`
+		Price    float64 `json:"price"`
+		Stale    bool    `json:"stale"`
+		Provider string  `json:"provider"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+		t.Fatalf("respuesta inválida: %v", err)
+	}
+	if body.Crypto != "bitcoin" || body.Currency != "usd" || body.Price != 42261.04 || body.Stale || body.Provider != "coingecko" {
+		t.Fatalf("respuesta = %+v", body)
+	}
+}
+
+func TestGetCurrentPriceHandlerUnknownCoin(t *testing.T) {
+	w := serve(t, "/market/notacoin/price")
+	if w.Code != http.StatusInternalServerError {
+		t.Fatalf("estado = %d, se esperaba 500: %s", w.Code, w.Body)
+	}
+}
+
+func TestGetHistoricalPricesHandler(t *testing.T) {
+	tests := []struct {
+		name   string
+		target string
+		status int
+		points int
+	}{
+		{name: "rango grabado", target: "/market/bitcoin/history?start=01-01-2024&end=04-01-2024", status: http.StatusOK, points: 4},
+		{name: "inicio mal formado", target: "/market/bitcoin/history?start=2024/01/01&end=04-01-2024", status: http.StatusBadRequest},
+		{name: "fin mal formado", target: "/market/bitcoin/history?start=01-01-2024&end=ayer", status: http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := serve(t, tt.target)
+			if w.Code != tt.status {
+				t.Fatalf("estado = %d, se esperaba %d: %s", w.Code, tt.status, w.Body)
+			}
+			if tt.status != http.StatusOK {
+				return
+			}
+			var body []struct {
+				Timestamp float64 `json:"timestamp"`
+				Price     float64 `json:"price"`
+			}
+			if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+				t.Fatalf("respuesta inválida: %v", err)
+			}
+			if len(body) != tt.points {
+				t.Fatalf("se obtuvieron %d precios, se esperaban %d", len(body), tt.points)
+			}
+			if body[0].Timestamp != 1704067200000 || body[0].Price != 42261.04 {
+				t.Fatalf("primer precio = %+v", body[0])
+			}
+		})
+	}
+}
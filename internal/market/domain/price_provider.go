@@ -0,0 +1,51 @@
+package domain
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// ErrUnsupportedCoin se devuelve cuando el proveedor no cotiza la moneda o la divisa pedida.
+// No es una falla del proveedor: la cadena de proveedores prueba con el siguiente sin marcarlo como caído.
+var ErrUnsupportedCoin = errors.New("moneda no soportada o no disponible")
+
+// PriceQuote es un precio con el momento en que se lo pidió al proveedor.
+// Stale indica que se sirvió vencido desde un caché porque se estaba refrescando o porque el proveedor no respondió.
+type PriceQuote struct {
+	Price     float64
+	Volume24h float64
+	FetchedAt time.Time
+	Stale     bool
+	Provider  string // Quién dio el precio (coingecko, binance, ...).
+}
+
+// Age devuelve la antigüedad del precio.
+func (q PriceQuote) Age(now time.Time) time.Duration {
+	return now.Sub(q.FetchedAt)
+}
+
+// PriceProvider es una fuente de datos de mercado. Las monedas se identifican con los ids de CoinGecko
+// (bitcoin, solana, ...) y las divisas en minúscula (usd, eur); cada proveedor traduce a sus propios símbolos.
+// Los métodos ...Context cortan el pedido cuando se cancela ctx (ej. el cliente HTTP cortó la conexión);
+// los que no lo reciben usan context.Background(). Cada proveedor puede sumar su propio tope de espera.
+type PriceProvider interface {
+	// Name identifica al proveedor en logs y métricas.
+	Name() string
+	GetCurrentPrice(crypto string, currency string) (float64, error)
+	GetPriceWithVolume(crypto string, currency string) (price float64, volume24h float64, err error)
+	// GetQuote es como GetCurrentPrice pero dice cuándo se obtuvo el precio y si se sirvió vencido.
+	// Si el proveedor no respondió y sirvió el vencido de su caché devuelve las dos cosas: la cotización con Stale
+	// y el error, así quien lleva la salud del proveedor lo cuenta como falla.
+	GetQuote(crypto string, currency string) (PriceQuote, error)
+	GetQuoteContext(ctx context.Context, crypto string, currency string) (PriceQuote, error)
+	// GetPrices pide varias monedas en varias divisas de una vez. Devuelve moneda -> divisa -> precio;
+	// las monedas que el proveedor no conoce no aparecen en el mapa.
+	GetPrices(cryptos []string, currencies []string) (map[string]map[string]float64, error)
+	GetPricesContext(ctx context.Context, cryptos []string, currencies []string) (map[string]map[string]float64, error)
+	// GetHistoricalPrices devuelve precios en USD entre start y end (timestamps UNIX en segundos), como
+	// {"timestamp": milisegundos, "price": precio}, del más viejo al más nuevo.
+	GetHistoricalPrices(crypto string, start string, end string) ([]map[string]interface{}, error)
+	GetHistoricalPricesContext(ctx context.Context, crypto string, start string, end string) ([]map[string]interface{}, error)
+	CheckAPIStatus() bool
+}
@@ -0,0 +1,347 @@
+package infrastructure
+
+import (
+	"context"
+	"cryptoproject/internal/market/domain"
+	"cryptoproject/pkg/logger"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// binanceName es el nombre del proveedor en MARKET_PROVIDERS, logs y métricas.
+const binanceName = "binance"
+
+// binanceKlineLimit es el máximo de velas que devuelve Binance por pedido.
+const binanceKlineLimit = 1000
+
+// defaultBinanceSymbols traduce los ids de CoinGecko a los símbolos base de Binance.
+// BINANCE_SYMBOLS ("id:SIMBOLO,...") agrega o pisa entradas.
+var defaultBinanceSymbols = map[string]string{
+	"bitcoin":     "BTC",
+	"ethereum":    "ETH",
+	"solana":      "SOL",
+	"dogecoin":    "DOGE",
+	"cardano":     "ADA",
+	"ripple":      "XRP",
+	"binancecoin": "BNB",
+	"litecoin":    "LTC",
+	"polkadot":    "DOT",
+	"tron":        "TRX",
+	"chainlink":   "LINK",
+	"avalanche-2": "AVAX",
+}
+
+// binanceInvalidSymbol es el código de error con el que Binance responde 400 a un par que no existe.
+const binanceInvalidSymbol = -1121
+
+// binanceQuotes traduce las divisas a la moneda de cotización de Binance. USD se cotiza contra USDT,
+// que sigue al dólar lo bastante de cerca para usarlo de respaldo.
+var binanceQuotes = map[string]string{
+	"usd": "USDT",
+	"eur": "EUR",
+	"try": "TRY",
+	"brl": "BRL",
+}
+
+// BinanceService es un PriceProvider que usa la API REST pública de Binance (no necesita API key).
+// Se usa como respaldo de CoinGecko en la cadena de proveedores.
+type BinanceService struct {
+	baseURL string
+	client  *http.Client
+	symbols map[string]string
+	mu      sync.Mutex
+	invalid map[string]bool // Pares que armamos con symbols pero Binance no lista; se dejan de pedir en lote.
+}
+
+// NewBinanceService crea el adaptador de Binance con BINANCE_BASE_URL, BINANCE_TIMEOUT y BINANCE_SYMBOLS.
+func NewBinanceService() domain.PriceProvider {
+	timeout, err := time.ParseDuration(getEnv("BINANCE_TIMEOUT", "5s"))
+	if err != nil {
+		logger.Warn("Error al parsear BINANCE_TIMEOUT, usando valor por defecto: 5s")
+		timeout = 5 * time.Second
+	}
+
+	symbols := make(map[string]string, len(defaultBinanceSymbols))
+	for id, symbol := range defaultBinanceSymbols {
+		symbols[id] = symbol
+	}
+	for _, pair := range strings.Split(getEnv("BINANCE_SYMBOLS", ""), ",") {
+		id, symbol, found := strings.Cut(strings.TrimSpace(pair), ":")
+		if found && id != "" && symbol != "" {
+			symbols[strings.ToLower(id)] = strings.ToUpper(symbol)
+		}
+	}
+
+	return &BinanceService{
+		baseURL: getEnv("BINANCE_BASE_URL", "https://api.binance.com"),
+		client:  &http.Client{Timeout: timeout},
+		symbols: symbols,
+		invalid: make(map[string]bool),
+	}
+}
+
+// Name identifica al proveedor.
+func (s *BinanceService) Name() string {
+	return binanceName
+}
+
+// symbol arma el par de Binance (ej. BTCUSDT) para una moneda y divisa.
+func (s *BinanceService) symbol(crypto, currency string) (string, error) {
+	base, ok := s.symbols[crypto]
+	if !ok {
+		return "", fmt.Errorf("%w en Binance: '%s'", domain.ErrUnsupportedCoin, crypto)
+	}
+	quote, ok := binanceQuotes[currency]
+	if !ok {
+		return "", fmt.Errorf("%w en Binance: divisa '%s'", domain.ErrUnsupportedCoin, currency)
+	}
+	return base + quote, nil
+}
+
+// binanceTicker es la parte que usamos de /api/v3/ticker/24hr. Binance manda los números como texto.
+type binanceTicker struct {
+	Symbol      string `json:"symbol"`
+	LastPrice   string `json:"lastPrice"`
+	QuoteVolume string `json:"quoteVolume"` // Volumen de 24 horas en la moneda de cotización.
+}
+
+// binanceError es el cuerpo de las respuestas de error de Binance.
+type binanceError struct {
+	Code int    `json:"code"`
+	Msg  string `json:"msg"`
+}
+
+// get hace un GET a la API y decodifica la respuesta en out. Un par que Binance no lista devuelve ErrUnsupportedCoin,
+// así la cadena no da a Binance por caído por una moneda que simplemente no tiene.
+func (s *BinanceService) get(ctx context.Context, path string, query url.Values, out interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.baseURL+path+"?"+query.Encode(), nil)
+	if err != nil {
+		return err
+	}
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("fallo en la solicitud a Binance: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusBadRequest {
+		var apiErr binanceError
+		if json.NewDecoder(resp.Body).Decode(&apiErr) == nil && apiErr.Code == binanceInvalidSymbol {
+			return fmt.Errorf("%w en Binance: %s", domain.ErrUnsupportedCoin, apiErr.Msg)
+		}
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("API de Binance devolvió estado: %d", resp.StatusCode)
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("error al decodificar JSON de Binance: %w", err)
+	}
+	return nil
+}
+
+// GetCurrentPrice obtiene el último precio operado del par.
+func (s *BinanceService) GetCurrentPrice(crypto, currency string) (float64, error) {
+	quote, err := s.GetQuote(crypto, currency)
+	if err != nil {
+		return 0, err
+	}
+	return quote.Price, nil
+}
+
+// GetPriceWithVolume obtiene el último precio y el volumen de 24 horas en la divisa pedida.
+func (s *BinanceService) GetPriceWithVolume(crypto, currency string) (float64, float64, error) {
+	quote, err := s.GetQuote(crypto, currency)
+	if err != nil {
+		return 0, 0, err
+	}
+	return quote.Price, quote.Volume24h, nil
+}
+
+// GetQuote obtiene precio y volumen del ticker de 24 horas. Binance no necesita caché: el precio siempre es de ahora.
+func (s *BinanceService) GetQuote(crypto, currency string) (domain.PriceQuote, error) {
+	return s.GetQuoteContext(context.Background(), crypto, currency)
+}
+
+// GetQuoteContext es GetQuote cortando el pedido si se cancela ctx.
+func (s *BinanceService) GetQuoteContext(ctx context.Context, crypto, currency string) (domain.PriceQuote, error) {
+	symbol, err := s.symbol(crypto, currency)
+	if err != nil {
+		return domain.PriceQuote{}, err
+	}
+	ticker, err := s.ticker(ctx, symbol)
+	if err != nil {
+		return domain.PriceQuote{}, err
+	}
+	price, err := strconv.ParseFloat(ticker.LastPrice, 64)
+	if err != nil {
+		return domain.PriceQuote{}, fmt.Errorf("precio inválido de Binance para %s: %w", symbol, err)
+	}
+	volume, _ := strconv.ParseFloat(ticker.QuoteVolume, 64)
+	return domain.PriceQuote{Price: price, Volume24h: volume, FetchedAt: time.Now(), Provider: binanceName}, nil
+}
+
+// ticker pide el ticker de 24 horas de un par y recuerda si Binance no lo lista.
+func (s *BinanceService) ticker(ctx context.Context, symbol string) (binanceTicker, error) {
+	var ticker binanceTicker
+	err := s.get(ctx, "/api/v3/ticker/24hr", url.Values{"symbol": {symbol}}, &ticker)
+	if errors.Is(err, domain.ErrUnsupportedCoin) {
+		s.mu.Lock()
+		s.invalid[symbol] = true
+		s.mu.Unlock()
+	}
+	return ticker, err
+}
+
+// GetPrices pide todos los pares en una sola llamada. Las monedas o divisas sin par en Binance no aparecen en el mapa.
+// Si un solo par del lote no existe Binance rechaza el lote entero con 400; en ese caso se piden de a uno,
+// se anotan los que no existen y desde ahí ya no se incluyen en el lote.
+func (s *BinanceService) GetPrices(cryptos []string, currencies []string) (map[string]map[string]float64, error) {
+	return s.GetPricesContext(context.Background(), cryptos, currencies)
+}
+
+// GetPricesContext es GetPrices cortando los pedidos si se cancela ctx.
+func (s *BinanceService) GetPricesContext(ctx context.Context, cryptos []string, currencies []string) (map[string]map[string]float64, error) {
+	type pair struct{ crypto, currency string }
+	pairs := make(map[string]pair)
+	symbols := make([]string, 0)
+	s.mu.Lock()
+	for _, crypto := range cryptos {
+		for _, currency := range currencies {
+			symbol, err := s.symbol(crypto, currency)
+			if err != nil || s.invalid[symbol] {
+				continue
+			}
+			if _, ok := pairs[symbol]; !ok {
+				pairs[symbol] = pair{crypto: crypto, currency: currency}
+				symbols = append(symbols, symbol)
+			}
+		}
+	}
+	s.mu.Unlock()
+	result := make(map[string]map[string]float64)
+	if len(symbols) == 0 {
+		return result, nil
+	}
+
+	encoded, err := json.Marshal(symbols)
+	if err != nil {
+		return nil, err
+	}
+	var tickers []binanceTicker
+	err = s.get(ctx, "/api/v3/ticker/24hr", url.Values{"symbols": {string(encoded)}}, &tickers)
+	if errors.Is(err, domain.ErrUnsupportedCoin) {
+		tickers, err = s.tickers(ctx, symbols)
+	}
+	if err != nil {
+		return nil, err
+	}
+	for _, ticker := range tickers {
+		p, ok := pairs[ticker.Symbol]
+		price, err := strconv.ParseFloat(ticker.LastPrice, 64)
+		if !ok || err != nil {
+			continue
+		}
+		if result[p.crypto] == nil {
+			result[p.crypto] = make(map[string]float64)
+		}
+		result[p.crypto][p.currency] = price
+	}
+	return result, nil
+}
+
+// tickers pide los pares de a uno, salteando los que Binance no lista.
+func (s *BinanceService) tickers(ctx context.Context, symbols []string) ([]binanceTicker, error) {
+	tickers := make([]binanceTicker, 0, len(symbols))
+	for _, symbol := range symbols {
+		ticker, err := s.ticker(ctx, symbol)
+		if errors.Is(err, domain.ErrUnsupportedCoin) {
+			logger.Warn("Binance no lista el par, se deja de pedir:", symbol)
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		tickers = append(tickers, ticker)
+	}
+	return tickers, nil
+}
+
+// GetHistoricalPrices arma la serie con el cierre de las velas del par contra USDT. El tamaño de vela sigue
+// la granularidad de CoinGecko (5 minutos hasta un día, 1 hora hasta 90 días, 1 día en adelante) y se pagina
+// de a binanceKlineLimit velas.
+func (s *BinanceService) GetHistoricalPrices(crypto, start, end string) ([]map[string]interface{}, error) {
+	return s.GetHistoricalPricesContext(context.Background(), crypto, start, end)
+}
+
+// GetHistoricalPricesContext es GetHistoricalPrices cortando los pedidos si se cancela ctx.
+func (s *BinanceService) GetHistoricalPricesContext(ctx context.Context, crypto, start, end string) ([]map[string]interface{}, error) {
+	symbol, err := s.symbol(crypto, "usd")
+	if err != nil {
+		return nil, err
+	}
+	from, errFrom := strconv.ParseInt(start, 10, 64)
+	to, errTo := strconv.ParseInt(end, 10, 64)
+	if errFrom != nil || errTo != nil {
+		return nil, errors.New("el rango de fechas es inválido")
+	}
+
+	span := time.Duration(to-from) * time.Second
+	interval, step := "1d", 24*time.Hour
+	switch {
+	case span <= 24*time.Hour:
+		interval, step = "5m", 5*time.Minute
+	case span <= 90*24*time.Hour:
+		interval, step = "1h", time.Hour
+	}
+
+	historicalPrices := []map[string]interface{}{}
+	startMs, endMs := from*1000, to*1000
+	for startMs <= endMs {
+		var klines [][]interface{}
+		query := url.Values{
+			"symbol":    {symbol},
+			"interval":  {interval},
+			"startTime": {strconv.FormatInt(startMs, 10)},
+			"endTime":   {strconv.FormatInt(endMs, 10)},
+			"limit":     {strconv.Itoa(binanceKlineLimit)},
+		}
+		if err := s.get(ctx, "/api/v3/klines", query, &klines); err != nil {
+			return nil, err
+		}
+		for _, kline := range klines {
+			// Cada vela es [apertura, open, high, low, close, ...]; usamos la hora de apertura y el cierre.
+			if len(kline) < 5 {
+				continue
+			}
+			openTime, okTime := kline[0].(float64)
+			closeText, okClose := kline[4].(string)
+			price, err := strconv.ParseFloat(closeText, 64)
+			if !okTime || !okClose || err != nil {
+				continue
+			}
+			historicalPrices = append(historicalPrices, map[string]interface{}{
+				"timestamp": openTime,
+				"price":     price,
+			})
+		}
+		if len(klines) < binanceKlineLimit {
+			break
+		}
+		last, _ := klines[len(klines)-1][0].(float64)
+		startMs = int64(last) + step.Milliseconds()
+	}
+	return historicalPrices, nil
+}
+
+// CheckAPIStatus revisa si la API de Binance responde.
+func (s *BinanceService) CheckAPIStatus() bool {
+	var out map[string]interface{}
+	return s.get(context.Background(), "/api/v3/ping", url.Values{}, &out) == nil
+}
@@ -0,0 +1,104 @@
+package infrastructure
+
+import (
+	"cryptoproject/internal/market/domain"
+	"cryptoproject/pkg/logger"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+)
+
+// fakeBinance imita /api/v3/ticker/24hr: conoce solo los pares de prices y, como Binance, rechaza el lote
+// entero con 400 si alguno no existe. Guarda los pedidos para ver qué se pidió.
+func fakeBinance(t *testing.T, prices map[string]string) (*httptest.Server, func() []string) {
+	t.Helper()
+	var mu sync.Mutex
+	var requests []string
+	invalid := func(w http.ResponseWriter) {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{"code":-1121,"msg":"Invalid symbol."}`))
+	}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		requests = append(requests, r.URL.RawQuery)
+		mu.Unlock()
+
+		if symbol := r.URL.Query().Get("symbol"); symbol != "" {
+			price, ok := prices[symbol]
+			if !ok {
+				invalid(w)
+				return
+			}
+			json.NewEncoder(w).Encode(binanceTicker{Symbol: symbol, LastPrice: price, QuoteVolume: "1000"})
+			return
+		}
+		var symbols []string
+		json.Unmarshal([]byte(r.URL.Query().Get("symbols")), &symbols)
+		tickers := make([]binanceTicker, 0, len(symbols))
+		for _, symbol := range symbols {
+			price, ok := prices[symbol]
+			if !ok {
+				invalid(w)
+				return
+			}
+			tickers = append(tickers, binanceTicker{Symbol: symbol, LastPrice: price, QuoteVolume: "1000"})
+		}
+		json.NewEncoder(w).Encode(tickers)
+	}))
+	t.Cleanup(srv.Close)
+	return srv, func() []string {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]string(nil), requests...)
+	}
+}
+
+func TestBinanceGetPricesSkipsUnlistedPairs(t *testing.T) {
+	logger.InitLogger()
+	srv, requests := fakeBinance(t, map[string]string{"BTCUSDT": "42000.5", "DOTUSDT": "8.1", "BTCTRY": "1344016"})
+	t.Setenv("BINANCE_BASE_URL", srv.URL)
+	t.Setenv("BINANCE_SYMBOLS", "")
+	s := NewBinanceService().(*BinanceService)
+
+	// DOTTRY está en los mapas de símbolos y divisas, pero Binance no lo lista.
+	for round := 1; round <= 2; round++ {
+		prices, err := s.GetPrices([]string{"bitcoin", "polkadot"}, []string{"usd", "try"})
+		if err != nil {
+			t.Fatalf("vuelta %d: GetPrices: %v", round, err)
+		}
+		if prices["bitcoin"]["usd"] != 42000.5 || prices["bitcoin"]["try"] != 1344016 || prices["polkadot"]["usd"] != 8.1 {
+			t.Fatalf("vuelta %d: precios = %v", round, prices)
+		}
+		if _, ok := prices["polkadot"]["try"]; ok {
+			t.Fatalf("vuelta %d: apareció un precio para un par que no existe: %v", round, prices)
+		}
+	}
+
+	// Primera vuelta: el lote rechazado y los cuatro pares de a uno. Segunda: un solo lote, ya sin DOTTRY.
+	if got := len(requests()); got != 6 {
+		t.Fatalf("se hicieron %d pedidos, se esperaban 6: %v", got, requests())
+	}
+
+	if _, err := s.GetQuote("polkadot", "try"); !errors.Is(err, domain.ErrUnsupportedCoin) {
+		t.Fatalf("GetQuote de un par que no existe = %v, se esperaba ErrUnsupportedCoin", err)
+	}
+}
+
+func TestBinanceGetPricesFailsOnOtherErrors(t *testing.T) {
+	logger.InitLogger()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{"code":-1100,"msg":"Illegal characters found in parameter 'symbols'."}`))
+	}))
+	t.Cleanup(srv.Close)
+	t.Setenv("BINANCE_BASE_URL", srv.URL)
+	s := NewBinanceService()
+
+	_, err := s.GetPrices([]string{"bitcoin"}, []string{"usd"})
+	if err == nil || errors.Is(err, domain.ErrUnsupportedCoin) {
+		t.Fatalf("error = %v, se esperaba un error que no sea ErrUnsupportedCoin", err)
+	}
+}
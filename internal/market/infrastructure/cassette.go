@@ -0,0 +1,168 @@
+package infrastructure
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"cryptoproject/pkg/logger"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Modos de las cassettes: record guarda cada intercambio HTTP en disco y replay los sirve sin salir a la red.
+const (
+	cassetteRecord = "record"
+	cassetteReplay = "replay"
+)
+
+// cassetteUnsafe son los caracteres que no dejamos en el nombre de archivo.
+var cassetteUnsafe = regexp.MustCompile(`[^a-zA-Z0-9=&.,_-]+`)
+
+// cassette es lo que se guarda de un intercambio, salvo el cuerpo de la respuesta, que va aparte en un .body
+// tal cual llegó para poder devolverlo byte a byte.
+type cassette struct {
+	Method     string      `json:"method"`
+	URL        string      `json:"url"`
+	Status     int         `json:"status"`
+	Header     http.Header `json:"header"`
+	RecordedAt time.Time   `json:"recorded_at"`
+}
+
+// cassetteTransport es un http.RoundTripper que graba o reproduce intercambios HTTP en dir.
+// Cada pedido se identifica por método, ruta y query (sin el host, así una grabación contra la API real se puede
+// reproducir con cualquier COINGECKO_BASE_URL). Si el mismo pedido se repite, por ejemplo en los reintentos,
+// cada vez se guarda en un archivo numerado y al reproducir se sirven en el mismo orden; pasado el último
+// se repite el último.
+type cassetteTransport struct {
+	dir   string
+	mode  string
+	next  http.RoundTripper // Solo se usa al grabar.
+	mu    sync.Mutex
+	calls map[string]int
+}
+
+func newCassetteTransport(dir, mode string, next http.RoundTripper) *cassetteTransport {
+	return &cassetteTransport{dir: dir, mode: mode, next: next, calls: make(map[string]int)}
+}
+
+// cassetteName arma el nombre de archivo del pedido: una parte legible y un hash del pedido completo para que no choquen.
+func cassetteName(req *http.Request) string {
+	key := req.Method + " " + req.URL.RequestURI()
+	sum := sha256.Sum256([]byte(key))
+	readable := cassetteUnsafe.ReplaceAllString(req.Method+"_"+strings.TrimPrefix(req.URL.Path, "/")+"_"+req.URL.RawQuery, "_")
+	if len(readable) > 80 {
+		readable = readable[:80]
+	}
+	return readable + "-" + hex.EncodeToString(sum[:4])
+}
+
+// path devuelve la ruta sin extensión de la n-ésima vez que se hizo el pedido (la primera no lleva número).
+func (t *cassetteTransport) path(name string, n int) string {
+	if n > 1 {
+		name += "." + strconv.Itoa(n)
+	}
+	return filepath.Join(t.dir, name)
+}
+
+// count devuelve cuántas veces se hizo el pedido contando esta.
+func (t *cassetteTransport) count(name string) int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.calls[name]++
+	return t.calls[name]
+}
+
+// RoundTrip graba o reproduce el pedido según el modo.
+func (t *cassetteTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	name := cassetteName(req)
+	n := t.count(name)
+	if t.mode == cassetteReplay {
+		return t.replay(req, name, n)
+	}
+	return t.record(req, name, n)
+}
+
+// record hace el pedido de verdad y guarda la respuesta. Si no se puede guardar se avisa, pero la respuesta sigue.
+func (t *cassetteTransport) record(req *http.Request, name string, n int) (*http.Response, error) {
+	resp, err := t.next.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+
+	header := resp.Header.Clone()
+	header.Del("Set-Cookie")
+	meta, err := json.MarshalIndent(cassette{
+		Method:     req.Method,
+		URL:        req.URL.RequestURI(),
+		Status:     resp.StatusCode,
+		Header:     header,
+		RecordedAt: time.Now().UTC(),
+	}, "", "  ")
+	if err != nil {
+		return resp, nil
+	}
+
+	path := t.path(name, n)
+	if err := os.MkdirAll(t.dir, 0o755); err != nil {
+		logger.Error("Error al crear el directorio de cassettes:", err)
+		return resp, nil
+	}
+	if err := os.WriteFile(path+".body", body, 0o644); err != nil {
+		logger.Error("Error al guardar la cassette:", err)
+		return resp, nil
+	}
+	if err := os.WriteFile(path+".json", meta, 0o644); err != nil {
+		logger.Error("Error al guardar la cassette:", err)
+	}
+	return resp, nil
+}
+
+// replay arma la respuesta con lo grabado, sin salir a la red. Si el pedido nunca se grabó devuelve un error.
+func (t *cassetteTransport) replay(req *http.Request, name string, n int) (*http.Response, error) {
+	for ; n > 1; n-- {
+		if _, err := os.Stat(t.path(name, n) + ".json"); err == nil {
+			break
+		}
+	}
+	path := t.path(name, n)
+
+	raw, err := os.ReadFile(path + ".json")
+	if err != nil {
+		return nil, fmt.Errorf("no hay cassette para %s %s: %w", req.Method, req.URL.RequestURI(), err)
+	}
+	var meta cassette
+	if err := json.Unmarshal(raw, &meta); err != nil {
+		return nil, fmt.Errorf("cassette inválida %s: %w", path, err)
+	}
+	body, err := os.ReadFile(path + ".body")
+	if err != nil {
+		return nil, fmt.Errorf("cassette sin cuerpo %s: %w", path, err)
+	}
+
+	return &http.Response{
+		Status:        strconv.Itoa(meta.Status) + " " + http.StatusText(meta.Status),
+		StatusCode:    meta.Status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        meta.Header,
+		Body:          io.NopCloser(bytes.NewReader(body)),
+		ContentLength: int64(len(body)),
+		Request:       req,
+	}, nil
+}
@@ -1,37 +1,33 @@
 package infrastructure
 
 import (
+	"context"
 	"encoding/json"
-	"errors"
 	"fmt"
 	"net/http"
 	"os"
 	"strconv"
+	"strings"
 	"sync"
 	"time"
 
+	"cryptoproject/internal/market/domain"
 	"cryptoproject/pkg/logger"
 )
 
-// CoingeckoServiceInterface define los métodos que usamos para interactuar con CoinGecko.
-// Honestamente, esto asegura flexibilidad, pero no significa que sea 100% perfecto.
-type CoingeckoServiceInterface interface {
-	GetCurrentPrice(crypto string, currency string) (float64, error)
-	GetHistoricalPrices(crypto string, start string, end string) ([]map[string]interface{}, error)
-	CheckAPIStatus() bool
-}
+// coingeckoName es el nombre del proveedor en MARKET_PROVIDERS, logs y métricas.
+const coingeckoName = "coingecko"
 
-// CoingeckoService estructura el servicio de integración con CoinGecko.
+// CoingeckoService estructura el servicio de integración con CoinGecko. Es uno de los PriceProvider.
 type CoingeckoService struct {
-	baseURL      string
-	client       *http.Client
-	rateLimitMax int
-	rateLimit    time.Duration
-	lastRequest  time.Time
-	mu           sync.Mutex
-	cachedCoins  []string
-	cacheTime    time.Time
-	cacheTTL     time.Duration
+	baseURL     string
+	client      *http.Client
+	limiter     *rateLimiter
+	maxWait     time.Duration // Cuánto puede tardar un pedido entero, con la espera del rate limit y los reintentos.
+	cachedCoins []string
+	cacheTime   time.Time
+	cacheTTL    time.Duration
+	prices      *priceCache
 }
 
 // Estas variables globales son funcionales, pero no me encantan.
@@ -43,80 +39,149 @@ var (
 
 // NewCoingeckoService inicializa el servicio de CoinGecko usando el patrón Singleton.
 // Ojo: Este patrón funciona aquí, pero no lo abuses en otras partes, se pone feo si no es necesario.
-func NewCoingeckoService() CoingeckoServiceInterface {
+func NewCoingeckoService() domain.PriceProvider {
 	once.Do(func() {
-		timeout, err := time.ParseDuration(getEnv("COINGECKO_TIMEOUT", "5s"))
-		if err != nil {
-			logger.Warn("Error al parsear COINGECKO_TIMEOUT, usando valor por defecto: 5s")
-			timeout = 5 * time.Second
-		}
-
-		rateLimitMax, err := strconv.Atoi(getEnv("COINGECKO_RATE_LIMIT", "50"))
-		if err != nil {
-			logger.Warn("Error al parsear COINGECKO_RATE_LIMIT, usando valor por defecto: 50")
-			rateLimitMax = 50
-		}
-
-		coingeckoServiceInst = &CoingeckoService{
-			baseURL:      getEnv("COINGECKO_BASE_URL", "https://api.coingecko.com/api/v3"),
-			client:       &http.Client{Timeout: timeout},
-			rateLimitMax: rateLimitMax,
-			rateLimit:    time.Minute / time.Duration(rateLimitMax),
-			lastRequest:  time.Time{},
-			cachedCoins:  nil,
-			cacheTime:    time.Time{},
-			cacheTTL:     24 * time.Hour, // Esto es mucho tiempo, pero para el caso está bien.
-		}
+		coingeckoServiceInst = newCoingeckoService()
 	})
 	return coingeckoServiceInst
 }
 
-// enforceRateLimit asegura que respetemos los límites de la API.
-// Ojo aquí: Si empiezan a aparecer retrasos por el sleep, quizás tengamos que revisar.
-func (s *CoingeckoService) enforceRateLimit() {
-	s.mu.Lock()
-	defer s.mu.Unlock()
+// newCoingeckoService arma el servicio desde las variables de entorno, sin pasar por el singleton.
+// Con COINGECKO_CASSETTE_MODE=record se graba cada intercambio HTTP en COINGECKO_CASSETTE_DIR, y con replay
+// se sirven esas grabaciones sin salir a la red (ver cassetteTransport).
+func newCoingeckoService() *CoingeckoService {
+	timeout, err := time.ParseDuration(getEnv("COINGECKO_TIMEOUT", "5s"))
+	if err != nil {
+		logger.Warn("Error al parsear COINGECKO_TIMEOUT, usando valor por defecto: 5s")
+		timeout = 5 * time.Second
+	}
 
-	now := time.Now()
-	if s.lastRequest.IsZero() || now.Sub(s.lastRequest) >= s.rateLimit {
-		s.lastRequest = now
-		return
+	rateLimitMax, err := strconv.Atoi(getEnv("COINGECKO_RATE_LIMIT", "50"))
+	if err != nil || rateLimitMax < 1 {
+		logger.Warn("Error al parsear COINGECKO_RATE_LIMIT, usando valor por defecto: 50")
+		rateLimitMax = 50
+	}
+
+	burst, err := strconv.Atoi(getEnv("COINGECKO_RATE_BURST", "5"))
+	if err != nil || burst < 1 {
+		logger.Warn("Error al parsear COINGECKO_RATE_BURST, usando valor por defecto: 5")
+		burst = 5
+	}
+
+	maxWait, err := time.ParseDuration(getEnv("COINGECKO_MAX_WAIT", "30s"))
+	if err != nil || maxWait <= 0 {
+		logger.Warn("Error al parsear COINGECKO_MAX_WAIT, usando valor por defecto: 30s")
+		maxWait = 30 * time.Second
+	}
+
+	priceTTL, err := time.ParseDuration(getEnv("COINGECKO_PRICE_TTL", "30s"))
+	if err != nil {
+		logger.Warn("Error al parsear COINGECKO_PRICE_TTL, usando valor por defecto: 30s")
+		priceTTL = 30 * time.Second
+	}
+
+	priceMaxStale, err := time.ParseDuration(getEnv("COINGECKO_PRICE_MAX_STALE", "10m"))
+	if err != nil {
+		logger.Warn("Error al parsear COINGECKO_PRICE_MAX_STALE, usando valor por defecto: 10m")
+		priceMaxStale = 10 * time.Minute
+	}
+
+	client := &http.Client{Timeout: timeout}
+	perMinute := float64(rateLimitMax)
+	switch mode := getEnv("COINGECKO_CASSETTE_MODE", "off"); mode {
+	case "off":
+	case cassetteRecord:
+		client.Transport = newCassetteTransport(getEnv("COINGECKO_CASSETTE_DIR", "testdata/cassettes/coingecko"), mode, http.DefaultTransport)
+	case cassetteReplay:
+		client.Transport = newCassetteTransport(getEnv("COINGECKO_CASSETTE_DIR", "testdata/cassettes/coingecko"), mode, nil)
+		perMinute = 0 // Reproduciendo no hay API que cuidar.
+	default:
+		logger.Warn("COINGECKO_CASSETTE_MODE desconocido, se ignora:", mode)
+	}
+
+	return &CoingeckoService{
+		baseURL:     getEnv("COINGECKO_BASE_URL", "https://api.coingecko.com/api/v3"),
+		client:      client,
+		limiter:     newRateLimiter(coingeckoName, perMinute, burst),
+		maxWait:     maxWait,
+		cachedCoins: nil,
+		cacheTime:   time.Time{},
+		cacheTTL:    24 * time.Hour, // Esto es mucho tiempo, pero para el caso está bien.
+		prices:      newPriceCache(priceTTL, priceMaxStale),
 	}
+}
+
+// Name identifica al proveedor.
+func (s *CoingeckoService) Name() string {
+	return coingeckoName
+}
 
-	timeToWait := s.rateLimit - now.Sub(s.lastRequest)
-	time.Sleep(timeToWait)
-	s.lastRequest = time.Now()
+// requestContext arma el contexto de un pedido a la API a partir del de quien llama: se corta si se cancela ctx
+// y, además, a los COINGECKO_MAX_WAIT, contando la espera por el rate limit y los reintentos.
+func (s *CoingeckoService) requestContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	return context.WithTimeout(ctx, s.maxWait)
 }
 
-// retryPolicy maneja reintentos para las solicitudes a la API.
+// retryPolicy maneja reintentos para las solicitudes a la API. Cada intento pasa por el rate limit.
 // Honestamente, este número de reintentos es arbitrario. Quizás sea mejor configurable.
-func (s *CoingeckoService) retryPolicy(requestFunc func() (*http.Response, error)) (*http.Response, error) {
+// Si CoinGecko responde 429 se frenan todos los pedidos lo que diga Retry-After y el próximo intento espera eso.
+func (s *CoingeckoService) retryPolicy(ctx context.Context, url string) (*http.Response, error) {
 	const maxRetries = 3
 	var err error
 	var resp *http.Response
 
 	for attempts := 0; attempts < maxRetries; attempts++ {
-		resp, err = requestFunc()
+		if waitErr := s.limiter.Wait(ctx); waitErr != nil {
+			if err == nil {
+				return nil, waitErr
+			}
+			return nil, fmt.Errorf("error tras múltiples intentos: %w (%v)", err, waitErr)
+		}
+
+		var req *http.Request
+		req, err = http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+		if err != nil {
+			return nil, err
+		}
+		resp, err = s.client.Do(req)
 		if err == nil && resp.StatusCode == http.StatusOK {
 			return resp, nil
 		}
+		if err == nil {
+			// Respondió pero con error (ej. 500): cerramos el cuerpo y guardamos el estado para el mensaje final.
+			resp.Body.Close()
+			err = fmt.Errorf("API de CoinGecko devolvió estado: %d", resp.StatusCode)
+			if resp.StatusCode == http.StatusTooManyRequests {
+				wait := retryAfter(resp.Header)
+				logger.Warn("CoinGecko nos limitó (429), frenamos los pedidos por", wait)
+				s.limiter.Block(wait)
+				continue
+			}
+		}
 
+		if attempts == maxRetries-1 {
+			break
+		}
 		// Ojo: El sleep aquí hace que los reintentos sean lentos si hay muchas solicitudes fallidas.
-		time.Sleep(time.Second * time.Duration(attempts+1))
+		select {
+		case <-time.After(time.Second * time.Duration(attempts+1)):
+		case <-ctx.Done():
+			return nil, fmt.Errorf("error tras múltiples intentos: %w", err)
+		}
 	}
-	return nil, errors.New("error tras múltiples intentos: " + err.Error())
+	return nil, fmt.Errorf("error tras múltiples intentos: %w", err)
 }
 
 // CheckAPIStatus revisa si la API de CoinGecko está operativa.
 // Este método funciona, pero no es el más eficiente.
 // Quizás en el futuro podamos implementar algo más ligero.
+// Ya no se llama antes de cada precio: gastaba un lugar del rate limit por pedido y el caché cubre las caídas.
 func (s *CoingeckoService) CheckAPIStatus() bool {
-	s.enforceRateLimit()
+	ctx, cancel := s.requestContext(context.Background())
+	defer cancel()
 	url := fmt.Sprintf("%s/ping", s.baseURL)
 
-	resp, err := s.retryPolicy(func() (*http.Response, error) {
-		return s.client.Get(url)
-	})
+	resp, err := s.retryPolicy(ctx, url)
 	if err != nil {
 		logger.Error("Error al verificar el estado de CoinGecko:", err)
 		return false
@@ -127,57 +192,178 @@ func (s *CoingeckoService) CheckAPIStatus() bool {
 }
 
 // GetCurrentPrice obtiene el precio actual de una criptomoneda.
-// Aquí no hay magia: si la API falla, no hay mucho que podamos hacer.
+// Pasa por el caché de precios (ver GetQuote), así que puede devolver un precio de hasta COINGECKO_PRICE_TTL.
 func (s *CoingeckoService) GetCurrentPrice(crypto, currency string) (float64, error) {
-	if !s.CheckAPIStatus() {
-		return 0, fmt.Errorf("API de CoinGecko no está disponible")
+	quote, err := s.GetQuote(crypto, currency)
+	if err != nil && !quote.Stale {
+		return 0, err
 	}
+	return quote.Price, nil
+}
 
-	s.enforceRateLimit()
-	url := fmt.Sprintf("%s/simple/price?ids=%s&vs_currencies=%s", s.baseURL, crypto, currency)
+// GetPriceWithVolume obtiene el precio actual y el volumen operado en las últimas 24 horas, en la misma moneda.
+// El volumen lo usa el modelo de deslizamiento; si CoinGecko no lo trae, devolvemos 0.
+func (s *CoingeckoService) GetPriceWithVolume(crypto, currency string) (float64, float64, error) {
+	quote, err := s.GetQuote(crypto, currency)
+	if err != nil && !quote.Stale {
+		return 0, 0, err
+	}
+	return quote.Price, quote.Volume24h, nil
+}
 
-	resp, err := s.retryPolicy(func() (*http.Response, error) {
-		return s.client.Get(url)
-	})
-	if err != nil {
-		logger.Error("Error al realizar solicitud a CoinGecko:", err)
-		return 0, fmt.Errorf("fallo en la solicitud a CoinGecko: %w", err)
+// GetQuote devuelve el precio con su antigüedad. Si el precio guardado no venció no se llama a CoinGecko.
+// Vencido, el primer pedido lo refresca y los que llegan mientras tanto reciben el vencido (stale-while-revalidate);
+// si CoinGecko falla también se sirve el vencido, siempre que no tenga más de COINGECKO_PRICE_MAX_STALE, pero
+// junto con el error, porque ahí CoinGecko sí está fallando.
+func (s *CoingeckoService) GetQuote(crypto, currency string) (domain.PriceQuote, error) {
+	return s.GetQuoteContext(context.Background(), crypto, currency)
+}
+
+// GetQuoteContext es GetQuote cortando el pedido a CoinGecko si se cancela ctx.
+func (s *CoingeckoService) GetQuoteContext(ctx context.Context, crypto, currency string) (domain.PriceQuote, error) {
+	key := priceKey{coin: crypto, currency: currency}
+	if quote, ok := s.prices.fresh(key, time.Now()); ok {
+		return quote, nil
 	}
-	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
-		return 0, fmt.Errorf("API de CoinGecko devolvió estado: %d", resp.StatusCode)
+	claimed := s.prices.claim(key)
+	if !claimed {
+		if quote, ok := s.prices.stale(key, time.Now()); ok {
+			return quote, nil
+		}
 	}
 
-	var data map[string]map[string]float64
-	if err := json.NewDecoder(resp.Body).Decode(&data); err != nil {
-		logger.Error("Error al decodificar respuesta de CoinGecko:", err)
-		return 0, fmt.Errorf("error al decodificar JSON: %w", err)
+	quote, err := s.fetchQuote(ctx, crypto, currency)
+	if err != nil {
+		if claimed {
+			s.prices.release(key)
+		}
+		if stale, ok := s.prices.stale(key, time.Now()); ok {
+			logger.Warn("CoinGecko no respondió, usamos el precio guardado de", crypto, "con antigüedad", stale.Age(time.Now()))
+			return stale, err
+		}
+		return domain.PriceQuote{}, err
 	}
+	s.prices.store(key, quote)
+	return quote, nil
+}
 
+// fetchQuote pide a CoinGecko el precio y el volumen de una moneda.
+func (s *CoingeckoService) fetchQuote(ctx context.Context, crypto, currency string) (domain.PriceQuote, error) {
+	data, err := s.fetchPrices(ctx, []string{crypto}, []string{currency})
+	if err != nil {
+		return domain.PriceQuote{}, err
+	}
 	if _, exists := data[crypto]; !exists {
-		return 0, fmt.Errorf("moneda '%s' no soportada o no disponible en CoinGecko", crypto)
+		return domain.PriceQuote{}, fmt.Errorf("%w en CoinGecko: '%s'", domain.ErrUnsupportedCoin, crypto)
+	}
+	price, ok := data[crypto][currency]
+	if !ok {
+		return domain.PriceQuote{}, fmt.Errorf("%w en CoinGecko: '%s' en '%s'", domain.ErrUnsupportedCoin, crypto, currency)
+	}
+	return domain.PriceQuote{Price: price, Volume24h: data[crypto][currency+"_24h_vol"], FetchedAt: time.Now(), Provider: coingeckoName}, nil
+}
+
+// GetPrices obtiene el precio de varias monedas en una sola solicitud; simple/price acepta varios ids y divisas separados por coma.
+// Así una cartera con muchas monedas gasta una sola llamada del rate limit. Solo se piden las monedas que no están
+// frescas en el caché; si CoinGecko falla se completan con precios vencidos, y si alguna no tiene devolvemos el error.
+func (s *CoingeckoService) GetPrices(cryptos []string, currencies []string) (map[string]map[string]float64, error) {
+	return s.GetPricesContext(context.Background(), cryptos, currencies)
+}
+
+// GetPricesContext es GetPrices cortando el pedido a CoinGecko si se cancela ctx.
+func (s *CoingeckoService) GetPricesContext(ctx context.Context, cryptos []string, currencies []string) (map[string]map[string]float64, error) {
+	result := make(map[string]map[string]float64)
+	set := func(coin, currency string, price float64) {
+		if result[coin] == nil {
+			result[coin] = make(map[string]float64)
+		}
+		result[coin][currency] = price
+	}
+
+	now := time.Now()
+	missing := make([]string, 0)
+	for _, crypto := range cryptos {
+		cached := true
+		for _, currency := range currencies {
+			quote, ok := s.prices.fresh(priceKey{coin: crypto, currency: currency}, now)
+			if !ok {
+				cached = false
+				break
+			}
+			set(crypto, currency, quote.Price)
+		}
+		if !cached {
+			missing = append(missing, crypto)
+		}
+	}
+	if len(missing) == 0 {
+		return result, nil
+	}
+
+	data, err := s.fetchPrices(ctx, missing, currencies)
+	if err != nil {
+		for _, crypto := range missing {
+			for _, currency := range currencies {
+				quote, ok := s.prices.stale(priceKey{coin: crypto, currency: currency}, time.Now())
+				if !ok {
+					return nil, err
+				}
+				set(crypto, currency, quote.Price)
+			}
+		}
+		logger.Warn("CoinGecko no respondió, usamos precios guardados:", err)
+		return result, nil
 	}
-	if price, ok := data[crypto][currency]; ok {
-		return price, nil
+
+	fetchedAt := time.Now()
+	for crypto, prices := range data {
+		for _, currency := range currencies {
+			price, ok := prices[currency]
+			if !ok {
+				continue
+			}
+			s.prices.store(priceKey{coin: crypto, currency: currency}, domain.PriceQuote{Price: price, Volume24h: prices[currency+"_24h_vol"], FetchedAt: fetchedAt, Provider: coingeckoName})
+			set(crypto, currency, price)
+		}
+	}
+	return result, nil
+}
+
+// fetchPrices hace la llamada a simple/price. Siempre pide el volumen para que el caché sirva también a GetPriceWithVolume.
+func (s *CoingeckoService) fetchPrices(ctx context.Context, cryptos []string, currencies []string) (map[string]map[string]float64, error) {
+	ctx, cancel := s.requestContext(ctx)
+	defer cancel()
+	url := fmt.Sprintf("%s/simple/price?ids=%s&vs_currencies=%s&include_24hr_vol=true", s.baseURL, strings.Join(cryptos, ","), strings.Join(currencies, ","))
+
+	resp, err := s.retryPolicy(ctx, url)
+	if err != nil {
+		logger.Error("Error al realizar solicitud a CoinGecko:", err)
+		return nil, fmt.Errorf("fallo en la solicitud a CoinGecko: %w", err)
 	}
+	defer resp.Body.Close()
 
-	return 0, fmt.Errorf("precio para '%s' en '%s' no encontrado", crypto, currency)
+	var data map[string]map[string]float64
+	if err := json.NewDecoder(resp.Body).Decode(&data); err != nil {
+		logger.Error("Error al decodificar respuesta de CoinGecko:", err)
+		return nil, fmt.Errorf("error al decodificar JSON: %w", err)
+	}
+	return data, nil
 }
 
 // GetHistoricalPrices obtiene precios históricos de una criptomoneda.
 // Esto está bien para ahora, pero si las fechas son largas, los datos se vuelven enormes.
 func (s *CoingeckoService) GetHistoricalPrices(crypto, start, end string) ([]map[string]interface{}, error) {
-	if !s.CheckAPIStatus() {
-		return nil, fmt.Errorf("API de CoinGecko no está disponible")
-	}
+	return s.GetHistoricalPricesContext(context.Background(), crypto, start, end)
+}
 
-	s.enforceRateLimit()
+// GetHistoricalPricesContext es GetHistoricalPrices cortando el pedido a CoinGecko si se cancela ctx.
+func (s *CoingeckoService) GetHistoricalPricesContext(ctx context.Context, crypto, start, end string) ([]map[string]interface{}, error) {
+	ctx, cancel := s.requestContext(ctx)
+	defer cancel()
 	url := fmt.Sprintf("%s/coins/%s/market_chart/range?vs_currency=usd&from=%s&to=%s", s.baseURL, crypto, start, end)
 
-	resp, err := s.retryPolicy(func() (*http.Response, error) {
-		return s.client.Get(url)
-	})
+	resp, err := s.retryPolicy(ctx, url)
 	if err != nil {
 		logger.Error("Error al realizar solicitud a CoinGecko:", err)
 		return nil, err
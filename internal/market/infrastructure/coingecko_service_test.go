@@ -0,0 +1,196 @@
+package infrastructure
+
+import (
+	"context"
+	"cryptoproject/internal/market/domain"
+	"cryptoproject/pkg/logger"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+)
+
+// replayService arma un CoingeckoService que responde con las cassettes de dir, sin salir a la red.
+// Para regrabarlas contra la API: COINGECKO_CASSETTE_MODE=record con COINGECKO_CASSETTE_DIR apuntando a ese directorio.
+func replayService(t *testing.T, dir string) *CoingeckoService {
+	t.Helper()
+	logger.InitLogger()
+	t.Setenv("COINGECKO_CASSETTE_MODE", cassetteReplay)
+	t.Setenv("COINGECKO_CASSETTE_DIR", dir)
+	t.Setenv("COINGECKO_BASE_URL", "")
+	return newCoingeckoService()
+}
+
+func TestCoingeckoReplayCurrentPrice(t *testing.T) {
+	tests := []struct {
+		name    string
+		crypto  string
+		price   float64
+		wantErr error
+	}{
+		{name: "bitcoin", crypto: "bitcoin", price: 42261.04},
+		{name: "ethereum", crypto: "ethereum", price: 2281.47},
+		{name: "moneda que CoinGecko no conoce", crypto: "notacoin", wantErr: domain.ErrUnsupportedCoin},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := replayService(t, "testdata/cassettes/coingecko")
+			price, err := s.GetCurrentPrice(tt.crypto, "usd")
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("error = %v, se esperaba %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("GetCurrentPrice: %v", err)
+			}
+			if price != tt.price {
+				t.Fatalf("precio = %v, se esperaba %v", price, tt.price)
+			}
+		})
+	}
+}
+
+func TestCoingeckoReplayPriceWithVolume(t *testing.T) {
+	s := replayService(t, "testdata/cassettes/coingecko")
+	price, volume, err := s.GetPriceWithVolume("bitcoin", "usd")
+	if err != nil {
+		t.Fatalf("GetPriceWithVolume: %v", err)
+	}
+	if price != 42261.04 || volume != 18726543982.51 {
+		t.Fatalf("precio, volumen = %v, %v; se esperaba 42261.04, 18726543982.51", price, volume)
+	}
+
+	// El segundo pedido sale del caché: el precio y el volumen tienen que ser los mismos.
+	quote, err := s.GetQuote("bitcoin", "usd")
+	if err != nil {
+		t.Fatalf("GetQuote: %v", err)
+	}
+	if quote.Price != price || quote.Volume24h != volume || quote.Stale || quote.Provider != coingeckoName {
+		t.Fatalf("cotización del caché = %+v", quote)
+	}
+}
+
+func TestCoingeckoReplayHistoricalPrices(t *testing.T) {
+	s := replayService(t, "testdata/cassettes/coingecko")
+	prices, err := s.GetHistoricalPrices("bitcoin", "1704067200", "1704326400")
+	if err != nil {
+		t.Fatalf("GetHistoricalPrices: %v", err)
+	}
+	want := []map[string]interface{}{
+		{"timestamp": 1704067200000.0, "price": 42261.04},
+		{"timestamp": 1704153600000.0, "price": 44179.92},
+		{"timestamp": 1704240000000.0, "price": 44951.32},
+		{"timestamp": 1704326400000.0, "price": 42848.17},
+	}
+	if len(prices) != len(want) {
+		t.Fatalf("se obtuvieron %d precios, se esperaban %d", len(prices), len(want))
+	}
+	for i := range want {
+		if prices[i]["timestamp"] != want[i]["timestamp"] || prices[i]["price"] != want[i]["price"] {
+			t.Fatalf("precio %d = %v, se esperaba %v", i, prices[i], want[i])
+		}
+	}
+}
+
+func TestCoingeckoReplayMissingCassette(t *testing.T) {
+	s := replayService(t, "testdata/cassettes/coingecko")
+	if _, err := s.GetHistoricalPrices("bitcoin", "1", "2"); err == nil {
+		t.Fatal("un pedido sin cassette tiene que fallar")
+	}
+}
+
+// El vencido se sirve en los dos casos, pero solo si CoinGecko no respondió viene con el error, para que la cadena
+// de proveedores lo cuente como falla.
+func TestCoingeckoStaleQuote(t *testing.T) {
+	tests := []struct {
+		name       string
+		refreshing bool // Otro pedido ya está refrescando el precio.
+		wantErr    bool
+	}{
+		{name: "refresco en curso", refreshing: true},
+		{name: "CoinGecko no respondió", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// No hay cassette de solana, así que pedirla a CoinGecko falla.
+			s := replayService(t, "testdata/cassettes/coingecko")
+			key := priceKey{coin: "solana", currency: "usd"}
+			s.prices.store(key, domain.PriceQuote{Price: 98.5, FetchedAt: time.Now().Add(-2 * time.Minute), Provider: coingeckoName})
+			if tt.refreshing {
+				s.prices.claim(key)
+			}
+
+			quote, err := s.GetQuote("solana", "usd")
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("error = %v, se esperaba error: %v", err, tt.wantErr)
+			}
+			if !quote.Stale || quote.Price != 98.5 {
+				t.Fatalf("cotización = %+v, se esperaba el precio vencido", quote)
+			}
+			if price, err := s.GetCurrentPrice("solana", "usd"); err != nil || price != 98.5 {
+				t.Fatalf("GetCurrentPrice = %v, %v; se esperaba el precio vencido sin error", price, err)
+			}
+		})
+	}
+}
+
+// Con el contexto del pedido ya cancelado no se espera a CoinGecko: ni el rate limit ni los reintentos.
+func TestCoingeckoCancelledContext(t *testing.T) {
+	s := replayService(t, "testdata/cassettes/coingecko")
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	begin := time.Now()
+	if _, err := s.GetQuoteContext(ctx, "bitcoin", "usd"); !errors.Is(err, context.Canceled) {
+		t.Fatalf("error = %v, se esperaba context.Canceled", err)
+	}
+	if _, err := s.GetHistoricalPricesContext(ctx, "bitcoin", "1704067200", "1704326400"); !errors.Is(err, context.Canceled) {
+		t.Fatalf("error = %v, se esperaba context.Canceled", err)
+	}
+	if elapsed := time.Since(begin); elapsed >= time.Second {
+		t.Fatalf("tardó %v con el contexto cancelado", elapsed)
+	}
+}
+
+func TestCoingeckoReplayRetryAfter(t *testing.T) {
+	// La grabación tiene un 429 con Retry-After: 1 y después la respuesta buena.
+	s := replayService(t, "testdata/cassettes/coingecko_rate_limited")
+	begin := time.Now()
+	price, err := s.GetCurrentPrice("bitcoin", "usd")
+	if err != nil {
+		t.Fatalf("GetCurrentPrice: %v", err)
+	}
+	if price != 42261.04 {
+		t.Fatalf("precio = %v, se esperaba 42261.04", price)
+	}
+	// El reintento espera lo que dijo Retry-After y no suma la pausa entre intentos.
+	if elapsed := time.Since(begin); elapsed < time.Second || elapsed >= 2*time.Second {
+		t.Fatalf("el reintento tardó %v, se esperaba entre 1s y 2s", elapsed)
+	}
+}
+
+func TestRetryAfter(t *testing.T) {
+	tests := []struct {
+		name   string
+		header string
+		want   time.Duration
+	}{
+		{name: "segundos", header: "7", want: 7 * time.Second},
+		{name: "sin header", header: "", want: defaultRetryAfter},
+		{name: "basura", header: "pronto", want: defaultRetryAfter},
+		{name: "fecha pasada", header: "Mon, 01 Jan 2024 00:00:00 GMT", want: 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			header := http.Header{}
+			if tt.header != "" {
+				header.Set("Retry-After", tt.header)
+			}
+			if got := retryAfter(header); got != tt.want {
+				t.Fatalf("retryAfter(%q) = %v, se esperaba %v", tt.header, got, tt.want)
+			}
+		})
+	}
+}
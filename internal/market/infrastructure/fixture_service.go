@@ -0,0 +1,377 @@
+package infrastructure
+
+import (
+	"context"
+	"cryptoproject/internal/market/domain"
+	"cryptoproject/pkg/logger"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// fixtureName es el nombre del proveedor en MARKET_PROVIDERS, logs y métricas.
+const fixtureName = "fixture"
+
+// fixtureCoin es el punto de partida del modelo para una moneda: precio en USD al inicio y volumen de 24 horas típico.
+type fixtureCoin struct {
+	price  float64
+	volume float64
+}
+
+// defaultFixtureCoins son las monedas que conoce el modelo, con precios de principios de 2024.
+// MARKET_FIXTURE_COINS ("id:precio,...") agrega o pisa entradas.
+var defaultFixtureCoins = map[string]fixtureCoin{
+	"bitcoin":     {price: 42000, volume: 20e9},
+	"ethereum":    {price: 2300, volume: 10e9},
+	"solana":      {price: 100, volume: 2e9},
+	"dogecoin":    {price: 0.09, volume: 500e6},
+	"cardano":     {price: 0.6, volume: 400e6},
+	"ripple":      {price: 0.6, volume: 1e9},
+	"binancecoin": {price: 310, volume: 800e6},
+	"litecoin":    {price: 72, volume: 300e6},
+	"polkadot":    {price: 8, volume: 200e6},
+	"tron":        {price: 0.1, volume: 300e6},
+	"chainlink":   {price: 15, volume: 400e6},
+	"avalanche-2": {price: 38, volume: 500e6},
+}
+
+// fixtureRates convierte el precio en USD a otras divisas con un tipo de cambio fijo, para que todo siga siendo determinista.
+var fixtureRates = map[string]float64{
+	"usd": 1,
+	"eur": 0.92,
+	"gbp": 0.79,
+	"jpy": 150,
+	"brl": 5,
+	"try": 32,
+	"ars": 900,
+}
+
+// fixturePoint es un precio grabado en USD.
+type fixturePoint struct {
+	Time   time.Time
+	Price  float64
+	Volume float64
+}
+
+// FixtureService es un PriceProvider que no sale a la red: sirve precios grabados en archivos JSON o CSV
+// (MARKET_FIXTURE_PATH) y, para las monedas que no están grabadas, los genera con una caminata aleatoria con semilla.
+// Sirve para tests de integración y demos: con la misma configuración siempre da los mismos precios.
+type FixtureService struct {
+	coins    map[string]fixtureCoin
+	recorded map[string][]fixturePoint // Por moneda, ordenados por fecha.
+	walk     *randomWalk
+	clock    time.Time // Si no es cero, los precios actuales se calculan en este momento y no en time.Now.
+}
+
+// NewFixtureService crea el proveedor con MARKET_FIXTURE_PATH, MARKET_FIXTURE_SEED, MARKET_FIXTURE_START,
+// MARKET_FIXTURE_VOLATILITY, MARKET_FIXTURE_COINS y MARKET_FIXTURE_TIME.
+func NewFixtureService() domain.PriceProvider {
+	seed, err := strconv.ParseInt(getEnv("MARKET_FIXTURE_SEED", "42"), 10, 64)
+	if err != nil {
+		logger.Warn("Error al parsear MARKET_FIXTURE_SEED, usando valor por defecto: 42")
+		seed = 42
+	}
+	start, err := time.Parse(time.RFC3339, getEnv("MARKET_FIXTURE_START", "2024-01-01T00:00:00Z"))
+	if err != nil {
+		logger.Warn("Error al parsear MARKET_FIXTURE_START, usando valor por defecto: 2024-01-01T00:00:00Z")
+		start = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	}
+	volatility, err := strconv.ParseFloat(getEnv("MARKET_FIXTURE_VOLATILITY", "0.03"), 64)
+	if err != nil || volatility < 0 {
+		logger.Warn("Error al parsear MARKET_FIXTURE_VOLATILITY, usando valor por defecto: 0.03")
+		volatility = 0.03
+	}
+
+	var clock time.Time
+	if value := getEnv("MARKET_FIXTURE_TIME", ""); value != "" {
+		if clock, err = time.Parse(time.RFC3339, value); err != nil {
+			logger.Warn("Error al parsear MARKET_FIXTURE_TIME, se usa la hora actual")
+		}
+	}
+
+	coins := make(map[string]fixtureCoin, len(defaultFixtureCoins))
+	for id, coin := range defaultFixtureCoins {
+		coins[id] = coin
+	}
+	for _, pair := range strings.Split(getEnv("MARKET_FIXTURE_COINS", ""), ",") {
+		id, value, found := strings.Cut(strings.TrimSpace(pair), ":")
+		price, err := strconv.ParseFloat(value, 64)
+		if !found || id == "" || err != nil || price <= 0 {
+			continue
+		}
+		coins[strings.ToLower(id)] = fixtureCoin{price: price, volume: 100e6}
+	}
+
+	recorded := make(map[string][]fixturePoint)
+	if path := getEnv("MARKET_FIXTURE_PATH", ""); path != "" {
+		if recorded, err = loadFixtures(path); err != nil {
+			logger.Error("Error al cargar los precios grabados, se usa solo el modelo:", err)
+			recorded = make(map[string][]fixturePoint)
+		}
+	}
+
+	return &FixtureService{coins: coins, recorded: recorded, walk: newRandomWalk(seed, start, volatility), clock: clock}
+}
+
+// Name identifica al proveedor.
+func (s *FixtureService) Name() string {
+	return fixtureName
+}
+
+// now es el momento en el que se calculan los precios actuales.
+func (s *FixtureService) now() time.Time {
+	if !s.clock.IsZero() {
+		return s.clock
+	}
+	return time.Now()
+}
+
+// knows indica si la moneda está grabada o la conoce el modelo.
+func (s *FixtureService) knows(crypto string) bool {
+	if _, ok := s.recorded[crypto]; ok {
+		return true
+	}
+	_, ok := s.coins[crypto]
+	return ok
+}
+
+// usdAt devuelve precio y volumen en USD en el momento t. Lo grabado tiene prioridad sobre el modelo:
+// se usa el último precio grabado hasta t (o el primero, si t es anterior a la grabación).
+func (s *FixtureService) usdAt(crypto string, t time.Time) (float64, float64) {
+	if points, ok := s.recorded[crypto]; ok {
+		i := sort.Search(len(points), func(i int) bool { return points[i].Time.After(t) })
+		if i > 0 {
+			i--
+		}
+		return points[i].Price, points[i].Volume
+	}
+	coin := s.coins[crypto]
+	return s.walk.Price(crypto, coin.price, t), s.walk.Volume(crypto, coin.volume, t)
+}
+
+// GetCurrentPrice obtiene el precio de ahora.
+func (s *FixtureService) GetCurrentPrice(crypto, currency string) (float64, error) {
+	quote, err := s.GetQuote(crypto, currency)
+	if err != nil {
+		return 0, err
+	}
+	return quote.Price, nil
+}
+
+// GetPriceWithVolume obtiene el precio y el volumen de 24 horas de ahora.
+func (s *FixtureService) GetPriceWithVolume(crypto, currency string) (float64, float64, error) {
+	quote, err := s.GetQuote(crypto, currency)
+	if err != nil {
+		return 0, 0, err
+	}
+	return quote.Price, quote.Volume24h, nil
+}
+
+// GetQuote obtiene precio y volumen en la divisa pedida.
+func (s *FixtureService) GetQuote(crypto, currency string) (domain.PriceQuote, error) {
+	rate, ok := fixtureRates[currency]
+	if !ok {
+		return domain.PriceQuote{}, fmt.Errorf("%w en los precios de prueba: divisa '%s'", domain.ErrUnsupportedCoin, currency)
+	}
+	if !s.knows(crypto) {
+		return domain.PriceQuote{}, fmt.Errorf("%w en los precios de prueba: '%s'", domain.ErrUnsupportedCoin, crypto)
+	}
+	price, volume := s.usdAt(crypto, s.now())
+	return domain.PriceQuote{Price: price * rate, Volume24h: volume * rate, FetchedAt: time.Now(), Provider: fixtureName}, nil
+}
+
+// GetQuoteContext es GetQuote; no sale a la red, así que no hay pedido que cortar con ctx.
+func (s *FixtureService) GetQuoteContext(ctx context.Context, crypto, currency string) (domain.PriceQuote, error) {
+	return s.GetQuote(crypto, currency)
+}
+
+// GetPrices obtiene varias monedas en varias divisas. Las que no conoce no aparecen en el mapa.
+func (s *FixtureService) GetPrices(cryptos []string, currencies []string) (map[string]map[string]float64, error) {
+	now := s.now()
+	result := make(map[string]map[string]float64)
+	for _, crypto := range cryptos {
+		if !s.knows(crypto) {
+			continue
+		}
+		price, _ := s.usdAt(crypto, now)
+		for _, currency := range currencies {
+			rate, ok := fixtureRates[currency]
+			if !ok {
+				continue
+			}
+			if result[crypto] == nil {
+				result[crypto] = make(map[string]float64)
+			}
+			result[crypto][currency] = price * rate
+		}
+	}
+	return result, nil
+}
+
+// GetPricesContext es GetPrices; no sale a la red, así que no hay pedido que cortar con ctx.
+func (s *FixtureService) GetPricesContext(ctx context.Context, cryptos []string, currencies []string) (map[string]map[string]float64, error) {
+	return s.GetPrices(cryptos, currencies)
+}
+
+// GetHistoricalPrices devuelve la serie en USD. Si la moneda está grabada, son los puntos grabados dentro del rango;
+// si no, el modelo con la misma granularidad que CoinGecko (5 minutos hasta un día, 1 hora hasta 90 días, 1 día en adelante).
+func (s *FixtureService) GetHistoricalPrices(crypto, start, end string) ([]map[string]interface{}, error) {
+	from, errFrom := strconv.ParseInt(start, 10, 64)
+	to, errTo := strconv.ParseInt(end, 10, 64)
+	if errFrom != nil || errTo != nil {
+		return nil, errors.New("el rango de fechas es inválido")
+	}
+	if !s.knows(crypto) {
+		return nil, fmt.Errorf("%w en los precios de prueba: '%s'", domain.ErrUnsupportedCoin, crypto)
+	}
+	startTime, endTime := time.Unix(from, 0), time.Unix(to, 0)
+
+	historicalPrices := []map[string]interface{}{}
+	if points, ok := s.recorded[crypto]; ok {
+		for _, point := range points {
+			if point.Time.Before(startTime) || point.Time.After(endTime) {
+				continue
+			}
+			historicalPrices = append(historicalPrices, map[string]interface{}{
+				"timestamp": float64(point.Time.UnixMilli()),
+				"price":     point.Price,
+			})
+		}
+		return historicalPrices, nil
+	}
+
+	step := 24 * time.Hour
+	switch span := endTime.Sub(startTime); {
+	case span <= 24*time.Hour:
+		step = walkStep
+	case span <= 90*24*time.Hour:
+		step = time.Hour
+	}
+	times := make([]time.Time, 0)
+	for t := startTime.Truncate(step); !t.After(endTime); t = t.Add(step) {
+		if !t.Before(startTime) {
+			times = append(times, t)
+		}
+	}
+	coin := s.coins[crypto]
+	for i, price := range s.walk.Prices(crypto, coin.price, times) {
+		historicalPrices = append(historicalPrices, map[string]interface{}{
+			"timestamp": float64(times[i].UnixMilli()),
+			"price":     price,
+		})
+	}
+	return historicalPrices, nil
+}
+
+// GetHistoricalPricesContext es GetHistoricalPrices; no sale a la red, así que no hay pedido que cortar con ctx.
+func (s *FixtureService) GetHistoricalPricesContext(ctx context.Context, crypto, start, end string) ([]map[string]interface{}, error) {
+	return s.GetHistoricalPrices(crypto, start, end)
+}
+
+// CheckAPIStatus siempre responde: no hay API de por medio.
+func (s *FixtureService) CheckAPIStatus() bool {
+	return true
+}
+
+// loadFixtures lee los precios grabados de un archivo .json o .csv, o de todos los que haya en un directorio.
+func loadFixtures(path string) (map[string][]fixturePoint, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	files := []string{path}
+	if info.IsDir() {
+		entries, err := os.ReadDir(path)
+		if err != nil {
+			return nil, err
+		}
+		files = files[:0]
+		for _, entry := range entries {
+			ext := strings.ToLower(filepath.Ext(entry.Name()))
+			if !entry.IsDir() && (ext == ".json" || ext == ".csv") {
+				files = append(files, filepath.Join(path, entry.Name()))
+			}
+		}
+	}
+
+	recorded := make(map[string][]fixturePoint)
+	for _, file := range files {
+		if err := loadFixtureFile(file, recorded); err != nil {
+			return nil, fmt.Errorf("%s: %w", file, err)
+		}
+	}
+	for coin, points := range recorded {
+		sort.SliceStable(points, func(i, j int) bool { return points[i].Time.Before(points[j].Time) })
+		recorded[coin] = points
+	}
+	return recorded, nil
+}
+
+// loadFixtureFile agrega a recorded los precios de un archivo. El JSON es
+// {"bitcoin": [{"timestamp": 1704067200, "price": 42000, "volume": 2e10}, ...]} y el CSV tiene las columnas
+// coin,timestamp,price[,volume]. Los timestamps son UNIX en segundos y los precios en USD.
+func loadFixtureFile(file string, recorded map[string][]fixturePoint) error {
+	f, err := os.Open(file)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	if strings.ToLower(filepath.Ext(file)) == ".json" {
+		var data map[string][]struct {
+			Timestamp int64   `json:"timestamp"`
+			Price     float64 `json:"price"`
+			Volume    float64 `json:"volume"`
+		}
+		if err := json.NewDecoder(f).Decode(&data); err != nil {
+			return fmt.Errorf("error al decodificar JSON: %w", err)
+		}
+		for coin, points := range data {
+			coin = strings.ToLower(coin)
+			for _, point := range points {
+				recorded[coin] = append(recorded[coin], fixturePoint{Time: time.Unix(point.Timestamp, 0), Price: point.Price, Volume: point.Volume})
+			}
+		}
+		return nil
+	}
+
+	reader := csv.NewReader(f)
+	reader.FieldsPerRecord = -1
+	for line := 1; ; line++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if len(record) < 3 {
+			return fmt.Errorf("línea %d: se esperan las columnas coin,timestamp,price[,volume]", line)
+		}
+		timestamp, err := strconv.ParseInt(strings.TrimSpace(record[1]), 10, 64)
+		if err != nil {
+			if line == 1 {
+				continue // Encabezado.
+			}
+			return fmt.Errorf("línea %d: timestamp inválido", line)
+		}
+		price, err := strconv.ParseFloat(strings.TrimSpace(record[2]), 64)
+		if err != nil {
+			return fmt.Errorf("línea %d: precio inválido", line)
+		}
+		var volume float64
+		if len(record) > 3 {
+			volume, _ = strconv.ParseFloat(strings.TrimSpace(record[3]), 64)
+		}
+		coin := strings.ToLower(strings.TrimSpace(record[0]))
+		recorded[coin] = append(recorded[coin], fixturePoint{Time: time.Unix(timestamp, 0), Price: price, Volume: volume})
+	}
+}
@@ -0,0 +1,106 @@
+package infrastructure
+
+import (
+	"cryptoproject/pkg/logger"
+	"math"
+	"strconv"
+	"testing"
+	"time"
+)
+
+// fixtureService arma el proveedor de prueba solo con el modelo, con la semilla y el inicio de siempre salvo seed.
+func fixtureService(t *testing.T, seed int64) *FixtureService {
+	t.Helper()
+	logger.InitLogger()
+	t.Setenv("MARKET_FIXTURE_SEED", strconv.FormatInt(seed, 10))
+	t.Setenv("MARKET_FIXTURE_START", "2024-01-01T00:00:00Z")
+	t.Setenv("MARKET_FIXTURE_VOLATILITY", "0.03")
+	t.Setenv("MARKET_FIXTURE_PATH", "")
+	t.Setenv("MARKET_FIXTURE_COINS", "")
+	t.Setenv("MARKET_FIXTURE_TIME", "")
+	return NewFixtureService().(*FixtureService)
+}
+
+// history pide la serie del modelo entre from y to y la devuelve como momentos y precios.
+func history(t *testing.T, s *FixtureService, coin string, from, to time.Time) ([]time.Time, []float64) {
+	t.Helper()
+	points, err := s.GetHistoricalPrices(coin, strconv.FormatInt(from.Unix(), 10), strconv.FormatInt(to.Unix(), 10))
+	if err != nil {
+		t.Fatalf("GetHistoricalPrices: %v", err)
+	}
+	times, prices := make([]time.Time, len(points)), make([]float64, len(points))
+	for i, point := range points {
+		times[i] = time.UnixMilli(int64(point["timestamp"].(float64))).UTC()
+		prices[i] = point["price"].(float64)
+	}
+	return times, prices
+}
+
+func TestFixtureDeterminism(t *testing.T) {
+	day := func(y int, m time.Month, d int) time.Time { return time.Date(y, m, d, 0, 0, 0, 0, time.UTC) }
+	tests := []struct {
+		name   string
+		seed   int64
+		coin   string
+		from   time.Time
+		to     time.Time
+		step   time.Duration
+		points int
+	}{
+		{name: "un día cada 5 minutos", seed: 42, coin: "bitcoin", from: day(2024, 1, 3), to: day(2024, 1, 4), step: walkStep, points: 289},
+		{name: "una semana por hora", seed: 7, coin: "ethereum", from: day(2024, 1, 1), to: day(2024, 1, 8), step: time.Hour, points: 169},
+		{name: "un año por día", seed: 42, coin: "solana", from: day(2024, 1, 1), to: day(2024, 12, 31), step: 24 * time.Hour, points: 366},
+		{name: "empieza antes del modelo", seed: 1, coin: "dogecoin", from: day(2023, 12, 30), to: day(2024, 1, 3), step: time.Hour, points: 97},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			times, prices := history(t, fixtureService(t, tt.seed), tt.coin, tt.from, tt.to)
+			if len(prices) != tt.points {
+				t.Fatalf("se obtuvieron %d precios, se esperaban %d", len(prices), tt.points)
+			}
+			for i := 1; i < len(times); i++ {
+				if times[i].Sub(times[i-1]) != tt.step {
+					t.Fatalf("entre %v y %v hay %v, se esperaba %v", times[i-1], times[i], times[i].Sub(times[i-1]), tt.step)
+				}
+			}
+
+			// La misma semilla da la misma serie aunque antes se hayan pedido otras fechas.
+			again := fixtureService(t, tt.seed)
+			history(t, again, tt.coin, tt.to.AddDate(0, 1, 0), tt.to.AddDate(0, 1, 1))
+			_, repeated := history(t, again, tt.coin, tt.from, tt.to)
+			for i := range prices {
+				if repeated[i] != prices[i] {
+					t.Fatalf("precio %d (%v) = %v, la primera vez fue %v", i, times[i], repeated[i], prices[i])
+				}
+			}
+
+			// Otra semilla da otra serie.
+			_, other := history(t, fixtureService(t, tt.seed+1), tt.coin, tt.from, tt.to)
+			differs := false
+			for i := range prices {
+				differs = differs || other[i] != prices[i]
+			}
+			if !differs {
+				t.Fatal("con otra semilla se obtuvo la misma serie")
+			}
+
+			// Cada punto del historial, incluidas las aperturas de cada día donde se engancha el puente browniano,
+			// coincide con el precio actual si el reloj está en ese momento.
+			current := fixtureService(t, tt.seed)
+			base := defaultFixtureCoins[tt.coin].price
+			for i, at := range times {
+				current.clock = at
+				price, err := current.GetCurrentPrice(tt.coin, "usd")
+				if err != nil {
+					t.Fatalf("GetCurrentPrice: %v", err)
+				}
+				if price != prices[i] {
+					t.Fatalf("en %v el historial da %v y el precio actual %v", at, prices[i], price)
+				}
+				if at.Before(current.walk.start) && math.Abs(price-base) > 1e-9*base {
+					t.Fatalf("antes del inicio del modelo el precio es %v, se esperaba el base %v", price, base)
+				}
+			}
+		})
+	}
+}
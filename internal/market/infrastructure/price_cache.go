@@ -0,0 +1,92 @@
+package infrastructure
+
+import (
+	"cryptoproject/internal/market/domain"
+	"sync"
+	"time"
+)
+
+// priceKey identifica un precio en el caché: la moneda y la divisa en la que está.
+type priceKey struct {
+	coin     string
+	currency string
+}
+
+// priceEntry es un precio guardado y si ya hay alguien pidiéndolo de nuevo.
+type priceEntry struct {
+	quote      domain.PriceQuote
+	refreshing bool
+}
+
+// priceCache guarda los últimos precios en memoria. Un precio vale ttl; vencido todavía se puede servir
+// hasta maxStale mientras se refresca o si CoinGecko está caído.
+type priceCache struct {
+	mu       sync.Mutex
+	ttl      time.Duration
+	maxStale time.Duration
+	entries  map[priceKey]*priceEntry
+}
+
+func newPriceCache(ttl, maxStale time.Duration) *priceCache {
+	return &priceCache{ttl: ttl, maxStale: maxStale, entries: make(map[priceKey]*priceEntry)}
+}
+
+// fresh devuelve el precio si está guardado y no venció.
+func (c *priceCache) fresh(key priceKey, now time.Time) (domain.PriceQuote, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	entry, ok := c.entries[key]
+	if !ok || entry.quote.Age(now) >= c.ttl {
+		return domain.PriceQuote{}, false
+	}
+	return entry.quote, true
+}
+
+// stale devuelve el precio vencido si todavía se puede servir, marcado como Stale.
+func (c *priceCache) stale(key priceKey, now time.Time) (domain.PriceQuote, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	entry, ok := c.entries[key]
+	if !ok || entry.quote.Age(now) > c.maxStale {
+		return domain.PriceQuote{}, false
+	}
+	quote := entry.quote
+	quote.Stale = true
+	return quote, true
+}
+
+// claim marca que el que llama va a refrescar el precio. Devuelve false si ya lo está refrescando otro.
+// Quien obtiene el claim tiene que llamar a release o a store al terminar.
+func (c *priceCache) claim(key priceKey) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	entry, ok := c.entries[key]
+	if !ok {
+		c.entries[key] = &priceEntry{refreshing: true}
+		return true
+	}
+	if entry.refreshing {
+		return false
+	}
+	entry.refreshing = true
+	return true
+}
+
+// release libera el claim sin cambiar el precio (el refresco falló).
+func (c *priceCache) release(key priceKey) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if entry, ok := c.entries[key]; ok {
+		entry.refreshing = false
+		if entry.quote.FetchedAt.IsZero() {
+			delete(c.entries, key) // Nunca tuvo precio, no dejamos una entrada vacía.
+		}
+	}
+}
+
+// store guarda el precio nuevo y libera el claim si lo había.
+func (c *priceCache) store(key priceKey, quote domain.PriceQuote) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries[key] = &priceEntry{quote: quote}
+}
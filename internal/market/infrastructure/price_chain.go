@@ -0,0 +1,252 @@
+package infrastructure
+
+import (
+	"context"
+	"cryptoproject/internal/market/domain"
+	"cryptoproject/pkg/logger"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// providerUp expone en /metrics si cada proveedor de mercado está sano (1) o apartado por fallas (0).
+var providerUp = promauto.NewGaugeVec(prometheus.GaugeOpts{
+	Name: "market_provider_up",
+	Help: "Si el proveedor de datos de mercado está sano (1) o apartado por fallas seguidas (0).",
+}, []string{"provider"})
+
+// providerHealth lleva las fallas seguidas de un proveedor y hasta cuándo queda apartado.
+type providerHealth struct {
+	failures  int
+	downUntil time.Time
+}
+
+// PriceChain combina varios proveedores en orden de preferencia: cada pedido va al primero sano
+// y, si falla, pasa al siguiente. Un proveedor que falla failureThreshold veces seguidas queda apartado
+// durante cooldown; si todos están apartados se prueban igual, porque algún precio es mejor que ninguno.
+type PriceChain struct {
+	providers        []domain.PriceProvider
+	failureThreshold int
+	cooldown         time.Duration
+	mu               sync.Mutex
+	health           map[string]*providerHealth
+}
+
+// NewPriceChain crea la cadena de proveedores.
+func NewPriceChain(providers []domain.PriceProvider, failureThreshold int, cooldown time.Duration) domain.PriceProvider {
+	health := make(map[string]*providerHealth, len(providers))
+	for _, provider := range providers {
+		health[provider.Name()] = &providerHealth{}
+		providerUp.WithLabelValues(provider.Name()).Set(1)
+	}
+	return &PriceChain{providers: providers, failureThreshold: failureThreshold, cooldown: cooldown, health: health}
+}
+
+// Name devuelve los proveedores de la cadena en orden.
+func (c *PriceChain) Name() string {
+	names := make([]string, 0, len(c.providers))
+	for _, provider := range c.providers {
+		names = append(names, provider.Name())
+	}
+	return strings.Join(names, ",")
+}
+
+// candidates devuelve los proveedores en el orden en que hay que probarlos: primero los sanos y al final los apartados.
+func (c *PriceChain) candidates(now time.Time) []domain.PriceProvider {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	healthy := make([]domain.PriceProvider, 0, len(c.providers))
+	down := make([]domain.PriceProvider, 0)
+	for _, provider := range c.providers {
+		if now.Before(c.health[provider.Name()].downUntil) {
+			down = append(down, provider)
+		} else {
+			healthy = append(healthy, provider)
+		}
+	}
+	return append(healthy, down...)
+}
+
+// errStaleQuote marca que el proveedor respondió con un precio vencido de su caché porque otro pedido lo está
+// refrescando. Es una falla blanda: se prueba con los demás, pero no cuenta para apartar al proveedor.
+// Si el vencido vino porque el proveedor no respondió, GetQuote trae además ese error y ese sí cuenta.
+var errStaleQuote = errors.New("el proveedor solo tiene un precio vencido")
+
+// record anota el resultado de un pedido. Que el proveedor no tenga la moneda, o que solo tenga un precio vencido
+// mientras se refresca, no cuenta como falla.
+func (c *PriceChain) record(provider domain.PriceProvider, err error) {
+	if errors.Is(err, domain.ErrUnsupportedCoin) || errors.Is(err, errStaleQuote) {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	health := c.health[provider.Name()]
+
+	if err == nil {
+		if health.failures >= c.failureThreshold {
+			logger.Info("Proveedor de mercado recuperado:", provider.Name())
+		}
+		health.failures, health.downUntil = 0, time.Time{}
+		providerUp.WithLabelValues(provider.Name()).Set(1)
+		return
+	}
+
+	health.failures++
+	if health.failures >= c.failureThreshold {
+		health.downUntil = time.Now().Add(c.cooldown)
+		providerUp.WithLabelValues(provider.Name()).Set(0)
+		logger.Warn("Proveedor de mercado apartado por", health.failures, "fallas seguidas:", provider.Name(), err)
+	}
+}
+
+// try corre op contra cada proveedor hasta que uno responda. Si fallan todos devuelve los errores juntos.
+// Si se cancela ctx no se sigue probando, y esa falla no se le cuenta al proveedor: el que se fue es el cliente.
+func (c *PriceChain) try(ctx context.Context, op func(provider domain.PriceProvider) error) error {
+	var errs []error
+	for _, provider := range c.candidates(time.Now()) {
+		err := op(provider)
+		if ctx.Err() != nil {
+			return errors.Join(append(errs, ctx.Err())...)
+		}
+		c.record(provider, err)
+		if err == nil {
+			return nil
+		}
+		errs = append(errs, fmt.Errorf("%s: %w", provider.Name(), err))
+	}
+	return errors.Join(errs...)
+}
+
+// GetCurrentPrice obtiene el precio del primer proveedor que responda.
+func (c *PriceChain) GetCurrentPrice(crypto, currency string) (float64, error) {
+	quote, err := c.GetQuote(crypto, currency)
+	if err != nil {
+		return 0, err
+	}
+	return quote.Price, nil
+}
+
+// GetPriceWithVolume obtiene precio y volumen del primer proveedor que responda.
+func (c *PriceChain) GetPriceWithVolume(crypto, currency string) (float64, float64, error) {
+	quote, err := c.GetQuote(crypto, currency)
+	if err != nil {
+		return 0, 0, err
+	}
+	return quote.Price, quote.Volume24h, nil
+}
+
+// GetQuote obtiene el precio con su antigüedad del primer proveedor que tenga un precio fresco.
+// Un precio vencido (ej. CoinGecko caído sirviendo su caché) se guarda como último recurso y solo se devuelve
+// si ningún otro proveedor responde.
+func (c *PriceChain) GetQuote(crypto, currency string) (domain.PriceQuote, error) {
+	return c.GetQuoteContext(context.Background(), crypto, currency)
+}
+
+// GetQuoteContext es GetQuote dejando de probar proveedores si se cancela ctx.
+func (c *PriceChain) GetQuoteContext(ctx context.Context, crypto, currency string) (domain.PriceQuote, error) {
+	var quote domain.PriceQuote
+	var stale *domain.PriceQuote
+	err := c.try(ctx, func(provider domain.PriceProvider) error {
+		candidate, err := provider.GetQuoteContext(ctx, crypto, currency)
+		if candidate.Stale && stale == nil {
+			stale = &candidate
+		}
+		if err != nil {
+			return err
+		}
+		if candidate.Stale {
+			return errStaleQuote
+		}
+		quote = candidate
+		return nil
+	})
+	if err != nil && stale != nil && ctx.Err() == nil {
+		return *stale, nil
+	}
+	return quote, err
+}
+
+// GetPrices junta los precios de varios proveedores: lo que uno no trae (o no conoce) se le pide al siguiente.
+func (c *PriceChain) GetPrices(cryptos []string, currencies []string) (map[string]map[string]float64, error) {
+	return c.GetPricesContext(context.Background(), cryptos, currencies)
+}
+
+// GetPricesContext es GetPrices dejando de probar proveedores si se cancela ctx.
+func (c *PriceChain) GetPricesContext(ctx context.Context, cryptos []string, currencies []string) (map[string]map[string]float64, error) {
+	result := make(map[string]map[string]float64)
+	remaining := cryptos
+	var errs []error
+	answered := false
+	for _, provider := range c.candidates(time.Now()) {
+		if len(remaining) == 0 {
+			break
+		}
+		data, err := provider.GetPricesContext(ctx, remaining, currencies)
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		c.record(provider, err)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", provider.Name(), err))
+			continue
+		}
+		answered = true
+
+		missing := make([]string, 0)
+		for _, crypto := range remaining {
+			complete := true
+			for _, currency := range currencies {
+				price, ok := data[crypto][currency]
+				if !ok {
+					complete = false
+					continue
+				}
+				if result[crypto] == nil {
+					result[crypto] = make(map[string]float64)
+				}
+				if _, exists := result[crypto][currency]; !exists {
+					result[crypto][currency] = price
+				}
+			}
+			if !complete {
+				missing = append(missing, crypto)
+			}
+		}
+		remaining = missing
+	}
+	if !answered {
+		return nil, errors.Join(errs...)
+	}
+	return result, nil
+}
+
+// GetHistoricalPrices obtiene la serie del primer proveedor que responda.
+func (c *PriceChain) GetHistoricalPrices(crypto, start, end string) ([]map[string]interface{}, error) {
+	return c.GetHistoricalPricesContext(context.Background(), crypto, start, end)
+}
+
+// GetHistoricalPricesContext es GetHistoricalPrices dejando de probar proveedores si se cancela ctx.
+func (c *PriceChain) GetHistoricalPricesContext(ctx context.Context, crypto, start, end string) ([]map[string]interface{}, error) {
+	var prices []map[string]interface{}
+	err := c.try(ctx, func(provider domain.PriceProvider) error {
+		var err error
+		prices, err = provider.GetHistoricalPricesContext(ctx, crypto, start, end)
+		return err
+	})
+	return prices, err
+}
+
+// CheckAPIStatus indica si al menos un proveedor responde.
+func (c *PriceChain) CheckAPIStatus() bool {
+	for _, provider := range c.candidates(time.Now()) {
+		if provider.CheckAPIStatus() {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,168 @@
+package infrastructure
+
+import (
+	"context"
+	"cryptoproject/internal/market/domain"
+	"cryptoproject/pkg/logger"
+	"errors"
+	"testing"
+	"time"
+)
+
+// fakeProvider responde siempre la misma cotización o el mismo error y cuenta los pedidos.
+// cancel, si está, se llama en cada pedido, como si el cliente se fuera mientras se espera al proveedor.
+type fakeProvider struct {
+	name   string
+	quote  domain.PriceQuote
+	err    error
+	calls  int
+	cancel context.CancelFunc
+}
+
+func (f *fakeProvider) Name() string { return f.name }
+func (f *fakeProvider) GetCurrentPrice(crypto, currency string) (float64, error) {
+	quote, err := f.GetQuote(crypto, currency)
+	return quote.Price, err
+}
+func (f *fakeProvider) GetPriceWithVolume(crypto, currency string) (float64, float64, error) {
+	quote, err := f.GetQuote(crypto, currency)
+	return quote.Price, quote.Volume24h, err
+}
+func (f *fakeProvider) GetQuote(crypto, currency string) (domain.PriceQuote, error) {
+	f.calls++
+	return f.quote, f.err
+}
+func (f *fakeProvider) GetQuoteContext(ctx context.Context, crypto, currency string) (domain.PriceQuote, error) {
+	if f.cancel != nil {
+		f.cancel()
+	}
+	return f.GetQuote(crypto, currency)
+}
+func (f *fakeProvider) GetPrices(cryptos, currencies []string) (map[string]map[string]float64, error) {
+	return nil, f.err
+}
+func (f *fakeProvider) GetPricesContext(ctx context.Context, cryptos, currencies []string) (map[string]map[string]float64, error) {
+	return nil, f.err
+}
+func (f *fakeProvider) GetHistoricalPrices(crypto, start, end string) ([]map[string]interface{}, error) {
+	return nil, f.err
+}
+func (f *fakeProvider) GetHistoricalPricesContext(ctx context.Context, crypto, start, end string) ([]map[string]interface{}, error) {
+	return nil, f.err
+}
+func (f *fakeProvider) CheckAPIStatus() bool { return f.err == nil }
+
+func TestPriceChainQuote(t *testing.T) {
+	logger.InitLogger()
+	stale := domain.PriceQuote{Price: 90, FetchedAt: time.Now().Add(-5 * time.Minute), Stale: true, Provider: "primary"}
+	fresh := domain.PriceQuote{Price: 100, FetchedAt: time.Now(), Provider: "backup"}
+	down := errors.New("caído")
+
+	tests := []struct {
+		name      string
+		primary   *fakeProvider
+		backup    *fakeProvider
+		wantPrice float64
+		wantStale bool
+		wantErr   bool
+	}{
+		{
+			name:      "el primero fresco gana",
+			primary:   &fakeProvider{name: "primary", quote: domain.PriceQuote{Price: 95, Provider: "primary"}},
+			backup:    &fakeProvider{name: "backup", quote: fresh},
+			wantPrice: 95,
+		},
+		{
+			name:      "un precio vencido pasa al siguiente",
+			primary:   &fakeProvider{name: "primary", quote: stale},
+			backup:    &fakeProvider{name: "backup", quote: fresh},
+			wantPrice: 100,
+		},
+		{
+			name:      "el vencido queda de último recurso",
+			primary:   &fakeProvider{name: "primary", quote: stale},
+			backup:    &fakeProvider{name: "backup", err: down},
+			wantPrice: 90,
+			wantStale: true,
+		},
+		{
+			name:      "el vencido de un proveedor caído también",
+			primary:   &fakeProvider{name: "primary", quote: stale, err: down},
+			backup:    &fakeProvider{name: "backup", err: down},
+			wantPrice: 90,
+			wantStale: true,
+		},
+		{
+			name:    "sin precios falla",
+			primary: &fakeProvider{name: "primary", err: down},
+			backup:  &fakeProvider{name: "backup", err: down},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			chain := NewPriceChain([]domain.PriceProvider{tt.primary, tt.backup}, 3, time.Minute)
+			quote, err := chain.GetQuote("bitcoin", "usd")
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("error = %v, se esperaba error: %v", err, tt.wantErr)
+			}
+			if quote.Price != tt.wantPrice || quote.Stale != tt.wantStale {
+				t.Fatalf("cotización = %+v, se esperaba precio %v y stale %v", quote, tt.wantPrice, tt.wantStale)
+			}
+		})
+	}
+}
+
+// Un precio vencido mientras se refresca no aparta al proveedor; servirlo porque el proveedor no respondió sí.
+func TestPriceChainStaleHealth(t *testing.T) {
+	logger.InitLogger()
+	tests := []struct {
+		name  string
+		err   error // Lo que devuelve el proveedor junto con el precio vencido.
+		calls int   // Pedidos que le llegan en tres vueltas con umbral 1.
+	}{
+		{name: "refresco en curso", calls: 3},
+		{name: "el proveedor no respondió", err: errors.New("caído"), calls: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			primary := &fakeProvider{name: "primary", quote: domain.PriceQuote{Price: 90, Stale: true}, err: tt.err}
+			backup := &fakeProvider{name: "backup", quote: domain.PriceQuote{Price: 100}}
+			chain := NewPriceChain([]domain.PriceProvider{primary, backup}, 1, time.Minute)
+
+			for i := 0; i < 3; i++ {
+				quote, err := chain.GetQuote("bitcoin", "usd")
+				if err != nil || quote.Price != 100 {
+					t.Fatalf("cotización = %+v, %v; se esperaba la del respaldo", quote, err)
+				}
+			}
+			// Apartado, el primario se prueba después del respaldo, que ya responde.
+			if primary.calls != tt.calls {
+				t.Fatalf("el proveedor con precio vencido recibió %d pedidos, se esperaban %d", primary.calls, tt.calls)
+			}
+		})
+	}
+}
+
+// Si el cliente se va mientras se espera al proveedor, la cadena no prueba con el siguiente
+// ni le cuenta la falla al proveedor.
+func TestPriceChainStopsWhenCancelled(t *testing.T) {
+	logger.InitLogger()
+	ctx, cancel := context.WithCancel(context.Background())
+	primary := &fakeProvider{name: "primary", err: context.Canceled, cancel: cancel}
+	backup := &fakeProvider{name: "backup", quote: domain.PriceQuote{Price: 100}}
+	chain := NewPriceChain([]domain.PriceProvider{primary, backup}, 1, time.Minute)
+
+	if _, err := chain.GetQuoteContext(ctx, "bitcoin", "usd"); !errors.Is(err, context.Canceled) {
+		t.Fatalf("error = %v, se esperaba context.Canceled", err)
+	}
+	if backup.calls != 0 {
+		t.Fatalf("el respaldo recibió %d pedidos con el cliente ya ido", backup.calls)
+	}
+
+	// El primario no quedó apartado: con un contexto nuevo sigue siendo el primero.
+	primary.err, primary.cancel, primary.quote = nil, nil, domain.PriceQuote{Price: 95}
+	if quote, err := chain.GetQuote("bitcoin", "usd"); err != nil || quote.Price != 95 {
+		t.Fatalf("cotización = %+v, %v; se esperaba la del primario", quote, err)
+	}
+}
@@ -0,0 +1,59 @@
+package infrastructure
+
+import (
+	"cryptoproject/internal/market/domain"
+	"cryptoproject/pkg/logger"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	priceProviderOnce sync.Once
+	priceProviderInst domain.PriceProvider
+)
+
+// NewPriceProvider arma la fuente de precios que usa toda la aplicación a partir de MARKET_PROVIDERS,
+// una lista en orden de preferencia (por defecto "coingecko,binance"). Con más de uno se combinan en una PriceChain.
+// "fixture" no sale a la red (ver FixtureService), para tests y demos.
+// Igual que NewCoingeckoService, se arma una sola vez para que todos compartan el caché y el estado de salud.
+func NewPriceProvider() domain.PriceProvider {
+	priceProviderOnce.Do(func() {
+		providers := make([]domain.PriceProvider, 0)
+		for _, name := range strings.Split(getEnv("MARKET_PROVIDERS", "coingecko,binance"), ",") {
+			switch strings.ToLower(strings.TrimSpace(name)) {
+			case coingeckoName:
+				providers = append(providers, NewCoingeckoService())
+			case binanceName:
+				providers = append(providers, NewBinanceService())
+			case fixtureName:
+				providers = append(providers, NewFixtureService())
+			case "":
+			default:
+				logger.Warn("Proveedor de mercado desconocido en MARKET_PROVIDERS, se ignora:", name)
+			}
+		}
+		if len(providers) == 0 {
+			logger.Warn("MARKET_PROVIDERS no tiene proveedores válidos, usando coingecko")
+			providers = append(providers, NewCoingeckoService())
+		}
+		if len(providers) == 1 {
+			priceProviderInst = providers[0]
+			return
+		}
+
+		threshold, err := strconv.Atoi(getEnv("MARKET_PROVIDER_FAILURE_THRESHOLD", "3"))
+		if err != nil || threshold < 1 {
+			logger.Warn("Error al parsear MARKET_PROVIDER_FAILURE_THRESHOLD, usando valor por defecto: 3")
+			threshold = 3
+		}
+		cooldown, err := time.ParseDuration(getEnv("MARKET_PROVIDER_COOLDOWN", "1m"))
+		if err != nil {
+			logger.Warn("Error al parsear MARKET_PROVIDER_COOLDOWN, usando valor por defecto: 1m")
+			cooldown = time.Minute
+		}
+		priceProviderInst = NewPriceChain(providers, threshold, cooldown)
+	})
+	return priceProviderInst
+}
@@ -0,0 +1,115 @@
+package infrastructure
+
+import (
+	"hash/fnv"
+	"math"
+	"math/rand"
+	"sync"
+	"time"
+)
+
+// walkStep es la resolución de los precios generados: dentro de cada tramo de 5 minutos el precio no cambia.
+const walkStep = 5 * time.Minute
+
+// walkStepsPerDay es la cantidad de tramos de un día.
+const walkStepsPerDay = int(24 * time.Hour / walkStep)
+
+// walkSeries guarda el logaritmo del precio al empezar cada día desde el inicio del modelo, y el generador
+// con el que se siguen agregando días. Se generan en orden, así el resultado no depende de qué fechas se pidieron antes.
+type walkSeries struct {
+	anchors []float64
+	rng     *rand.Rand
+}
+
+// randomWalk genera precios con una caminata aleatoria geométrica a partir de una semilla: con la misma semilla,
+// la misma moneda y el mismo momento, siempre da el mismo precio. Cada día se sortea el precio de cierre y dentro
+// del día se arma un puente browniano entre la apertura y el cierre, así cualquier momento se calcula sin
+// recorrer la serie entera.
+type randomWalk struct {
+	seed       int64
+	start      time.Time // Antes de start el precio es el de start.
+	volatility float64   // Desvío diario del logaritmo del precio (0.03 = 3% diario).
+	mu         sync.Mutex
+	series     map[string]*walkSeries
+}
+
+func newRandomWalk(seed int64, start time.Time, volatility float64) *randomWalk {
+	return &randomWalk{seed: seed, start: start, volatility: volatility, series: make(map[string]*walkSeries)}
+}
+
+// walkSeed mezcla la semilla con la moneda y un número (día u hora) para que cada tramo tenga su propio sorteo.
+func walkSeed(seed int64, coin string, n int64) int64 {
+	h := fnv.New64a()
+	h.Write([]byte(coin))
+	return seed ^ int64(h.Sum64()^uint64(n)*0x9E3779B97F4A7C15)
+}
+
+// anchor devuelve el logaritmo del precio al empezar el día, extendiendo la serie si hace falta.
+func (w *randomWalk) anchor(coin string, base float64, day int) float64 {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	s, ok := w.series[coin]
+	if !ok {
+		s = &walkSeries{anchors: []float64{math.Log(base)}, rng: rand.New(rand.NewSource(walkSeed(w.seed, coin, 0)))}
+		w.series[coin] = s
+	}
+	for len(s.anchors) <= day {
+		last := s.anchors[len(s.anchors)-1]
+		s.anchors = append(s.anchors, last+w.volatility*s.rng.NormFloat64())
+	}
+	return s.anchors[day]
+}
+
+// day arma el logaritmo del precio de cada tramo de 5 minutos del día.
+func (w *randomWalk) day(coin string, base float64, day int) []float64 {
+	from, to := w.anchor(coin, base, day), w.anchor(coin, base, day+1)
+	rng := rand.New(rand.NewSource(walkSeed(w.seed, coin, int64(day)+1)))
+	sigma := w.volatility / math.Sqrt(float64(walkStepsPerDay))
+
+	path := make([]float64, walkStepsPerDay+1)
+	for k := 1; k <= walkStepsPerDay; k++ {
+		path[k] = path[k-1] + sigma*rng.NormFloat64()
+	}
+	// Puente browniano: se corrige el camino para que termine justo en el cierre del día.
+	steps := make([]float64, walkStepsPerDay)
+	for k := range steps {
+		frac := float64(k) / float64(walkStepsPerDay)
+		steps[k] = from + frac*(to-from) + path[k] - frac*path[walkStepsPerDay]
+	}
+	return steps
+}
+
+// position ubica un momento en el modelo: qué día desde start y qué tramo dentro del día.
+func (w *randomWalk) position(t time.Time) (int, int) {
+	if t.Before(w.start) {
+		return 0, 0
+	}
+	elapsed := t.Sub(w.start)
+	return int(elapsed / (24 * time.Hour)), int(elapsed % (24 * time.Hour) / walkStep)
+}
+
+// Price devuelve el precio en USD de la moneda en el momento t, partiendo de base en start.
+func (w *randomWalk) Price(coin string, base float64, t time.Time) float64 {
+	day, step := w.position(t)
+	return math.Exp(w.day(coin, base, day)[step])
+}
+
+// Prices es como Price para varios momentos ordenados; reusa el día ya armado mientras no cambie.
+func (w *randomWalk) Prices(coin string, base float64, times []time.Time) []float64 {
+	prices := make([]float64, len(times))
+	current, steps := -1, []float64(nil)
+	for i, t := range times {
+		day, step := w.position(t)
+		if day != current {
+			current, steps = day, w.day(coin, base, day)
+		}
+		prices[i] = math.Exp(steps[step])
+	}
+	return prices
+}
+
+// Volume devuelve el volumen de 24 horas en USD: varía cada hora entre 75% y 125% de base.
+func (w *randomWalk) Volume(coin string, base float64, t time.Time) float64 {
+	hour := t.Unix() / int64(time.Hour/time.Second)
+	return base * (0.75 + 0.5*rand.New(rand.NewSource(walkSeed(w.seed, coin, hour))).Float64())
+}
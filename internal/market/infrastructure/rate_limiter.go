@@ -0,0 +1,130 @@
+package infrastructure
+
+import (
+	"context"
+	"errors"
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// rateLimitWait expone en /metrics cuánto espera cada pedido a un proveedor antes de salir, por el rate limit.
+var rateLimitWait = promauto.NewHistogramVec(prometheus.HistogramOpts{
+	Name:    "market_rate_limit_wait_seconds",
+	Help:    "Tiempo que espera un pedido al proveedor de mercado por el rate limit.",
+	Buckets: []float64{0.001, 0.01, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60},
+}, []string{"provider"})
+
+// errRateLimitWait se devuelve cuando no se llega a tener turno antes de que venza o se cancele el contexto.
+var errRateLimitWait = errors.New("rate limit: no hay turno antes de que venza el contexto")
+
+// defaultRetryAfter es cuánto se frena todo si el proveedor responde 429 sin Retry-After.
+const defaultRetryAfter = time.Minute
+
+// rateLimiter es un token bucket: se llena a razón de rate fichas por segundo hasta burst, y cada pedido gasta una.
+// Así se permiten ráfagas cortas sin pasarse del promedio. El lock solo se toma para reservar la ficha;
+// la espera se hace afuera, así los pedidos no se encolan detrás de uno que está durmiendo.
+type rateLimiter struct {
+	name         string
+	rate         float64 // Fichas por segundo; 0 es sin límite.
+	burst        float64
+	mu           sync.Mutex
+	tokens       float64 // Puede ser negativo: son los turnos ya reservados por pedidos que están esperando.
+	last         time.Time
+	blockedUntil time.Time // Hasta cuándo no se pide nada porque el proveedor respondió 429.
+}
+
+// newRateLimiter crea el limitador con perMinute pedidos por minuto en promedio y ráfagas de hasta burst.
+func newRateLimiter(name string, perMinute float64, burst int) *rateLimiter {
+	if burst < 1 {
+		burst = 1
+	}
+	return &rateLimiter{name: name, rate: perMinute / 60, burst: float64(burst), tokens: float64(burst), last: time.Now()}
+}
+
+// reserve toma una ficha y devuelve en qué momento se puede usar. Sin límite no hay fichas, pero un 429 igual frena.
+func (l *rateLimiter) reserve(now time.Time) time.Time {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	ready := now
+	if l.rate > 0 {
+		l.tokens = math.Min(l.burst, l.tokens+now.Sub(l.last).Seconds()*l.rate)
+		l.last = now
+		l.tokens--
+		if l.tokens < 0 {
+			ready = now.Add(time.Duration(-l.tokens / l.rate * float64(time.Second)))
+		}
+	}
+	if ready.Before(l.blockedUntil) {
+		ready = l.blockedUntil
+	}
+	return ready
+}
+
+// cancel devuelve una ficha reservada que no se llegó a usar.
+func (l *rateLimiter) cancel() {
+	if l.rate <= 0 {
+		return
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.tokens = math.Min(l.burst, l.tokens+1)
+}
+
+// Wait espera a que haya una ficha. Si el contexto vence antes, o ya se sabe que vencería, devuelve error sin gastarla.
+func (l *rateLimiter) Wait(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	now := time.Now()
+	ready := l.reserve(now)
+	delay := ready.Sub(now)
+	if delay <= 0 {
+		rateLimitWait.WithLabelValues(l.name).Observe(0)
+		return nil
+	}
+	if deadline, ok := ctx.Deadline(); ok && deadline.Before(ready) {
+		l.cancel()
+		return errRateLimitWait
+	}
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		rateLimitWait.WithLabelValues(l.name).Observe(delay.Seconds())
+		return nil
+	case <-ctx.Done():
+		l.cancel()
+		return errRateLimitWait
+	}
+}
+
+// Block frena todos los pedidos durante d, por ejemplo después de un 429.
+func (l *rateLimiter) Block(d time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if until := time.Now().Add(d); until.After(l.blockedUntil) {
+		l.blockedUntil = until
+	}
+}
+
+// retryAfter lee el header Retry-After, que puede venir en segundos o como fecha HTTP.
+func retryAfter(header http.Header) time.Duration {
+	value := header.Get("Retry-After")
+	if seconds, err := strconv.Atoi(value); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second
+	}
+	if date, err := http.ParseTime(value); err == nil {
+		if d := time.Until(date); d > 0 {
+			return d
+		}
+		return 0
+	}
+	return defaultRetryAfter
+}
@@ -0,0 +1,79 @@
+package application
+
+import (
+	portfolioDomain "cryptoproject/internal/portfolio/domain"
+	tradingDomain "cryptoproject/internal/trading/domain"
+	"cryptoproject/pkg/logger"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+)
+
+// defaultBenchmark es la moneda contra la que se compara la cartera si no se pasa ?benchmark.
+const defaultBenchmark = "bitcoin"
+
+// coinIDPattern valida el id de CoinGecko del benchmark (bitcoin, usd-coin, ...).
+var coinIDPattern = regexp.MustCompile(`^[a-z0-9-]+$`)
+
+// HandleAnalytics calcula el rendimiento de la cartera sobre la serie de patrimonio (ver HandleHistory):
+// rendimiento ponderado por tiempo y por dinero, máxima caída, volatilidad y Sharpe, comparado contra ?benchmark.
+// Los depósitos de /account/balance/add se toman como flujos de caja, así no cuentan como ganancia.
+func (pc *PortfolioController) HandleAnalytics(c *gin.Context) {
+	userID := c.GetString("user_id") // Recuperar ID del usuario desde el contexto JWT
+	userUUID, err := uuid.Parse(userID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID de usuario inválido"})
+		return
+	}
+
+	now := time.Now().UTC()
+	window, err := parseHistoryWindow(c, now)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	benchmark := strings.ToLower(c.DefaultQuery("benchmark", defaultBenchmark))
+	if !coinIDPattern.MatchString(benchmark) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "El benchmark es inválido"})
+		return
+	}
+	riskFree, err := decimal.NewFromString(c.DefaultQuery("risk_free", "0"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "La tasa libre de riesgo es inválida"})
+		return
+	}
+
+	points, err := pc.history(c.Request.Context(), userID, userUUID, window, now)
+	if err != nil {
+		logger.Error("Error al reconstruir la cartera:", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error al reconstruir el historial de la cartera"})
+		return
+	}
+	entries, err := pc.ledger.History(userUUID, tradingDomain.QuoteAsset, now)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error al obtener los depósitos"})
+		return
+	}
+
+	var prices portfolioDomain.PriceSeries
+	if len(points) > 0 {
+		from := strconv.FormatInt(points[0].Time.Add(-priceLookback).Unix(), 10)
+		to := strconv.FormatInt(points[len(points)-1].Time.Unix(), 10)
+		raw, err := pc.market.GetHistoricalPricesContext(c.Request.Context(), benchmark, from, to)
+		if err != nil {
+			logger.Error("Error al obtener los precios del benchmark:", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "No se pudieron obtener los precios del benchmark"})
+			return
+		}
+		prices = priceSeries(raw)
+	}
+
+	flows := portfolioDomain.DepositFlows(entries)
+	c.JSON(http.StatusOK, portfolioDomain.Analyze(points, flows, window.interval, riskFree, benchmark, prices))
+}
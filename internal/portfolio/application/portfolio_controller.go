@@ -0,0 +1,102 @@
+package application
+
+import (
+	authDomain "cryptoproject/internal/auth/domain"
+	ledgerApp "cryptoproject/internal/ledger/application"
+	marketDomain "cryptoproject/internal/market/domain"
+	portfolioDomain "cryptoproject/internal/portfolio/domain"
+	tradingDomain "cryptoproject/internal/trading/domain"
+	"cryptoproject/pkg/logger"
+	"errors"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// currencyPattern valida el código de divisa que se le pasa a CoinGecko (usd, eur, btc, ...).
+var currencyPattern = regexp.MustCompile(`^[a-z]{3,5}$`)
+
+// PortfolioController valúa la cartera del usuario con precios de mercado y arma su evolución en el tiempo.
+type PortfolioController struct {
+	userRepo        authDomain.UserRepository
+	holdingRepo     tradingDomain.HoldingRepository
+	transactionRepo tradingDomain.TransactionRepository
+	snapshotRepo    portfolioDomain.SnapshotRepository
+	ledger          *ledgerApp.Ledger
+	market          marketDomain.PriceProvider
+}
+
+// NewPortfolioController crea una nueva instancia de PortfolioController.
+func NewPortfolioController(
+	userRepo authDomain.UserRepository,
+	holdingRepo tradingDomain.HoldingRepository,
+	transactionRepo tradingDomain.TransactionRepository,
+	snapshotRepo portfolioDomain.SnapshotRepository,
+	ledger *ledgerApp.Ledger,
+	market marketDomain.PriceProvider,
+) *PortfolioController {
+	return &PortfolioController{
+		userRepo:        userRepo,
+		holdingRepo:     holdingRepo,
+		transactionRepo: transactionRepo,
+		snapshotRepo:    snapshotRepo,
+		ledger:          ledger,
+		market:          market,
+	}
+}
+
+// HandlePortfolio devuelve el valor de mercado de cada tenencia, su precio medio de entrada, el P&L no realizado,
+// el porcentaje de la cartera y el patrimonio total en la divisa de ?currency= (usd por defecto).
+// Todas las monedas se cotizan con una sola llamada al proveedor de mercado.
+func (pc *PortfolioController) HandlePortfolio(c *gin.Context) {
+	userID := c.GetString("user_id") // Recuperar ID del usuario desde el contexto JWT
+	userUUID, err := uuid.Parse(userID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID de usuario inválido"})
+		return
+	}
+
+	currency := strings.ToLower(c.DefaultQuery("currency", tradingDomain.QuoteAsset))
+	if !currencyPattern.MatchString(currency) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "La divisa es inválida"})
+		return
+	}
+
+	user, err := pc.userRepo.FindByID(userID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Usuario no encontrado"})
+		return
+	}
+	holdings, err := pc.holdingRepo.FindByUserID(userUUID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error al obtener las tenencias"})
+		return
+	}
+
+	currencies := []string{tradingDomain.QuoteAsset}
+	if currency != tradingDomain.QuoteAsset {
+		currencies = append(currencies, currency)
+	}
+	prices, err := pc.market.GetPricesContext(c.Request.Context(), portfolioDomain.PriceIDs(holdings), currencies)
+	if err != nil {
+		logger.Error("Error al cotizar la cartera:", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "No se pudo obtener el precio actual"})
+		return
+	}
+
+	valuation, err := portfolioDomain.Value(holdings, user.Balance, currency, prices, time.Now())
+	if errors.Is(err, portfolioDomain.ErrNoFXRate) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "La divisa no está soportada"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error al valuar la cartera"})
+		return
+	}
+
+	c.JSON(http.StatusOK, valuation)
+}
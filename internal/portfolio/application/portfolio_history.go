@@ -0,0 +1,162 @@
+package application
+
+import (
+	"context"
+	portfolioDomain "cryptoproject/internal/portfolio/domain"
+	tradingDomain "cryptoproject/internal/trading/domain"
+	"cryptoproject/pkg/dates"
+	"cryptoproject/pkg/logger"
+	"errors"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// defaultHistoryRange es cuánto para atrás mira la serie si no se pasa ?start.
+const defaultHistoryRange = 30 * 24 * time.Hour
+
+// priceLookback es cuánto antes del primer punto se piden precios, para tener uno conocido en ese instante.
+const priceLookback = 24 * time.Hour
+
+// HandleHistory devuelve la evolución del patrimonio en USD entre ?start y ?end, un punto cada ?interval.
+// Cada punto sale de la foto que guardó el job; los huecos se reconstruyen aplicando las transacciones
+// hasta ese momento y valuando con los precios históricos de CoinGecko.
+func (pc *PortfolioController) HandleHistory(c *gin.Context) {
+	userID := c.GetString("user_id") // Recuperar ID del usuario desde el contexto JWT
+	userUUID, err := uuid.Parse(userID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID de usuario inválido"})
+		return
+	}
+
+	now := time.Now().UTC()
+	window, err := parseHistoryWindow(c, now)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	points, err := pc.history(c.Request.Context(), userID, userUUID, window, now)
+	if err != nil {
+		logger.Error("Error al reconstruir la cartera:", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error al reconstruir el historial de la cartera"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"start":    window.start,
+		"end":      window.end,
+		"interval": window.interval.String(),
+		"points":   points,
+	})
+}
+
+// historyWindow es el rango y la frecuencia pedidos para la serie de patrimonio.
+type historyWindow struct {
+	start, end time.Time
+	interval   time.Duration
+	times      []time.Time
+}
+
+// parseHistoryWindow lee ?start, ?end e ?interval. end nunca pasa de now porque el futuro no se puede valuar.
+func parseHistoryWindow(c *gin.Context, now time.Time) (historyWindow, error) {
+	window := historyWindow{end: now}
+	if value := c.Query("end"); value != "" {
+		parsed, err := dates.ParseEnd(value)
+		if err != nil {
+			return window, errors.New("Fecha de fin inválida: " + err.Error())
+		}
+		if parsed.Before(window.end) {
+			window.end = parsed
+		}
+	}
+	window.start = window.end.Add(-defaultHistoryRange)
+	if value := c.Query("start"); value != "" {
+		parsed, err := dates.Parse(value)
+		if err != nil {
+			return window, errors.New("Fecha de inicio inválida: " + err.Error())
+		}
+		window.start = parsed
+	}
+
+	var err error
+	if window.interval, err = portfolioDomain.ParseHistoryInterval(c.Query("interval")); err != nil {
+		return window, err
+	}
+	window.times, err = portfolioDomain.HistoryTimes(window.start, window.end, window.interval)
+	return window, err
+}
+
+// history arma la serie de patrimonio de la ventana: fotos del job y, en los huecos, la cartera reconstruida.
+func (pc *PortfolioController) history(ctx context.Context, userID string, userUUID uuid.UUID, window historyWindow, now time.Time) ([]portfolioDomain.HistoryPoint, error) {
+	last := window.times[len(window.times)-1]
+	snapshots, err := pc.snapshotRepo.FindRange(userUUID, window.start, last.Add(window.interval))
+	if err != nil {
+		return nil, err
+	}
+
+	var replayer *portfolioDomain.Replayer
+	if missing := portfolioDomain.MissingTimes(window.times, window.interval, snapshots); len(missing) > 0 {
+		replayer, err = pc.replayer(ctx, userID, userUUID, missing, now)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return portfolioDomain.BuildHistory(window.times, window.interval, snapshots, replayer), nil
+}
+
+// replayer prepara lo necesario para reconstruir los instantes sin foto: el historial de transacciones,
+// los movimientos de saldo del libro mayor y los precios históricos de cada moneda operada.
+// Si una moneda no se puede cotizar, los puntos que la necesitan quedan afuera de la serie.
+func (pc *PortfolioController) replayer(ctx context.Context, userID string, userUUID uuid.UUID, missing []time.Time, now time.Time) (*portfolioDomain.Replayer, error) {
+	user, err := pc.userRepo.FindByID(userID)
+	if err != nil {
+		return nil, err
+	}
+	transactions, err := pc.transactionRepo.FindByUserID(userUUID)
+	if err != nil {
+		return nil, err
+	}
+	entries, err := pc.ledger.History(userUUID, tradingDomain.QuoteAsset, now)
+	if err != nil {
+		return nil, err
+	}
+
+	first, last := missing[0], missing[len(missing)-1]
+	from := strconv.FormatInt(first.Add(-priceLookback).Unix(), 10)
+	to := strconv.FormatInt(last.Unix(), 10)
+	prices := make(map[string]portfolioDomain.PriceSeries)
+	for _, coin := range portfolioDomain.TradedCoins(transactions, last.Add(time.Nanosecond)) {
+		raw, err := pc.market.GetHistoricalPricesContext(ctx, coin, from, to)
+		if err != nil {
+			logger.Warn("No se pudieron obtener los precios históricos de", coin, ":", err)
+			continue
+		}
+		prices[coin] = priceSeries(raw)
+	}
+
+	cash := portfolioDomain.NewCashTimeline(entries, user.Balance)
+	return portfolioDomain.NewReplayer(transactions, cash, prices), nil
+}
+
+// priceSeries convierte la respuesta de GetHistoricalPrices (timestamp en milisegundos y precio) en una serie ordenada.
+func priceSeries(raw []map[string]interface{}) portfolioDomain.PriceSeries {
+	series := make(portfolioDomain.PriceSeries, 0, len(raw))
+	for _, point := range raw {
+		timestamp, okTime := point["timestamp"].(float64)
+		price, okPrice := point["price"].(float64)
+		if !okTime || !okPrice {
+			continue
+		}
+		series = append(series, portfolioDomain.PricePoint{
+			Time:  time.UnixMilli(int64(timestamp)).UTC(),
+			Price: tradingDomain.PriceFromFloat(price),
+		})
+	}
+	sort.Slice(series, func(i, j int) bool { return series[i].Time.Before(series[j].Time) })
+	return series
+}
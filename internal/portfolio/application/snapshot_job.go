@@ -0,0 +1,139 @@
+package application
+
+import (
+	"context"
+	authDomain "cryptoproject/internal/auth/domain"
+	marketDomain "cryptoproject/internal/market/domain"
+	portfolioDomain "cryptoproject/internal/portfolio/domain"
+	tradingDomain "cryptoproject/internal/trading/domain"
+	"cryptoproject/pkg/database"
+	"cryptoproject/pkg/logger"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+)
+
+// SnapshotJob es el worker que guarda cada cierto tiempo el valor de la cartera de cada usuario.
+// Las fotos se guardan con la hora truncada al intervalo, así que si corre en varias réplicas no se duplican.
+type SnapshotJob struct {
+	uow          database.UnitOfWork
+	userRepo     authDomain.UserRepository
+	holdingRepo  tradingDomain.HoldingRepository
+	snapshotRepo portfolioDomain.SnapshotRepository
+	market       marketDomain.PriceProvider
+	interval     time.Duration
+}
+
+// NewSnapshotJob crea una nueva instancia de SnapshotJob.
+func NewSnapshotJob(
+	uow database.UnitOfWork,
+	userRepo authDomain.UserRepository,
+	holdingRepo tradingDomain.HoldingRepository,
+	snapshotRepo portfolioDomain.SnapshotRepository,
+	market marketDomain.PriceProvider,
+	interval time.Duration,
+) *SnapshotJob {
+	return &SnapshotJob{
+		uow:          uow,
+		userRepo:     userRepo,
+		holdingRepo:  holdingRepo,
+		snapshotRepo: snapshotRepo,
+		market:       market,
+		interval:     interval,
+	}
+}
+
+// Start corre el ciclo del job hasta que se cancele el contexto.
+func (j *SnapshotJob) Start(ctx context.Context) {
+	logger.Info("Job de fotos de cartera iniciado, intervalo:", j.interval)
+	ticker := time.NewTicker(j.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			logger.Info("Job de fotos de cartera detenido")
+			return
+		case <-ticker.C:
+			if err := j.TakeAll(ctx, time.Now()); err != nil {
+				logger.Error("Error al guardar las fotos de cartera:", err)
+			}
+		}
+	}
+}
+
+// TakeAll guarda la foto de cada usuario. Cada moneda se cotiza una sola vez por pasada;
+// si una falla, los usuarios que la tienen se saltean y se reintentan en la próxima.
+func (j *SnapshotJob) TakeAll(ctx context.Context, now time.Time) error {
+	userIDs, err := j.userRepo.FindIDs()
+	if err != nil {
+		return fmt.Errorf("error al obtener los usuarios: %w", err)
+	}
+
+	takenAt := now.UTC().Truncate(j.interval)
+	prices := make(map[string]decimal.Decimal)
+	failed := make(map[string]bool)
+	for _, userID := range userIDs {
+		if ctx.Err() != nil {
+			return nil
+		}
+		if err := j.take(userID, takenAt, prices, failed); err != nil {
+			logger.Error("Error al guardar la foto de cartera del usuario", userID, ":", err)
+		}
+	}
+	return nil
+}
+
+// take valúa la cartera de un usuario y guarda la foto. prices y failed son el caché de la pasada.
+func (j *SnapshotJob) take(userID string, takenAt time.Time, prices map[string]decimal.Decimal, failed map[string]bool) error {
+	userUUID, err := uuid.Parse(userID)
+	if err != nil {
+		return err
+	}
+
+	// Saldo y tenencias se leen de la misma foto de la base: si una compra confirma entre las dos lecturas,
+	// la foto de cartera contaría el USD gastado y también la cripto comprada (o ninguno de los dos).
+	var user *authDomain.User
+	var holdings []tradingDomain.Holding
+	err = j.uow.Read(func(tx *gorm.DB) error {
+		var err error
+		user, err = j.userRepo.WithTx(tx).FindByID(userID)
+		if err != nil {
+			return err
+		}
+		holdings, err = j.holdingRepo.WithTx(tx).FindByUserID(userUUID)
+		if err != nil {
+			return fmt.Errorf("error al obtener las tenencias: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, holding := range holdings {
+		if !holding.Amount.IsPositive() || failed[holding.Coin] {
+			continue
+		}
+		if _, ok := prices[holding.Coin]; ok {
+			continue
+		}
+		price, err := j.market.GetCurrentPrice(holding.Coin, tradingDomain.QuoteAsset)
+		if err != nil {
+			logger.Warn("No se pudo cotizar", holding.Coin, "para las fotos de cartera:", err)
+			failed[holding.Coin] = true
+			continue
+		}
+		prices[holding.Coin] = tradingDomain.PriceFromFloat(price)
+	}
+
+	snapshot, ok := portfolioDomain.NewPortfolioSnapshot(userUUID, takenAt, holdings, user.Balance, prices)
+	if !ok {
+		return errors.New("falta el precio de alguna moneda")
+	}
+	return j.snapshotRepo.Save(snapshot)
+}
@@ -0,0 +1,267 @@
+package domain
+
+import (
+	ledgerDomain "cryptoproject/internal/ledger/domain"
+	"math"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// year es la duración que se usa para anualizar.
+const year = 365 * 24 * time.Hour
+
+// CashFlow es dinero que entra a la cartera desde afuera. Los depósitos no son rendimiento:
+// si no se descuentan, cargar saldo parecería una ganancia.
+type CashFlow struct {
+	Time   time.Time
+	Amount decimal.Decimal
+}
+
+// DepositFlows saca los depósitos de los movimientos de la cuenta en USD del usuario, en el orden en que vienen.
+// Las compras y ventas solo mueven plata dentro de la cartera, así que no son flujos.
+func DepositFlows(entries []ledgerDomain.Entry) []CashFlow {
+	flows := make([]CashFlow, 0)
+	for _, entry := range entries {
+		if entry.Journal != nil && entry.Journal.Type == ledgerDomain.JournalDeposit {
+			flows = append(flows, CashFlow{Time: entry.CreatedAt, Amount: entry.Amount})
+		}
+	}
+	return flows
+}
+
+// flowsBetween suma los flujos en (from, to].
+func flowsBetween(flows []CashFlow, from, to time.Time) decimal.Decimal {
+	total := decimal.Zero
+	for _, flow := range flows {
+		if flow.Time.After(from) && !flow.Time.After(to) {
+			total = total.Add(flow.Amount)
+		}
+	}
+	return total
+}
+
+// ReturnStats son las métricas de rendimiento de una serie. Los porcentajes van con 2 decimales;
+// lo que no se puede calcular (ej. volatilidad con un solo período) queda en null.
+type ReturnStats struct {
+	TotalReturnPct      *decimal.Decimal `json:"total_return_pct"`
+	AnnualizedReturnPct *decimal.Decimal `json:"annualized_return_pct"`
+	VolatilityPct       *decimal.Decimal `json:"volatility_pct"` // Desvío de los retornos por período, anualizado.
+	SharpeRatio         *decimal.Decimal `json:"sharpe_ratio"`
+	MaxDrawdownPct      *decimal.Decimal `json:"max_drawdown_pct"` // Peor caída desde un máximo, en positivo.
+	DrawdownPeak        *time.Time       `json:"drawdown_peak"`
+	DrawdownTrough      *time.Time       `json:"drawdown_trough"`
+}
+
+// BenchmarkStats son las métricas de la moneda contra la que se compara la cartera.
+type BenchmarkStats struct {
+	Coin string `json:"coin"`
+	ReturnStats
+}
+
+// Analytics es el análisis de rendimiento de la cartera en una ventana.
+// El rendimiento ponderado por tiempo (TWR) descuenta los depósitos y mide la gestión; el ponderado por dinero (MWR)
+// es la tasa interna de retorno de lo que el usuario puso y mide cuánto ganó su plata según cuándo la puso.
+type Analytics struct {
+	Start       time.Time       `json:"start"`
+	End         time.Time       `json:"end"`
+	Interval    string          `json:"interval"`
+	Points      int             `json:"points"`
+	StartValue  decimal.Decimal `json:"start_value"`
+	EndValue    decimal.Decimal `json:"end_value"`
+	NetDeposits decimal.Decimal `json:"net_deposits"`
+	RiskFreePct decimal.Decimal `json:"risk_free_pct"`
+	ReturnStats
+	MoneyWeightedReturnPct           *decimal.Decimal `json:"money_weighted_return_pct"`
+	AnnualizedMoneyWeightedReturnPct *decimal.Decimal `json:"annualized_money_weighted_return_pct"`
+	Benchmark                        *BenchmarkStats  `json:"benchmark"`
+	ExcessReturnPct                  *decimal.Decimal `json:"excess_return_pct"` // TWR de la cartera menos el retorno del benchmark.
+}
+
+// Analyze calcula las métricas a partir de la serie de patrimonio (ver BuildHistory), los depósitos y los precios
+// del benchmark. riskFreePct es la tasa libre de riesgo anual en porcentaje, para el Sharpe.
+// Si benchmark no tiene precio en algún punto de la serie, la comparación queda en null.
+func Analyze(points []HistoryPoint, flows []CashFlow, interval time.Duration, riskFreePct decimal.Decimal, benchmarkCoin string, benchmark PriceSeries) *Analytics {
+	analytics := &Analytics{
+		Interval:    interval.String(),
+		Points:      len(points),
+		StartValue:  decimal.Zero,
+		EndValue:    decimal.Zero,
+		NetDeposits: decimal.Zero,
+		RiskFreePct: riskFreePct,
+	}
+	if len(points) == 0 {
+		return analytics
+	}
+	first, last := points[0], points[len(points)-1]
+	analytics.Start, analytics.End = first.Time, last.Time
+	analytics.StartValue, analytics.EndValue = first.NetWorth, last.NetWorth
+	analytics.NetDeposits = flowsBetween(flows, first.Time, last.Time)
+
+	riskFree := riskFreePct.InexactFloat64() / 100
+	times := make([]time.Time, len(points))
+	returns := make([]float64, 0, len(points))
+	for i, point := range points {
+		times[i] = point.Time
+		if i == 0 {
+			continue
+		}
+		// Los depósitos del período se restan del valor final: se asume que llegaron al cierre del período.
+		previous := points[i-1].NetWorth
+		if !previous.IsPositive() {
+			returns = append(returns, 0) // Sin capital no hay rendimiento que medir.
+			continue
+		}
+		flow := flowsBetween(flows, points[i-1].Time, point.Time)
+		returns = append(returns, point.NetWorth.Sub(flow).Div(previous).InexactFloat64()-1)
+	}
+	analytics.ReturnStats = returnStats(times, returns, interval, riskFree)
+
+	if mwr, ok := moneyWeightedReturn(points, flows); ok {
+		analytics.MoneyWeightedReturnPct = percent(mwr)
+		analytics.AnnualizedMoneyWeightedReturnPct = percent(annualize(mwr, last.Time.Sub(first.Time)))
+	}
+
+	if benchmarkCoin == "" {
+		return analytics
+	}
+	benchmarkReturns := make([]float64, 0, len(points))
+	previous := 0.0
+	for i, t := range times {
+		price, ok := benchmark.At(t)
+		if !ok || !price.IsPositive() {
+			return analytics
+		}
+		current := price.InexactFloat64()
+		if i > 0 {
+			benchmarkReturns = append(benchmarkReturns, current/previous-1)
+		}
+		previous = current
+	}
+	analytics.Benchmark = &BenchmarkStats{Coin: benchmarkCoin, ReturnStats: returnStats(times, benchmarkReturns, interval, riskFree)}
+	if analytics.TotalReturnPct != nil && analytics.Benchmark.TotalReturnPct != nil {
+		excess := analytics.TotalReturnPct.Sub(*analytics.Benchmark.TotalReturnPct)
+		analytics.ExcessReturnPct = &excess
+	}
+	return analytics
+}
+
+// returnStats calcula las métricas de una serie de retornos por período; times tiene un instante más que returns.
+// El drawdown se mide sobre el índice de retornos encadenados, así un depósito no tapa una caída.
+func returnStats(times []time.Time, returns []float64, interval time.Duration, riskFree float64) ReturnStats {
+	stats := ReturnStats{}
+	if len(returns) == 0 {
+		return stats
+	}
+
+	index, peak, maxDrawdown := 1.0, 1.0, 0.0
+	peakTime := times[0]
+	for i, r := range returns {
+		index *= 1 + r
+		if index > peak {
+			peak, peakTime = index, times[i+1]
+		}
+		if drawdown := (peak - index) / peak; drawdown > maxDrawdown {
+			maxDrawdown = drawdown
+			peakAt, troughAt := peakTime, times[i+1]
+			stats.DrawdownPeak, stats.DrawdownTrough = &peakAt, &troughAt
+		}
+	}
+	total := index - 1
+	stats.TotalReturnPct = percent(total)
+	stats.AnnualizedReturnPct = percent(annualize(total, times[len(times)-1].Sub(times[0])))
+	stats.MaxDrawdownPct = percent(maxDrawdown)
+
+	if len(returns) < 2 {
+		return stats
+	}
+	mean := 0.0
+	for _, r := range returns {
+		mean += r
+	}
+	mean /= float64(len(returns))
+	variance := 0.0
+	for _, r := range returns {
+		variance += (r - mean) * (r - mean)
+	}
+	deviation := math.Sqrt(variance / float64(len(returns)-1))
+
+	periodsPerYear := float64(year) / float64(interval)
+	stats.VolatilityPct = percent(deviation * math.Sqrt(periodsPerYear))
+	if deviation > 0 {
+		periodRiskFree := math.Pow(1+riskFree, 1/periodsPerYear) - 1
+		stats.SharpeRatio = round((mean - periodRiskFree) / deviation * math.Sqrt(periodsPerYear))
+	}
+	return stats
+}
+
+// moneyWeightedReturn busca la tasa r del período que hace que lo puesto (valor inicial y depósitos) crezca hasta el valor final:
+// V0*(1+r) + Σ Fk*(1+r)^(1-yk) = Vfinal, con yk la fracción del período en la que llegó cada depósito.
+// Se resuelve por bisección; devuelve false si no se puso nada o no hay solución.
+func moneyWeightedReturn(points []HistoryPoint, flows []CashFlow) (float64, bool) {
+	first, last := points[0], points[len(points)-1]
+	span := last.Time.Sub(first.Time)
+	if span <= 0 {
+		return 0, false
+	}
+	start := first.NetWorth.InexactFloat64()
+	end := last.NetWorth.InexactFloat64()
+	type weighted struct{ amount, remaining float64 }
+	deposits := make([]weighted, 0)
+	invested := start
+	for _, flow := range flows {
+		if flow.Time.After(first.Time) && !flow.Time.After(last.Time) {
+			fraction := float64(flow.Time.Sub(first.Time)) / float64(span)
+			deposits = append(deposits, weighted{amount: flow.Amount.InexactFloat64(), remaining: 1 - fraction})
+			invested += flow.Amount.InexactFloat64()
+		}
+	}
+	if invested <= 0 {
+		return 0, false
+	}
+
+	// grown es cuánto valdría lo puesto a la tasa r; crece con r, así que la bisección converge.
+	grown := func(r float64) float64 {
+		value := start * (1 + r)
+		for _, deposit := range deposits {
+			value += deposit.amount * math.Pow(1+r, deposit.remaining)
+		}
+		return value
+	}
+	low, high := -0.999999, 100.0
+	if grown(low) > end || grown(high) < end {
+		return 0, false
+	}
+	for i := 0; i < 200; i++ {
+		mid := (low + high) / 2
+		if grown(mid) < end {
+			low = mid
+		} else {
+			high = mid
+		}
+	}
+	return (low + high) / 2, true
+}
+
+// annualize lleva un retorno de un período de duración span a tasa anual compuesta.
+// Ventanas de menos de un año no se anualizan: extrapolar unos días de rendimiento da números que no dicen nada.
+func annualize(r float64, span time.Duration) float64 {
+	if span < year || 1+r <= 0 {
+		return math.NaN()
+	}
+	return math.Pow(1+r, float64(year)/float64(span)) - 1
+}
+
+// percent convierte una fracción en porcentaje con 2 decimales. Devuelve nil si el número no es finito.
+func percent(value float64) *decimal.Decimal {
+	return round(value * 100)
+}
+
+// round redondea a 2 decimales. Devuelve nil si el número no es finito.
+func round(value float64) *decimal.Decimal {
+	if math.IsNaN(value) || math.IsInf(value, 0) {
+		return nil
+	}
+	result := decimal.NewFromFloat(value).Round(2)
+	return &result
+}
@@ -0,0 +1,122 @@
+package domain
+
+import (
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+func TestAnalyze(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	day := 24 * time.Hour
+	series := func(step time.Duration, values ...int64) []HistoryPoint {
+		points := make([]HistoryPoint, len(values))
+		for i, value := range values {
+			points[i] = HistoryPoint{Time: start.Add(time.Duration(i) * step), NetWorth: decimal.NewFromInt(value)}
+		}
+		return points
+	}
+	prices := func(values ...int64) PriceSeries {
+		series := make(PriceSeries, len(values))
+		for i, value := range values {
+			series[i] = PricePoint{Time: start.Add(time.Duration(i) * day), Price: decimal.NewFromInt(value)}
+		}
+		return series
+	}
+	deposit := func(at time.Duration, amount int64) CashFlow {
+		return CashFlow{Time: start.Add(at), Amount: decimal.NewFromInt(amount)}
+	}
+
+	// Las métricas esperadas son porcentajes con 2 decimales; "" significa null.
+	tests := []struct {
+		name      string
+		points    []HistoryPoint
+		step      time.Duration
+		flows     []CashFlow
+		riskFree  string
+		benchmark PriceSeries
+		twr       string
+		annual    string
+		mwr       string
+		drawdown  string
+		vol       string
+		sharpe    string
+		excess    string
+	}{
+		{
+			name: "sube, cae y se recupera", points: series(day, 100, 110, 99, 121), step: day, riskFree: "0",
+			twr: "21", mwr: "21", drawdown: "10", vol: "310.78", sharpe: "8.7",
+		},
+		{
+			name: "la tasa libre de riesgo baja el Sharpe", points: series(day, 100, 110, 99, 121), step: day, riskFree: "5",
+			twr: "21", mwr: "21", drawdown: "10", vol: "310.78", sharpe: "8.68",
+		},
+		{
+			name: "un depósito no es rendimiento", points: series(day, 100, 200, 220), step: day, riskFree: "0", flows: []CashFlow{deposit(day, 100)},
+			twr: "10", mwr: "13.48", drawdown: "0", vol: "135.09", sharpe: "13.51",
+		},
+		{
+			name: "contra un benchmark que duplica", points: series(day, 100, 110, 99, 121), step: day, riskFree: "0", benchmark: prices(10, 10, 10, 20),
+			twr: "21", mwr: "21", drawdown: "10", vol: "310.78", sharpe: "8.7", excess: "-79",
+		},
+		{
+			name: "un año se anualiza", points: series(year, 100, 150), step: year, riskFree: "0",
+			twr: "50", annual: "50", mwr: "50", drawdown: "0",
+		},
+		{
+			name: "un solo punto", points: series(day, 100), step: day, riskFree: "0",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			benchmarkCoin := ""
+			if tt.benchmark != nil {
+				benchmarkCoin = "bitcoin"
+			}
+			analytics := Analyze(tt.points, tt.flows, tt.step, decimal.RequireFromString(tt.riskFree), benchmarkCoin, tt.benchmark)
+
+			metrics := []struct {
+				name  string
+				value *decimal.Decimal
+				want  string
+			}{
+				{"TWR", analytics.TotalReturnPct, tt.twr},
+				{"TWR anualizado", analytics.AnnualizedReturnPct, tt.annual},
+				{"MWR", analytics.MoneyWeightedReturnPct, tt.mwr},
+				{"drawdown", analytics.MaxDrawdownPct, tt.drawdown},
+				{"volatilidad", analytics.VolatilityPct, tt.vol},
+				{"Sharpe", analytics.SharpeRatio, tt.sharpe},
+				{"exceso sobre el benchmark", analytics.ExcessReturnPct, tt.excess},
+			}
+			for _, metric := range metrics {
+				switch {
+				case metric.want == "" && metric.value != nil:
+					t.Errorf("%s = %s, se esperaba null", metric.name, metric.value)
+				case metric.want != "" && metric.value == nil:
+					t.Errorf("%s = null, se esperaba %s", metric.name, metric.want)
+				case metric.want != "" && !metric.value.Equal(decimal.RequireFromString(metric.want)):
+					t.Errorf("%s = %s, se esperaba %s", metric.name, metric.value, metric.want)
+				}
+			}
+		})
+	}
+}
+
+// El drawdown marca el máximo y el mínimo de la peor caída, no de la primera.
+func TestAnalyzeDrawdownWindow(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	values := []int64{100, 90, 120, 60, 130}
+	points := make([]HistoryPoint, len(values))
+	for i, value := range values {
+		points[i] = HistoryPoint{Time: start.AddDate(0, 0, i), NetWorth: decimal.NewFromInt(value)}
+	}
+
+	analytics := Analyze(points, nil, 24*time.Hour, decimal.Zero, "", nil)
+	if !analytics.MaxDrawdownPct.Equal(decimal.NewFromInt(50)) {
+		t.Fatalf("drawdown = %s, se esperaba 50", analytics.MaxDrawdownPct)
+	}
+	if !analytics.DrawdownPeak.Equal(start.AddDate(0, 0, 2)) || !analytics.DrawdownTrough.Equal(start.AddDate(0, 0, 3)) {
+		t.Fatalf("caída entre %v y %v, se esperaba entre el día 2 y el 3", analytics.DrawdownPeak, analytics.DrawdownTrough)
+	}
+}
@@ -0,0 +1,253 @@
+package domain
+
+import (
+	ledgerDomain "cryptoproject/internal/ledger/domain"
+	tradingDomain "cryptoproject/internal/trading/domain"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// Origen de cada punto de la serie histórica.
+const (
+	HistorySourceSnapshot = "snapshot" // Foto guardada por el job.
+	HistorySourceReplay   = "replay"   // Reconstruido con el historial y precios históricos.
+)
+
+// Límites de la serie histórica.
+const (
+	DefaultHistoryInterval = 24 * time.Hour
+	MinHistoryInterval     = 5 * time.Minute // CoinGecko no da precios históricos más finos que eso.
+	MaxHistoryPoints       = 1000
+)
+
+// ParseHistoryInterval lee el intervalo entre puntos: una duración de Go ("1h", "30m") o días ("1d", "7d").
+// Vacío significa un día.
+func ParseHistoryInterval(value string) (time.Duration, error) {
+	if value == "" {
+		return DefaultHistoryInterval, nil
+	}
+	var interval time.Duration
+	if days, found := strings.CutSuffix(value, "d"); found {
+		n, err := strconv.Atoi(days)
+		if err != nil {
+			return 0, errors.New("el intervalo es inválido")
+		}
+		interval = time.Duration(n) * 24 * time.Hour
+	} else {
+		parsed, err := time.ParseDuration(value)
+		if err != nil {
+			return 0, errors.New("el intervalo es inválido")
+		}
+		interval = parsed
+	}
+	if interval < MinHistoryInterval {
+		return 0, fmt.Errorf("el intervalo mínimo es %s", MinHistoryInterval)
+	}
+	return interval, nil
+}
+
+// HistoryTimes devuelve los instantes de la serie: desde start cada interval, sin llegar a end.
+func HistoryTimes(start, end time.Time, interval time.Duration) ([]time.Time, error) {
+	if !start.Before(end) {
+		return nil, errors.New("la fecha de inicio debe ser anterior a la fecha de fin")
+	}
+	if (end.Sub(start)-1)/interval >= MaxHistoryPoints {
+		return nil, fmt.Errorf("el rango pedido supera los %d puntos, usa un intervalo más grande", MaxHistoryPoints)
+	}
+	times := make([]time.Time, 0)
+	for t := start; t.Before(end); t = t.Add(interval) {
+		times = append(times, t)
+	}
+	return times, nil
+}
+
+// HistoryPoint es el valor de la cartera en USD en un instante de la serie.
+type HistoryPoint struct {
+	Time          time.Time       `json:"time"`
+	Cash          decimal.Decimal `json:"cash"`
+	HoldingsValue decimal.Decimal `json:"holdings_value"`
+	NetWorth      decimal.Decimal `json:"net_worth"`
+	Source        string          `json:"source"`
+}
+
+// snapshotFor busca la primera foto dentro del tramo [t, t+interval). Las fotos vienen ordenadas por TakenAt.
+func snapshotFor(t time.Time, interval time.Duration, snapshots []PortfolioSnapshot) (*PortfolioSnapshot, bool) {
+	i := sort.Search(len(snapshots), func(i int) bool { return !snapshots[i].TakenAt.Before(t) })
+	if i < len(snapshots) && snapshots[i].TakenAt.Before(t.Add(interval)) {
+		return &snapshots[i], true
+	}
+	return nil, false
+}
+
+// MissingTimes devuelve los instantes de la serie que no tienen foto en su tramo y hay que reconstruir.
+func MissingTimes(times []time.Time, interval time.Duration, snapshots []PortfolioSnapshot) []time.Time {
+	missing := make([]time.Time, 0)
+	for _, t := range times {
+		if _, ok := snapshotFor(t, interval, snapshots); !ok {
+			missing = append(missing, t)
+		}
+	}
+	return missing
+}
+
+// BuildHistory arma la serie: cada instante usa la foto de su tramo y, si no hay, lo que reconstruya replay.
+// Los instantes que no se pueden reconstruir (ej. sin precio para una moneda) quedan afuera.
+// replay se llama con los instantes en orden creciente y puede ser nil si no falta ninguno.
+func BuildHistory(times []time.Time, interval time.Duration, snapshots []PortfolioSnapshot, replay *Replayer) []HistoryPoint {
+	points := make([]HistoryPoint, 0, len(times))
+	for _, t := range times {
+		if snapshot, ok := snapshotFor(t, interval, snapshots); ok {
+			points = append(points, HistoryPoint{
+				Time:          t,
+				Cash:          snapshot.Cash,
+				HoldingsValue: snapshot.HoldingsValue,
+				NetWorth:      snapshot.NetWorth,
+				Source:        HistorySourceSnapshot,
+			})
+			continue
+		}
+		if replay == nil {
+			continue
+		}
+		if point, ok := replay.ValueAt(t); ok {
+			points = append(points, point)
+		}
+	}
+	return points
+}
+
+// PricePoint es un precio histórico en USD.
+type PricePoint struct {
+	Time  time.Time
+	Price decimal.Decimal
+}
+
+// PriceSeries son los precios históricos de una moneda, ordenados por fecha.
+type PriceSeries []PricePoint
+
+// At devuelve el último precio conocido en t.
+func (s PriceSeries) At(t time.Time) (decimal.Decimal, bool) {
+	i := sort.Search(len(s), func(i int) bool { return s[i].Time.After(t) })
+	if i == 0 {
+		return decimal.Zero, false
+	}
+	return s[i-1].Price, true
+}
+
+// CashTimeline es el saldo en USD del usuario a lo largo del tiempo, sacado de su cuenta en el libro mayor.
+type CashTimeline struct {
+	initial decimal.Decimal
+	entries []ledgerDomain.Entry
+}
+
+// NewCashTimeline arma la línea de tiempo con todos los movimientos de la cuenta, del más viejo al más nuevo.
+// Sin movimientos el saldo nunca cambió, así que vale current en todo momento. Antes del primer movimiento
+// se toma lo que había antes de él; si es el asiento de apertura, lo que abrió (ej. los 1000 USD iniciales).
+func NewCashTimeline(entries []ledgerDomain.Entry, current decimal.Decimal) *CashTimeline {
+	timeline := &CashTimeline{initial: current}
+	for _, entry := range entries {
+		if entry.BalanceAfter.Valid {
+			timeline.entries = append(timeline.entries, entry)
+		}
+	}
+	if len(timeline.entries) > 0 {
+		first := timeline.entries[0]
+		timeline.initial = first.BalanceAfter.Decimal
+		if first.Journal == nil || first.Journal.Type != ledgerDomain.JournalOpeningBalance {
+			timeline.initial = first.BalanceAfter.Decimal.Sub(first.Amount)
+		}
+	}
+	return timeline
+}
+
+// At devuelve el saldo en t.
+func (c *CashTimeline) At(t time.Time) decimal.Decimal {
+	i := sort.Search(len(c.entries), func(i int) bool { return c.entries[i].CreatedAt.After(t) })
+	if i == 0 {
+		return c.initial
+	}
+	return c.entries[i-1].BalanceAfter.Decimal
+}
+
+// Replayer reconstruye el valor de la cartera en instantes pasados: aplica las transacciones hasta ese momento
+// y valúa las tenencias con precios históricos.
+type Replayer struct {
+	transactions []tradingDomain.Transaction
+	cash         *CashTimeline
+	prices       map[string]PriceSeries
+	positions    map[string]*tradingDomain.Position
+	applied      int
+}
+
+// NewReplayer crea el reconstructor. Las transacciones tienen que venir ordenadas por fecha.
+func NewReplayer(transactions []tradingDomain.Transaction, cash *CashTimeline, prices map[string]PriceSeries) *Replayer {
+	return &Replayer{
+		transactions: transactions,
+		cash:         cash,
+		prices:       prices,
+		positions:    make(map[string]*tradingDomain.Position),
+	}
+}
+
+// TradedCoins devuelve las monedas operadas antes de end, que son las que puede hacer falta cotizar para reconstruir.
+// Las transacciones vienen ordenadas por fecha.
+func TradedCoins(transactions []tradingDomain.Transaction, end time.Time) []string {
+	seen := make(map[string]bool)
+	coins := make([]string, 0)
+	for _, tx := range transactions {
+		if !tx.Timestamp.Before(end) {
+			break
+		}
+		if !seen[tx.Coin] {
+			seen[tx.Coin] = true
+			coins = append(coins, tx.Coin)
+		}
+	}
+	sort.Strings(coins)
+	return coins
+}
+
+// ValueAt devuelve el valor de la cartera en t. Devuelve false si falta el precio de alguna moneda con saldo.
+// Se tiene que llamar con t creciente: las transacciones se aplican una sola vez. Una transacción que no cierra
+// (ej. una venta de más) se saltea y la posición queda como estaba, igual que en BuildPositions.
+func (r *Replayer) ValueAt(t time.Time) (HistoryPoint, bool) {
+	for ; r.applied < len(r.transactions) && !r.transactions[r.applied].Timestamp.After(t); r.applied++ {
+		tx := r.transactions[r.applied]
+		position, ok := r.positions[tx.Coin]
+		if !ok {
+			position = &tradingDomain.Position{Coin: tx.Coin, Amount: decimal.Zero, CostBasis: decimal.Zero}
+			r.positions[tx.Coin] = position
+		}
+		if _, err := position.Apply(tx); err != nil {
+			// Un historial inconsistente no debería cortar la serie; dejamos la posición como está.
+			continue
+		}
+	}
+
+	holdingsValue := decimal.Zero
+	for coin, position := range r.positions {
+		if !position.Amount.IsPositive() {
+			continue
+		}
+		price, ok := r.prices[coin].At(t)
+		if !ok {
+			return HistoryPoint{}, false
+		}
+		holdingsValue = holdingsValue.Add(tradingDomain.RoundQuote(price.Mul(position.Amount)))
+	}
+
+	cash := r.cash.At(t)
+	return HistoryPoint{
+		Time:          t,
+		Cash:          cash,
+		HoldingsValue: holdingsValue,
+		NetWorth:      cash.Add(holdingsValue),
+		Source:        HistorySourceReplay,
+	}, true
+}
@@ -0,0 +1,43 @@
+package domain
+
+import (
+	tradingDomain "cryptoproject/internal/trading/domain"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// Una venta de más en el historial se saltea: la posición queda como estaba y se sigue valuando con lo que viene.
+func TestReplayerSkipsInconsistentTransactions(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	trade := func(hours int, side string, amount int64) tradingDomain.Transaction {
+		return tradingDomain.Transaction{Coin: "bitcoin", Side: side, Amount: decimal.NewFromInt(amount), Price: decimal.NewFromInt(100), Timestamp: start.Add(time.Duration(hours) * time.Hour)}
+	}
+	transactions := []tradingDomain.Transaction{
+		trade(1, tradingDomain.SideBuy, 2),
+		trade(2, tradingDomain.SideSell, 5), // No cierra: solo hay 2.
+		trade(3, tradingDomain.SideBuy, 1),
+	}
+	prices := map[string]PriceSeries{"bitcoin": {{Time: start, Price: decimal.NewFromInt(10)}}}
+	replayer := NewReplayer(transactions, NewCashTimeline(nil, decimal.NewFromInt(50)), prices)
+
+	tests := []struct {
+		hours    int
+		holdings int64
+	}{
+		{hours: 0, holdings: 0},
+		{hours: 1, holdings: 20},
+		{hours: 2, holdings: 20},
+		{hours: 3, holdings: 30},
+	}
+	for _, tt := range tests {
+		point, ok := replayer.ValueAt(start.Add(time.Duration(tt.hours) * time.Hour))
+		if !ok {
+			t.Fatalf("a las %d horas no se pudo valuar la cartera", tt.hours)
+		}
+		if !point.HoldingsValue.Equal(decimal.NewFromInt(tt.holdings)) || !point.NetWorth.Equal(decimal.NewFromInt(50+tt.holdings)) {
+			t.Fatalf("a las %d horas: tenencias %s, patrimonio %s; se esperaba %d y %d", tt.hours, point.HoldingsValue, point.NetWorth, tt.holdings, 50+tt.holdings)
+		}
+	}
+}
@@ -0,0 +1,57 @@
+package domain
+
+import (
+	tradingDomain "cryptoproject/internal/trading/domain"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+)
+
+// PortfolioSnapshot es el valor de la cartera de un usuario en USD en un momento dado.
+// TakenAt se trunca al intervalo del job, así el índice único evita duplicados si corren varias réplicas.
+type PortfolioSnapshot struct {
+	ID            uint64          `gorm:"primaryKey;autoIncrement"`
+	UserID        uuid.UUID       `gorm:"type:uuid;not null;uniqueIndex:idx_portfolio_snapshots_user_taken,priority:1"`
+	TakenAt       time.Time       `gorm:"not null;uniqueIndex:idx_portfolio_snapshots_user_taken,priority:2"`
+	Cash          decimal.Decimal `gorm:"type:numeric;not null"`
+	HoldingsValue decimal.Decimal `gorm:"type:numeric;not null"`
+	NetWorth      decimal.Decimal `gorm:"type:numeric;not null"`
+	CreatedAt     time.Time       `gorm:"autoCreateTime"`
+}
+
+// TableName fija el nombre de la tabla.
+func (PortfolioSnapshot) TableName() string { return "portfolio_snapshots" }
+
+// NewPortfolioSnapshot valúa las tenencias con prices (moneda -> precio en USD) y arma la foto.
+// Devuelve false si falta el precio de alguna moneda con saldo: una foto incompleta mostraría una caída que no existió.
+func NewPortfolioSnapshot(userID uuid.UUID, takenAt time.Time, holdings []tradingDomain.Holding, cash decimal.Decimal, prices map[string]decimal.Decimal) (*PortfolioSnapshot, bool) {
+	holdingsValue := decimal.Zero
+	for _, holding := range holdings {
+		if !holding.Amount.IsPositive() {
+			continue
+		}
+		price, ok := prices[holding.Coin]
+		if !ok {
+			return nil, false
+		}
+		holdingsValue = holdingsValue.Add(tradingDomain.RoundQuote(price.Mul(holding.Amount)))
+	}
+	return &PortfolioSnapshot{
+		UserID:        userID,
+		TakenAt:       takenAt,
+		Cash:          cash,
+		HoldingsValue: holdingsValue,
+		NetWorth:      cash.Add(holdingsValue),
+	}, true
+}
+
+// SnapshotRepository define el acceso a las fotos de cartera.
+type SnapshotRepository interface {
+	// Save guarda la foto; si ya hay una del mismo usuario en el mismo TakenAt no hace nada.
+	Save(snapshot *PortfolioSnapshot) error
+	// FindRange devuelve las fotos del usuario con TakenAt en [start, end), de la más vieja a la más nueva.
+	FindRange(userID uuid.UUID, start, end time.Time) ([]PortfolioSnapshot, error)
+	WithTx(tx *gorm.DB) SnapshotRepository
+}
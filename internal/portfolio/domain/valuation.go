@@ -0,0 +1,139 @@
+package domain
+
+import (
+	tradingDomain "cryptoproject/internal/trading/domain"
+	"errors"
+	"sort"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// FXReferenceCoin es la moneda que se pide siempre junto con la cartera para sacar la cotización USD -> divisa,
+// así la valuación en otra divisa sigue siendo una sola llamada aunque el usuario no tenga criptomonedas.
+const FXReferenceCoin = "bitcoin"
+
+// ErrNoFXRate se devuelve cuando no se puede convertir de USD a la divisa pedida.
+var ErrNoFXRate = errors.New("no se pudo obtener la cotización de la divisa pedida")
+
+var hundred = decimal.NewFromInt(100)
+
+// PositionValue es una tenencia valuada en la divisa pedida.
+// Si no hubo precio para la moneda, Priced es false y los campos de valuación quedan en cero.
+type PositionValue struct {
+	Coin              string          `json:"coin"`
+	Amount            decimal.Decimal `json:"amount"`
+	Priced            bool            `json:"priced"`
+	Price             decimal.Decimal `json:"price"`
+	MarketValue       decimal.Decimal `json:"market_value"`
+	CostBasis         decimal.Decimal `json:"cost_basis"`
+	AverageEntryPrice decimal.Decimal `json:"average_entry_price"`
+	UnrealizedPnL     decimal.Decimal `json:"unrealized_pnl"`
+	UnrealizedPnLPct  decimal.Decimal `json:"unrealized_pnl_pct"`
+	AllocationPct     decimal.Decimal `json:"allocation_pct"`
+}
+
+// Valuation es la foto de la cartera de un usuario en una divisa.
+type Valuation struct {
+	Currency      string          `json:"currency"`
+	FXRate        decimal.Decimal `json:"fx_rate"` // Unidades de la divisa por 1 USD.
+	Cash          decimal.Decimal `json:"cash"`
+	CashPct       decimal.Decimal `json:"cash_allocation_pct"`
+	HoldingsValue decimal.Decimal `json:"holdings_value"`
+	CostBasis     decimal.Decimal `json:"cost_basis"`
+	UnrealizedPnL decimal.Decimal `json:"unrealized_pnl"`
+	NetWorth      decimal.Decimal `json:"net_worth"`
+	Positions     []PositionValue `json:"positions"`
+	ValuedAt      time.Time       `json:"valued_at"`
+}
+
+// FXRate saca cuántas unidades de currency vale 1 USD a partir de los precios de CoinGecko (moneda -> divisa -> precio).
+func FXRate(currency string, prices map[string]map[string]float64) (decimal.Decimal, error) {
+	if currency == tradingDomain.QuoteAsset {
+		return decimal.NewFromInt(1), nil
+	}
+	coins := make([]string, 0, len(prices))
+	for coin := range prices {
+		coins = append(coins, coin)
+	}
+	sort.Strings(coins)
+	// Preferimos la moneda de referencia; si no vino, cualquier otra con los dos precios sirve.
+	coins = append([]string{FXReferenceCoin}, coins...)
+	for _, coin := range coins {
+		usd, quote := prices[coin][tradingDomain.QuoteAsset], prices[coin][currency]
+		if usd > 0 && quote > 0 {
+			return decimal.NewFromFloat(quote).Div(decimal.NewFromFloat(usd)).Round(tradingDomain.PricePlaces), nil
+		}
+	}
+	return decimal.Zero, ErrNoFXRate
+}
+
+// Value valúa las tenencias y el saldo en USD en la divisa pedida. El costo base, que se guarda en USD,
+// se convierte con la cotización actual para que el P&L no realizado quede en la misma divisa.
+func Value(holdings []tradingDomain.Holding, cashUSD decimal.Decimal, currency string, prices map[string]map[string]float64, now time.Time) (*Valuation, error) {
+	rate, err := FXRate(currency, prices)
+	if err != nil {
+		return nil, err
+	}
+
+	valuation := &Valuation{
+		Currency:      currency,
+		FXRate:        rate,
+		Cash:          tradingDomain.RoundQuote(cashUSD.Mul(rate)),
+		HoldingsValue: decimal.Zero,
+		CostBasis:     decimal.Zero,
+		UnrealizedPnL: decimal.Zero,
+		Positions:     make([]PositionValue, 0, len(holdings)),
+		ValuedAt:      now,
+	}
+
+	for _, holding := range holdings {
+		if !holding.Amount.IsPositive() {
+			continue
+		}
+		position := PositionValue{
+			Coin:              holding.Coin,
+			Amount:            holding.Amount,
+			CostBasis:         tradingDomain.RoundQuote(holding.CostBasis.Mul(rate)),
+			AverageEntryPrice: holding.AverageCost().Mul(rate).Round(tradingDomain.PricePlaces),
+		}
+		if price, ok := prices[holding.Coin][currency]; ok {
+			position.Priced = true
+			position.Price = tradingDomain.PriceFromFloat(price)
+			position.MarketValue = tradingDomain.RoundQuote(position.Price.Mul(holding.Amount))
+			position.UnrealizedPnL = position.MarketValue.Sub(position.CostBasis)
+			if position.CostBasis.IsPositive() {
+				position.UnrealizedPnLPct = position.UnrealizedPnL.Div(position.CostBasis).Mul(hundred).Round(2)
+			}
+			valuation.HoldingsValue = valuation.HoldingsValue.Add(position.MarketValue)
+			valuation.CostBasis = valuation.CostBasis.Add(position.CostBasis)
+			valuation.UnrealizedPnL = valuation.UnrealizedPnL.Add(position.UnrealizedPnL)
+		}
+		valuation.Positions = append(valuation.Positions, position)
+	}
+
+	valuation.NetWorth = valuation.Cash.Add(valuation.HoldingsValue)
+	if valuation.NetWorth.IsPositive() {
+		for i := range valuation.Positions {
+			valuation.Positions[i].AllocationPct = valuation.Positions[i].MarketValue.Div(valuation.NetWorth).Mul(hundred).Round(2)
+		}
+		valuation.CashPct = valuation.Cash.Div(valuation.NetWorth).Mul(hundred).Round(2)
+	}
+
+	// Las posiciones más grandes primero.
+	sort.SliceStable(valuation.Positions, func(i, j int) bool {
+		return valuation.Positions[i].MarketValue.GreaterThan(valuation.Positions[j].MarketValue)
+	})
+	return valuation, nil
+}
+
+// PriceIDs devuelve los ids a pedir a CoinGecko para valuar las tenencias: las monedas con saldo y la de referencia.
+func PriceIDs(holdings []tradingDomain.Holding) []string {
+	ids := []string{FXReferenceCoin}
+	for _, holding := range holdings {
+		if holding.Amount.IsPositive() && holding.Coin != FXReferenceCoin {
+			ids = append(ids, holding.Coin)
+		}
+	}
+	return ids
+}
@@ -0,0 +1,42 @@
+package infrastructure
+
+import (
+	"cryptoproject/internal/portfolio/domain"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// GormSnapshotRepository implementa la interfaz SnapshotRepository usando GORM.
+type GormSnapshotRepository struct {
+	DB *gorm.DB
+}
+
+// NewSnapshotRepository crea una nueva instancia de GormSnapshotRepository.
+func NewSnapshotRepository(db *gorm.DB) domain.SnapshotRepository {
+	return &GormSnapshotRepository{DB: db}
+}
+
+// Save guarda la foto. Si otra réplica ya guardó la del mismo usuario e instante, se ignora.
+func (r *GormSnapshotRepository) Save(snapshot *domain.PortfolioSnapshot) error {
+	return r.DB.Clauses(clause.OnConflict{DoNothing: true}).Create(snapshot).Error
+}
+
+// FindRange devuelve las fotos del usuario con TakenAt en [start, end), de la más vieja a la más nueva.
+func (r *GormSnapshotRepository) FindRange(userID uuid.UUID, start, end time.Time) ([]domain.PortfolioSnapshot, error) {
+	var snapshots []domain.PortfolioSnapshot
+	err := r.DB.Where("user_id = ? AND taken_at >= ? AND taken_at < ?", userID, start, end).
+		Order("taken_at ASC").
+		Find(&snapshots).Error
+	if err != nil {
+		return nil, err
+	}
+	return snapshots, nil
+}
+
+// WithTx devuelve una copia del repositorio que trabaja dentro de la transacción dada.
+func (r *GormSnapshotRepository) WithTx(tx *gorm.DB) domain.SnapshotRepository {
+	return &GormSnapshotRepository{DB: tx}
+}
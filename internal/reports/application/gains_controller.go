@@ -0,0 +1,116 @@
+package application
+
+import (
+	"context"
+	marketDomain "cryptoproject/internal/market/domain"
+	reportsDomain "cryptoproject/internal/reports/domain"
+	tradingDomain "cryptoproject/internal/trading/domain"
+	"cryptoproject/pkg/logger"
+	"encoding/csv"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+)
+
+// GainsController arma el reporte de ganancias de capital a partir del historial de transacciones.
+type GainsController struct {
+	transactionRepo tradingDomain.TransactionRepository
+	market          marketDomain.PriceProvider
+}
+
+// NewGainsController crea una nueva instancia de GainsController.
+func NewGainsController(transactionRepo tradingDomain.TransactionRepository, market marketDomain.PriceProvider) *GainsController {
+	return &GainsController{transactionRepo: transactionRepo, market: market}
+}
+
+// HandleGains devuelve las ganancias realizadas del año (?year=, por defecto el actual) y los lotes abiertos
+// con su ganancia no realizada. ?method=fifo|lifo|average elige el método contable y ?format=csv descarga
+// las disposiciones del año como anexo de ganancias de capital.
+func (gc *GainsController) HandleGains(c *gin.Context) {
+	userUUID, err := uuid.Parse(c.GetString("user_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID de usuario inválido"})
+		return
+	}
+
+	now := time.Now()
+	year := now.UTC().Year()
+	if value := c.Query("year"); value != "" {
+		year, err = strconv.Atoi(value)
+		if err != nil || year < 2000 || year > now.UTC().Year() {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "El año es inválido"})
+			return
+		}
+	}
+
+	method, err := reportsDomain.ParseMethod(c.Query("method"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	format := c.DefaultQuery("format", "json")
+	if format != "json" && format != "csv" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "El formato debe ser json o csv"})
+		return
+	}
+
+	// Los lotes dependen de todo el historial, no solo del año pedido.
+	book := reportsDomain.NewLotBook(method)
+	err = gc.transactionRepo.StreamByUserID(userUUID, func(tx tradingDomain.Transaction) error {
+		if err := book.Apply(tx); err != nil {
+			// Un historial inconsistente no debería tumbar el reporte; se salta la venta como en BuildPositions.
+			logger.Error("Transacción omitida en el reporte de ganancias:", tx.ID, err)
+		}
+		return nil
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error al obtener el historial de transacciones"})
+		return
+	}
+
+	if format == "csv" {
+		report := reportsDomain.NewGainsReport(book, method, year, nil, now)
+		gc.writeSchedule(c, report)
+		return
+	}
+
+	report := reportsDomain.NewGainsReport(book, method, year, gc.currentPrices(c.Request.Context(), book.Coins()), now)
+	c.JSON(http.StatusOK, report)
+}
+
+// currentPrices pide el precio actual de cada moneda. Las que fallan quedan sin valuar en vez de tumbar el reporte.
+func (gc *GainsController) currentPrices(ctx context.Context, coins []string) map[string]decimal.Decimal {
+	prices := make(map[string]decimal.Decimal, len(coins))
+	for _, coin := range coins {
+		quote, err := gc.market.GetQuoteContext(ctx, coin, tradingDomain.QuoteAsset)
+		if err != nil && !quote.Stale {
+			logger.Error(fmt.Sprintf("No se pudo obtener el precio de %s para el reporte:", coin), err)
+			continue
+		}
+		prices[coin] = tradingDomain.PriceFromFloat(quote.Price)
+	}
+	return prices
+}
+
+// writeSchedule descarga las disposiciones del año como CSV.
+func (gc *GainsController) writeSchedule(c *gin.Context, report *reportsDomain.GainsReport) {
+	filename := fmt.Sprintf("capital-gains-%d-%s.csv", report.Year, report.Method)
+	c.Header("Content-Type", "text/csv; charset=utf-8")
+	c.Header("Content-Disposition", `attachment; filename="`+filename+`"`)
+	c.Status(http.StatusOK)
+
+	writer := csv.NewWriter(c.Writer)
+	if err := writer.Write(reportsDomain.GainsColumns); err != nil {
+		logger.Error("Error al escribir el anexo de ganancias:", err)
+		return
+	}
+	if err := writer.WriteAll(report.ScheduleRows()); err != nil {
+		logger.Error("Error al escribir el anexo de ganancias:", err)
+	}
+}
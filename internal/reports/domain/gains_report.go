@@ -0,0 +1,119 @@
+package domain
+
+import (
+	tradingDomain "cryptoproject/internal/trading/domain"
+	"sort"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// GainsColumns son las columnas del CSV del anexo de ganancias de capital, en el orden de Disposal.ScheduleRow.
+var GainsColumns = []string{"description", "date_acquired", "date_sold", "proceeds", "cost_basis", "gain_or_loss", "term"}
+
+// scheduleDateFormat es el formato de fecha del anexo.
+const scheduleDateFormat = "2006-01-02"
+
+// ScheduleRow devuelve la disposición como fila del anexo, con los montos redondeados a centavos.
+func (d Disposal) ScheduleRow() []string {
+	places := tradingDomain.AssetPlaces(tradingDomain.QuoteAsset)
+	return []string{
+		d.Amount.String() + " " + d.Coin,
+		d.Acquired.UTC().Format(scheduleDateFormat),
+		d.Sold.UTC().Format(scheduleDateFormat),
+		tradingDomain.RoundQuote(d.Proceeds).StringFixed(places),
+		tradingDomain.RoundQuote(d.CostBasis).StringFixed(places),
+		tradingDomain.RoundQuote(d.Gain).StringFixed(places),
+		d.Term,
+	}
+}
+
+// GainsSummary suma las ganancias realizadas, separadas por plazo.
+type GainsSummary struct {
+	Proceeds  decimal.Decimal `json:"proceeds"`
+	CostBasis decimal.Decimal `json:"cost_basis"`
+	ShortTerm decimal.Decimal `json:"short_term"`
+	LongTerm  decimal.Decimal `json:"long_term"`
+	Total     decimal.Decimal `json:"total"`
+}
+
+// OpenLotValue es un lote abierto valuado al precio actual. Si no hubo precio para la moneda los campos de valuación quedan en null.
+type OpenLotValue struct {
+	Lot
+	Term           string           `json:"term"` // Plazo que tendría si se vendiera hoy.
+	Price          *decimal.Decimal `json:"price"`
+	MarketValue    *decimal.Decimal `json:"market_value"`
+	UnrealizedGain *decimal.Decimal `json:"unrealized_gain"`
+}
+
+// GainsReport es el reporte de ganancias de un año: lo realizado en ese año y los lotes que siguen abiertos.
+type GainsReport struct {
+	Year       int             `json:"year"`
+	Method     string          `json:"method"`
+	Disposals  []Disposal      `json:"disposals"`
+	Realized   GainsSummary    `json:"realized"`
+	OpenLots   []OpenLotValue  `json:"open_lots"`
+	Unrealized decimal.Decimal `json:"unrealized"` // Solo suma los lotes que tienen precio.
+}
+
+// NewGainsReport arma el reporte a partir del libro de lotes ya cargado con todo el historial.
+// Las disposiciones se filtran por el año de la venta (UTC); los lotes abiertos se valúan con prices (moneda -> precio en USD).
+func NewGainsReport(book *LotBook, method string, year int, prices map[string]decimal.Decimal, now time.Time) *GainsReport {
+	report := &GainsReport{
+		Year:      year,
+		Method:    method,
+		Disposals: make([]Disposal, 0),
+		Realized: GainsSummary{
+			Proceeds: decimal.Zero, CostBasis: decimal.Zero,
+			ShortTerm: decimal.Zero, LongTerm: decimal.Zero, Total: decimal.Zero,
+		},
+		OpenLots:   make([]OpenLotValue, 0),
+		Unrealized: decimal.Zero,
+	}
+
+	for _, disposal := range book.Disposals() {
+		if disposal.Sold.UTC().Year() != year {
+			continue
+		}
+		report.Disposals = append(report.Disposals, disposal)
+		report.Realized.Proceeds = report.Realized.Proceeds.Add(disposal.Proceeds)
+		report.Realized.CostBasis = report.Realized.CostBasis.Add(disposal.CostBasis)
+		if disposal.Term == TermLong {
+			report.Realized.LongTerm = report.Realized.LongTerm.Add(disposal.Gain)
+		} else {
+			report.Realized.ShortTerm = report.Realized.ShortTerm.Add(disposal.Gain)
+		}
+		report.Realized.Total = report.Realized.Total.Add(disposal.Gain)
+	}
+
+	for _, lot := range book.OpenLots() {
+		value := OpenLotValue{Lot: lot, Term: HoldingTerm(lot.Acquired, now)}
+		if price, ok := prices[lot.Coin]; ok {
+			marketValue := price.Mul(lot.Amount)
+			gain := marketValue.Sub(lot.CostBasis)
+			value.Price, value.MarketValue, value.UnrealizedGain = &price, &marketValue, &gain
+			report.Unrealized = report.Unrealized.Add(gain)
+		}
+		report.OpenLots = append(report.OpenLots, value)
+	}
+	return report
+}
+
+// ScheduleRows devuelve las disposiciones ordenadas como en el anexo: primero las de corto plazo y después las de largo,
+// cada grupo por fecha de venta.
+func (r *GainsReport) ScheduleRows() [][]string {
+	disposals := make([]Disposal, len(r.Disposals))
+	copy(disposals, r.Disposals)
+	sort.SliceStable(disposals, func(i, j int) bool {
+		if disposals[i].Term != disposals[j].Term {
+			return disposals[i].Term == TermShort
+		}
+		return disposals[i].Sold.Before(disposals[j].Sold)
+	})
+
+	rows := make([][]string, 0, len(disposals))
+	for _, disposal := range disposals {
+		rows = append(rows, disposal.ScheduleRow())
+	}
+	return rows
+}
@@ -0,0 +1,228 @@
+package domain
+
+import (
+	tradingDomain "cryptoproject/internal/trading/domain"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+)
+
+// Métodos contables para asignar el costo de lo vendido.
+const (
+	MethodFIFO    = "fifo"    // Se vende primero lo que se compró primero.
+	MethodLIFO    = "lifo"    // Se vende primero lo último que se compró.
+	MethodAverage = "average" // Costo promedio de todo lo abierto; las fechas se toman en orden FIFO.
+)
+
+// Plazos de tenencia.
+const (
+	TermShort = "short"
+	TermLong  = "long"
+)
+
+// ParseMethod valida el método contable. Vacío significa FIFO.
+func ParseMethod(method string) (string, error) {
+	switch strings.ToLower(method) {
+	case "", MethodFIFO:
+		return MethodFIFO, nil
+	case MethodLIFO:
+		return MethodLIFO, nil
+	case MethodAverage:
+		return MethodAverage, nil
+	}
+	return "", errors.New("el método debe ser fifo, lifo o average")
+}
+
+// HoldingTerm indica si una tenencia es de largo plazo: más de un año entre la compra y la venta.
+func HoldingTerm(acquired, sold time.Time) string {
+	if sold.After(acquired.AddDate(1, 0, 0)) {
+		return TermLong
+	}
+	return TermShort
+}
+
+// Lot es un lote de impuestos: unidades de una moneda compradas en una misma transacción que siguen abiertas.
+type Lot struct {
+	TransactionID uuid.UUID       `json:"transaction_id"`
+	Coin          string          `json:"coin"`
+	Acquired      time.Time       `json:"acquired"`
+	Amount        decimal.Decimal `json:"amount"`
+	CostBasis     decimal.Decimal `json:"cost_basis"` // Costo en USD de las unidades abiertas, comisión incluida.
+}
+
+// take saca amount unidades del lote y devuelve el costo proporcional. Si se lleva todo, se lleva todo el costo.
+func (l *Lot) take(amount decimal.Decimal) decimal.Decimal {
+	cost := l.CostBasis
+	if amount.LessThan(l.Amount) {
+		cost = l.CostBasis.Mul(amount).Div(l.Amount).Round(tradingDomain.CostBasisPlaces)
+	}
+	l.Amount = l.Amount.Sub(amount)
+	l.CostBasis = l.CostBasis.Sub(cost)
+	return cost
+}
+
+// Disposal es una línea del anexo de ganancias de capital: la venta de unidades de un lote.
+// Una venta que toma unidades de varios lotes genera una línea por lote.
+type Disposal struct {
+	SaleTransactionID uuid.UUID       `json:"sale_transaction_id"`
+	Coin              string          `json:"coin"`
+	Amount            decimal.Decimal `json:"amount"`
+	Acquired          time.Time       `json:"acquired"`
+	Sold              time.Time       `json:"sold"`
+	Proceeds          decimal.Decimal `json:"proceeds"` // Lo recibido menos la comisión, en la parte de este lote.
+	CostBasis         decimal.Decimal `json:"cost_basis"`
+	Gain              decimal.Decimal `json:"gain"`
+	Term              string          `json:"term"`
+}
+
+// LotBook lleva los lotes abiertos de un usuario y registra cada venta como disposiciones según el método contable.
+// Las transacciones se tienen que aplicar ordenadas por fecha.
+type LotBook struct {
+	method    string
+	lots      map[string][]*Lot
+	disposals []Disposal
+}
+
+// NewLotBook crea un libro de lotes vacío para el método dado (ver ParseMethod).
+func NewLotBook(method string) *LotBook {
+	return &LotBook{method: method, lots: make(map[string][]*Lot)}
+}
+
+// Apply registra una transacción: las compras abren un lote y las ventas consumen lotes.
+func (b *LotBook) Apply(tx tradingDomain.Transaction) error {
+	if tx.Side != tradingDomain.SideSell {
+		// Igual que en Position, lo que no es venta cuenta como compra y la comisión va al costo.
+		b.lots[tx.Coin] = append(b.lots[tx.Coin], &Lot{
+			TransactionID: tx.ID,
+			Coin:          tx.Coin,
+			Acquired:      tx.Timestamp,
+			Amount:        tx.Amount,
+			CostBasis:     tradingDomain.BuyCost(tx.Price, tx.Amount).Add(tx.Fee),
+		})
+		// Con costo promedio todos los lotes quedan al mismo costo por unidad; así cada venta saca el promedio
+		// y los lotes solo aportan las fechas.
+		if b.method == MethodAverage {
+			b.spreadCost(tx.Coin)
+		}
+		return nil
+	}
+
+	open := decimal.Zero
+	for _, lot := range b.lots[tx.Coin] {
+		open = open.Add(lot.Amount)
+	}
+	if tx.Amount.GreaterThan(open) {
+		return fmt.Errorf("cantidad insuficiente de %s: abierta %s, venta %s", tx.Coin, open, tx.Amount)
+	}
+
+	proceeds := tradingDomain.SellProceeds(tx.Price, tx.Amount).Sub(tx.Fee)
+	remaining := tx.Amount
+	allocated := decimal.Zero
+	for remaining.IsPositive() {
+		lot := b.nextLot(tx.Coin)
+		portion := decimal.Min(remaining, lot.Amount)
+		remaining = remaining.Sub(portion)
+
+		disposal := Disposal{
+			SaleTransactionID: tx.ID,
+			Coin:              tx.Coin,
+			Amount:            portion,
+			Acquired:          lot.Acquired,
+			Sold:              tx.Timestamp,
+			CostBasis:         lot.take(portion),
+			Term:              HoldingTerm(lot.Acquired, tx.Timestamp),
+		}
+		// La última parte se lleva el resto para que la suma cierre exacta con lo recibido.
+		if remaining.IsZero() {
+			disposal.Proceeds = proceeds.Sub(allocated)
+		} else {
+			disposal.Proceeds = share(proceeds, portion, tx.Amount)
+		}
+		allocated = allocated.Add(disposal.Proceeds)
+		disposal.Gain = disposal.Proceeds.Sub(disposal.CostBasis)
+		b.disposals = append(b.disposals, disposal)
+		b.dropEmptyLots(tx.Coin)
+	}
+	return nil
+}
+
+// nextLot elige el lote del que sale la próxima unidad vendida. El costo promedio toma las fechas en orden FIFO.
+func (b *LotBook) nextLot(coin string) *Lot {
+	lots := b.lots[coin]
+	if b.method == MethodLIFO {
+		return lots[len(lots)-1]
+	}
+	return lots[0]
+}
+
+// dropEmptyLots quita los lotes que quedaron en cero.
+func (b *LotBook) dropEmptyLots(coin string) {
+	open := b.lots[coin][:0]
+	for _, lot := range b.lots[coin] {
+		if lot.Amount.IsPositive() {
+			open = append(open, lot)
+		}
+	}
+	b.lots[coin] = open
+}
+
+// spreadCost reparte el costo total de los lotes abiertos según su cantidad, para que todos queden al costo promedio.
+func (b *LotBook) spreadCost(coin string) {
+	lots := b.lots[coin]
+	total, amount := decimal.Zero, decimal.Zero
+	for _, lot := range lots {
+		total = total.Add(lot.CostBasis)
+		amount = amount.Add(lot.Amount)
+	}
+	allocated := decimal.Zero
+	for i, lot := range lots {
+		if i == len(lots)-1 {
+			lot.CostBasis = total.Sub(allocated)
+			continue
+		}
+		lot.CostBasis = share(total, lot.Amount, amount)
+		allocated = allocated.Add(lot.CostBasis)
+	}
+}
+
+// share devuelve la parte de total que corresponde a part sobre whole.
+func share(total, part, whole decimal.Decimal) decimal.Decimal {
+	if !whole.IsPositive() {
+		return decimal.Zero
+	}
+	return total.Mul(part).Div(whole).Round(tradingDomain.CostBasisPlaces)
+}
+
+// Disposals devuelve las disposiciones registradas, en orden de venta.
+func (b *LotBook) Disposals() []Disposal {
+	return b.disposals
+}
+
+// OpenLots devuelve los lotes abiertos de todas las monedas, del más viejo al más nuevo.
+func (b *LotBook) OpenLots() []Lot {
+	lots := make([]Lot, 0)
+	for _, coinLots := range b.lots {
+		for _, lot := range coinLots {
+			lots = append(lots, *lot)
+		}
+	}
+	sort.SliceStable(lots, func(i, j int) bool { return lots[i].Acquired.Before(lots[j].Acquired) })
+	return lots
+}
+
+// Coins devuelve las monedas con lotes abiertos, para pedir sus precios.
+func (b *LotBook) Coins() []string {
+	coins := make([]string, 0, len(b.lots))
+	for coin, lots := range b.lots {
+		if len(lots) > 0 {
+			coins = append(coins, coin)
+		}
+	}
+	sort.Strings(coins)
+	return coins
+}
@@ -0,0 +1,111 @@
+package domain
+
+import (
+	tradingDomain "cryptoproject/internal/trading/domain"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+)
+
+func TestHoldingTerm(t *testing.T) {
+	acquired := time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name string
+		sold time.Time
+		term string
+	}{
+		{name: "el mismo día", sold: acquired.Add(time.Hour), term: TermShort},
+		{name: "justo un año", sold: acquired.AddDate(1, 0, 0), term: TermShort},
+		{name: "un año y un segundo", sold: acquired.AddDate(1, 0, 0).Add(time.Second), term: TermLong},
+		{name: "dos años", sold: acquired.AddDate(2, 0, 0), term: TermLong},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if term := HoldingTerm(acquired, tt.sold); term != tt.term {
+				t.Fatalf("HoldingTerm = %s, se esperaba %s", term, tt.term)
+			}
+		})
+	}
+}
+
+func TestLotBook(t *testing.T) {
+	d := decimal.RequireFromString
+	day := func(y int, m time.Month, dd int) time.Time { return time.Date(y, m, dd, 0, 0, 0, 0, time.UTC) }
+	trade := func(side string, amount, price string, at time.Time) tradingDomain.Transaction {
+		return tradingDomain.Transaction{ID: uuid.New(), Coin: "bitcoin", Side: side, Amount: d(amount), Price: d(price), Timestamp: at}
+	}
+	// Dos compras a 100 y 200 y una venta de 1.5 a 300: recibe 450.
+	history := []tradingDomain.Transaction{
+		trade(tradingDomain.SideBuy, "1", "100", day(2024, 1, 1)),
+		trade(tradingDomain.SideBuy, "1", "200", day(2024, 6, 1)),
+		trade(tradingDomain.SideSell, "1.5", "300", day(2025, 3, 1)),
+	}
+
+	type line struct{ amount, cost, proceeds, term string }
+	tests := []struct {
+		method    string
+		disposals []line
+		openCost  string // Costo del medio bitcoin que queda abierto.
+	}{
+		{method: MethodFIFO, disposals: []line{{"1", "100", "300", TermLong}, {"0.5", "100", "150", TermShort}}, openCost: "100"},
+		{method: MethodLIFO, disposals: []line{{"1", "200", "300", TermShort}, {"0.5", "50", "150", TermLong}}, openCost: "50"},
+		{method: MethodAverage, disposals: []line{{"1", "150", "300", TermLong}, {"0.5", "75", "150", TermShort}}, openCost: "75"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.method, func(t *testing.T) {
+			book := NewLotBook(tt.method)
+			for _, tx := range history {
+				if err := book.Apply(tx); err != nil {
+					t.Fatalf("Apply: %v", err)
+				}
+			}
+
+			disposals := book.Disposals()
+			if len(disposals) != len(tt.disposals) {
+				t.Fatalf("se obtuvieron %d disposiciones, se esperaban %d", len(disposals), len(tt.disposals))
+			}
+			for i, want := range tt.disposals {
+				got := disposals[i]
+				if !got.Amount.Equal(d(want.amount)) || !got.CostBasis.Equal(d(want.cost)) || !got.Proceeds.Equal(d(want.proceeds)) || got.Term != want.term {
+					t.Fatalf("disposición %d = %s a costo %s por %s (%s); se esperaba %s a %s por %s (%s)",
+						i, got.Amount, got.CostBasis, got.Proceeds, got.Term, want.amount, want.cost, want.proceeds, want.term)
+				}
+				if !got.Gain.Equal(got.Proceeds.Sub(got.CostBasis)) {
+					t.Fatalf("disposición %d: ganancia %s no es lo recibido menos el costo", i, got.Gain)
+				}
+			}
+
+			open := book.OpenLots()
+			if len(open) != 1 || !open[0].Amount.Equal(d("0.5")) || !open[0].CostBasis.Equal(d(tt.openCost)) {
+				t.Fatalf("lotes abiertos = %+v, se esperaba 0.5 a costo %s", open, tt.openCost)
+			}
+
+			if err := book.Apply(trade(tradingDomain.SideSell, "1", "300", day(2025, 4, 1))); err == nil {
+				t.Fatal("se vendió más de lo abierto")
+			}
+		})
+	}
+}
+
+// Lo recibido se reparte entre los lotes sin perder centavos, aunque la división no sea exacta.
+func TestLotBookProceedsAddUp(t *testing.T) {
+	book := NewLotBook(MethodFIFO)
+	at := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	for i := 0; i < 3; i++ {
+		book.Apply(tradingDomain.Transaction{ID: uuid.New(), Coin: "bitcoin", Side: tradingDomain.SideBuy, Amount: decimal.NewFromInt(1), Price: decimal.NewFromInt(10), Timestamp: at})
+	}
+	sale := tradingDomain.Transaction{ID: uuid.New(), Coin: "bitcoin", Side: tradingDomain.SideSell, Amount: decimal.NewFromInt(3), Price: decimal.RequireFromString("33.33"), Fee: decimal.RequireFromString("0.01"), Timestamp: at.AddDate(0, 1, 0)}
+	if err := book.Apply(sale); err != nil {
+		t.Fatalf("Apply: %v", err)
+	}
+
+	total := decimal.Zero
+	for _, disposal := range book.Disposals() {
+		total = total.Add(disposal.Proceeds)
+	}
+	if !total.Equal(decimal.RequireFromString("99.98")) {
+		t.Fatalf("lo recibido suma %s, se esperaba 99.98", total)
+	}
+}
@@ -5,7 +5,11 @@ import (
 	accountApp "cryptoproject/internal/account/application" // Añadimos esta línea
 	"cryptoproject/internal/auth/application"
 	"cryptoproject/internal/auth/infrastructure"
+	idempotencyInfra "cryptoproject/internal/idempotency/infrastructure"
+	ledgerApp "cryptoproject/internal/ledger/application"
 	marketApp "cryptoproject/internal/market/application"
+	portfolioApp "cryptoproject/internal/portfolio/application"
+	reportsApp "cryptoproject/internal/reports/application"
 	tradingApp "cryptoproject/internal/trading/application"
 	"net/http"
 
@@ -20,8 +24,16 @@ func SetupRouter(
 	marketController *marketApp.MarketController,
 	registerController *application.RegisterController,
 	tradingController *tradingApp.TradingController,
+	quoteController *tradingApp.QuoteController,
+	orderController *tradingApp.OrderController,
+	exitRuleController *tradingApp.ExitRuleController,
+	recurringController *tradingApp.RecurringController,
 	accountController *accountApp.AccountController, // Añadimos AccountController aquí
+	statementController *ledgerApp.StatementController,
+	gainsController *reportsApp.GainsController,
+	portfolioController *portfolioApp.PortfolioController,
 	jwtMiddleware *infrastructure.JWTMiddleware,
+	idempotencyMiddleware *idempotencyInfra.IdempotencyMiddleware,
 ) *gin.Engine {
 	docs.SwaggerInfo.Title = "Crypto API"
 	docs.SwaggerInfo.Host = "localhost:8080"
@@ -47,13 +59,50 @@ func SetupRouter(
 	protected.GET("/market/:id/price", marketController.GetCurrentPriceHandler)
 	protected.GET("/market/:id/history", marketController.GetHistoricalPricesHandler)
 
+	// Los endpoints que mueven saldo aceptan Idempotency-Key para que los reintentos no dupliquen la operación.
+	idempotent := idempotencyMiddleware.Middleware()
+
 	// Trading
-	protected.POST("/trading/buy", tradingController.HandleBuy)
+	protected.POST("/trading/buy", idempotent, tradingController.HandleBuy)
+	protected.POST("/trading/sell", idempotent, tradingController.HandleSell)
+	protected.POST("/trading/swap", idempotent, tradingController.HandleSwap)
 	protected.GET("/trading/history", tradingController.HandleTransactionHistory)
+	protected.GET("/trading/history/export", tradingController.HandleExportHistory)
 	protected.GET("/trading/balance", tradingController.HandleBalance)
 
+	// Cotizaciones
+	protected.POST("/trading/quotes", quoteController.HandleCreateQuote)
+	protected.POST("/trading/quotes/:id/execute", quoteController.HandleExecuteQuote)
+
+	// Órdenes límite
+	protected.POST("/trading/orders", orderController.HandleCreateOrder)
+	protected.GET("/trading/orders", orderController.HandleListOrders)
+	protected.DELETE("/trading/orders/:id", orderController.HandleCancelOrder)
+
+	// Stop-loss y take-profit
+	protected.POST("/trading/exit-rules", exitRuleController.HandleCreateRule)
+	protected.GET("/trading/exit-rules", exitRuleController.HandleListRules)
+	protected.GET("/trading/exit-rules/:id/history", exitRuleController.HandleRuleHistory)
+	protected.DELETE("/trading/exit-rules/:id", exitRuleController.HandleCancelRule)
+
+	// Compras recurrentes
+	protected.POST("/trading/recurring", recurringController.HandleCreateRecurring)
+	protected.GET("/trading/recurring", recurringController.HandleListRecurring)
+	protected.PUT("/trading/recurring/:id", recurringController.HandleUpdateRecurring)
+	protected.DELETE("/trading/recurring/:id", recurringController.HandleCancelRecurring)
+	protected.GET("/trading/recurring/:id/runs", recurringController.HandleRecurringRuns)
+
 	// Account
-	protected.POST("/account/balance/add", accountController.HandleAddBalance) // Añadimos este endpoint
+	protected.POST("/account/balance/add", idempotent, accountController.HandleAddBalance) // Añadimos este endpoint
+	protected.GET("/account/statement", statementController.HandleStatement)
+
+	// Cartera
+	protected.GET("/portfolio", portfolioController.HandlePortfolio)
+	protected.GET("/portfolio/history", portfolioController.HandleHistory)
+	protected.GET("/portfolio/analytics", portfolioController.HandleAnalytics)
+
+	// Reportes
+	protected.GET("/reports/gains", gainsController.HandleGains)
 
 	return r
 }
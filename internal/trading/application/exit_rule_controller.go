@@ -0,0 +1,160 @@
+package application
+
+import (
+	tradingDomain "cryptoproject/internal/trading/domain"
+	"cryptoproject/pkg/database"
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+)
+
+// errRuleNotFound se usa dentro de la unidad de trabajo para responder 404.
+var errRuleNotFound = errors.New("regla no encontrada")
+
+// ExitRuleController maneja las reglas de stop-loss y take-profit de los usuarios.
+// La evaluación contra precios en vivo la hace ExitRuleMonitor en segundo plano.
+type ExitRuleController struct {
+	uow      database.UnitOfWork
+	ruleRepo tradingDomain.ExitRuleRepository
+}
+
+// NewExitRuleController crea una nueva instancia de ExitRuleController.
+func NewExitRuleController(uow database.UnitOfWork, ruleRepo tradingDomain.ExitRuleRepository) *ExitRuleController {
+	return &ExitRuleController{uow: uow, ruleRepo: ruleRepo}
+}
+
+// HandleCreateRule registra una regla de salida sobre una moneda.
+func (ec *ExitRuleController) HandleCreateRule(c *gin.Context) {
+	userUUID, err := uuid.Parse(c.GetString("user_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID de usuario inválido"})
+		return
+	}
+
+	triggerPrice, ok := parseAmount(c.PostForm("trigger_price"))
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "El precio disparador es inválido"})
+		return
+	}
+
+	// amount es opcional: si no viene, la regla vende toda la posición.
+	amount := decimal.Zero
+	if amountStr := c.PostForm("amount"); amountStr != "" {
+		amount, ok = parseAmount(amountStr)
+		if !ok {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "La cantidad ingresada es inválida"})
+			return
+		}
+	}
+
+	rule, event, err := tradingDomain.NewExitRule(userUUID, c.PostForm("coin"), c.PostForm("type"), triggerPrice, amount)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := ec.ruleRepo.Save(rule, event); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error al registrar la regla"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, rule)
+}
+
+// HandleListRules devuelve las reglas del usuario. Acepta ?status=active|triggered|failed|cancelled.
+func (ec *ExitRuleController) HandleListRules(c *gin.Context) {
+	userUUID, err := uuid.Parse(c.GetString("user_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID de usuario inválido"})
+		return
+	}
+
+	rules, err := ec.ruleRepo.FindByUserID(userUUID, c.Query("status"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error al obtener las reglas"})
+		return
+	}
+
+	c.JSON(http.StatusOK, rules)
+}
+
+// HandleRuleHistory devuelve el historial de estados de una regla.
+func (ec *ExitRuleController) HandleRuleHistory(c *gin.Context) {
+	rule, ok := ec.findOwnedRule(c)
+	if !ok {
+		return
+	}
+
+	events, err := ec.ruleRepo.FindEvents(rule.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error al obtener el historial de la regla"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"rule":    rule,
+		"history": events,
+	})
+}
+
+// HandleCancelRule cancela una regla activa.
+func (ec *ExitRuleController) HandleCancelRule(c *gin.Context) {
+	ruleID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID de regla inválido"})
+		return
+	}
+
+	// Bloqueamos la regla para que el monitor no la dispare mientras la cancelamos.
+	var rule *tradingDomain.ExitRule
+	err = ec.uow.Do(func(tx *gorm.DB) error {
+		rules := ec.ruleRepo.WithTx(tx)
+
+		var err error
+		rule, err = rules.FindByIDForUpdate(ruleID)
+		// Si la regla es de otro usuario respondemos igual que si no existiera.
+		if err != nil || rule.UserID.String() != c.GetString("user_id") {
+			return errRuleNotFound
+		}
+		event, err := rule.Cancel()
+		if err != nil {
+			return err
+		}
+		return rules.Update(rule, event)
+	})
+	switch {
+	case errors.Is(err, errRuleNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Regla no encontrada"})
+		return
+	case errors.Is(err, tradingDomain.ErrExitRuleNotActive):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error al cancelar la regla"})
+		return
+	}
+
+	c.JSON(http.StatusOK, rule)
+}
+
+// findOwnedRule busca la regla del parámetro :id y verifica que sea del usuario autenticado.
+// Si algo falla ya deja escrita la respuesta de error.
+func (ec *ExitRuleController) findOwnedRule(c *gin.Context) (*tradingDomain.ExitRule, bool) {
+	ruleID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID de regla inválido"})
+		return nil, false
+	}
+
+	rule, err := ec.ruleRepo.FindByID(ruleID)
+	// Si la regla es de otro usuario respondemos igual que si no existiera.
+	if err != nil || rule.UserID.String() != c.GetString("user_id") {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Regla no encontrada"})
+		return nil, false
+	}
+	return rule, true
+}
@@ -0,0 +1,159 @@
+package application
+
+import (
+	"context"
+	marketDomain "cryptoproject/internal/market/domain"
+	tradingDomain "cryptoproject/internal/trading/domain"
+	"cryptoproject/pkg/database"
+	"cryptoproject/pkg/logger"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+)
+
+// ExitRuleMonitor vigila los precios y ejecuta una venta a mercado cuando se dispara un stop-loss o take-profit.
+// La regla se dispara con el precio de mercado, pero la venta se ejecuta con el deslizamiento que corresponda,
+// igual que una venta a mercado del usuario.
+type ExitRuleMonitor struct {
+	uow      database.UnitOfWork
+	ruleRepo tradingDomain.ExitRuleRepository
+	executor *TradeExecutor
+	market   marketDomain.PriceProvider
+	slippage tradingDomain.SlippageModel
+	interval time.Duration
+}
+
+// NewExitRuleMonitor crea una nueva instancia de ExitRuleMonitor.
+func NewExitRuleMonitor(
+	uow database.UnitOfWork,
+	ruleRepo tradingDomain.ExitRuleRepository,
+	executor *TradeExecutor,
+	market marketDomain.PriceProvider,
+	slippage tradingDomain.SlippageModel,
+	interval time.Duration,
+) *ExitRuleMonitor {
+	return &ExitRuleMonitor{
+		uow:      uow,
+		ruleRepo: ruleRepo,
+		executor: executor,
+		market:   market,
+		slippage: slippage,
+		interval: interval,
+	}
+}
+
+// Start corre el ciclo de evaluación hasta que se cancele el contexto.
+func (m *ExitRuleMonitor) Start(ctx context.Context) {
+	logger.Info("Monitor de stop-loss/take-profit iniciado, intervalo:", m.interval)
+	ticker := time.NewTicker(m.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			logger.Info("Monitor de stop-loss/take-profit detenido")
+			return
+		case <-ticker.C:
+			m.EvaluateActiveRules()
+		}
+	}
+}
+
+// EvaluateActiveRules revisa todas las reglas activas con un solo precio por moneda.
+func (m *ExitRuleMonitor) EvaluateActiveRules() {
+	rules, err := m.ruleRepo.FindActive()
+	if err != nil {
+		logger.Error("Error al obtener reglas activas:", err)
+		return
+	}
+
+	quotes := make(map[string]marketDomain.PriceQuote)
+	for i := range rules {
+		rule := &rules[i]
+
+		quote, cached := quotes[rule.Coin]
+		if !cached {
+			quote, err = fetchQuote(context.Background(), m.market, rule.Coin)
+			if err != nil {
+				logger.Error(fmt.Sprintf("No se pudo obtener el precio de %s para las reglas de salida:", rule.Coin), err)
+				continue
+			}
+			quotes[rule.Coin] = quote
+		}
+
+		if !rule.IsTriggeredBy(tradingDomain.PriceFromFloat(quote.Price)) {
+			continue
+		}
+		if err := m.trigger(rule, quote); err != nil {
+			logger.Error("Error al ejecutar la regla de salida:", rule.ID, err)
+		}
+	}
+}
+
+// trigger ejecuta la venta de la regla. La regla se relee y se bloquea en la misma unidad de trabajo que la venta,
+// así una cancelación que llega mientras tanto no se pisa. Si no se puede vender por falta de cripto o porque
+// la comisión se come la venta, la regla queda fallida con el motivo; ante otros errores (base caída, etc.)
+// queda activa y se reintenta en la próxima vuelta.
+func (m *ExitRuleMonitor) trigger(rule *tradingDomain.ExitRule, quote marketDomain.PriceQuote) error {
+	marketPrice := tradingDomain.PriceFromFloat(quote.Price)
+	fillPrice := func(amount decimal.Decimal) decimal.Decimal {
+		return fillAt(m.slippage, quote, tradingDomain.SideSell, func(price decimal.Decimal) decimal.Decimal {
+			return price.Mul(amount)
+		}).Price
+	}
+	var sold decimal.Decimal
+	err := m.uow.Do(func(tx *gorm.DB) error {
+		rules := m.ruleRepo.WithTx(tx)
+
+		// Releemos y bloqueamos la regla por si el usuario la canceló mientras procesábamos la vuelta.
+		current, err := rules.FindByIDForUpdate(rule.ID)
+		if err != nil {
+			return err
+		}
+		*rule = *current
+		if rule.Status != tradingDomain.ExitRuleStatusActive {
+			return nil
+		}
+
+		// Si la regla pide más de lo que queda (porque vendió a mano), vendemos lo que haya. Lo que queda se mira
+		// con el usuario y la tenencia ya bloqueados, dentro de la misma venta.
+		result, err := m.executor.SellUpToTx(tx, rule.UserID.String(), rule.Coin, tradingDomain.LiquidityTaker, rule.Amount, fillPrice)
+		if err != nil {
+			return err
+		}
+		sold = result.Transaction.Amount
+		return rules.Update(rule, rule.MarkTriggered(marketPrice, result.Transaction.ID))
+	})
+
+	switch {
+	case errors.Is(err, ErrNoOpenPosition), errors.Is(err, ErrInsufficientHoldings), errors.Is(err, ErrFeeExceedsProceeds), errors.Is(err, ErrUserNotFound):
+		// La venta se revirtió completa; dejamos la regla fallida con el motivo.
+		return m.fail(rule.ID, marketPrice, err.Error())
+	case err != nil:
+		return err
+	}
+
+	if sold.IsPositive() {
+		logger.Info(fmt.Sprintf("Regla %s (%s) disparada a %s, vendidos %s %s", rule.ID, rule.Type, marketPrice, sold, rule.Coin))
+	}
+	return nil
+}
+
+// fail marca la regla como fallida, solo si sigue activa.
+func (m *ExitRuleMonitor) fail(ruleID uuid.UUID, marketPrice decimal.Decimal, reason string) error {
+	return m.uow.Do(func(tx *gorm.DB) error {
+		rules := m.ruleRepo.WithTx(tx)
+		rule, err := rules.FindByIDForUpdate(ruleID)
+		if err != nil {
+			return err
+		}
+		if rule.Status != tradingDomain.ExitRuleStatusActive {
+			return nil
+		}
+		return rules.Update(rule, rule.MarkFailed(marketPrice, reason))
+	})
+}
@@ -0,0 +1,126 @@
+package application
+
+import (
+	tradingDomain "cryptoproject/internal/trading/domain"
+	"cryptoproject/pkg/logger"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// exportFlushEvery es cada cuántas filas se empuja la respuesta al cliente.
+const exportFlushEvery = 500
+
+// exportWriter escribe las filas de la exportación en un formato.
+type exportWriter interface {
+	ContentType() string
+	Write(row tradingDomain.ExportRow) error
+	Flush() error
+}
+
+// csvExport escribe la exportación en CSV con una fila de encabezados.
+type csvExport struct {
+	writer        *csv.Writer
+	headerWritten bool
+}
+
+func (e *csvExport) ContentType() string { return "text/csv; charset=utf-8" }
+
+func (e *csvExport) Write(row tradingDomain.ExportRow) error {
+	if err := e.writeHeader(); err != nil {
+		return err
+	}
+	return e.writer.Write(row.CSV())
+}
+
+func (e *csvExport) Flush() error {
+	// Un historial vacío igual lleva los encabezados.
+	if err := e.writeHeader(); err != nil {
+		return err
+	}
+	e.writer.Flush()
+	return e.writer.Error()
+}
+
+func (e *csvExport) writeHeader() error {
+	if e.headerWritten {
+		return nil
+	}
+	e.headerWritten = true
+	return e.writer.Write(tradingDomain.ExportColumns)
+}
+
+// jsonlExport escribe la exportación en JSON Lines: un objeto por línea.
+type jsonlExport struct {
+	encoder *json.Encoder
+}
+
+func (e *jsonlExport) ContentType() string { return "application/x-ndjson" }
+
+func (e *jsonlExport) Write(row tradingDomain.ExportRow) error { return e.encoder.Encode(row) }
+
+func (e *jsonlExport) Flush() error { return nil }
+
+// newExportWriter devuelve el escritor del formato pedido, o false si el formato no existe.
+func newExportWriter(format string, w io.Writer) (exportWriter, bool) {
+	switch format {
+	case "csv":
+		return &csvExport{writer: csv.NewWriter(w)}, true
+	case "jsonl":
+		return &jsonlExport{encoder: json.NewEncoder(w)}, true
+	}
+	return nil, false
+}
+
+// HandleExportHistory descarga todo el historial del usuario en CSV o JSON Lines (?format=csv|jsonl).
+// Las filas se escriben a medida que salen de la base de datos, así que el tamaño del historial no pesa en memoria.
+func (tc *TradingController) HandleExportHistory(c *gin.Context) {
+	userUUID, err := uuid.Parse(c.GetString("user_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID de usuario inválido"})
+		return
+	}
+
+	format := c.DefaultQuery("format", "csv")
+	writer, ok := newExportWriter(format, c.Writer)
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "El formato debe ser csv o jsonl"})
+		return
+	}
+
+	filename := fmt.Sprintf("transactions-%s.%s", time.Now().UTC().Format("20060102"), format)
+	c.Header("Content-Type", writer.ContentType())
+	c.Header("Content-Disposition", `attachment; filename="`+filename+`"`)
+	c.Status(http.StatusOK)
+
+	exporter := tradingDomain.NewHistoryExporter()
+	count := 0
+	err = tc.transactionRepo.StreamByUserID(userUUID, func(tx tradingDomain.Transaction) error {
+		if err := writer.Write(exporter.Row(tx)); err != nil {
+			return err
+		}
+		count++
+		if count%exportFlushEvery == 0 {
+			if err := writer.Flush(); err != nil {
+				return err
+			}
+			c.Writer.Flush()
+		}
+		return nil
+	})
+	if err == nil {
+		err = writer.Flush()
+	}
+	if err != nil {
+		// Los encabezados HTTP ya se enviaron, así que solo queda cortar la descarga y dejarlo en el log.
+		logger.Error("Error al exportar el historial:", err)
+		return
+	}
+	c.Writer.Flush()
+}
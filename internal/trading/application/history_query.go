@@ -0,0 +1,121 @@
+package application
+
+import (
+	tradingDomain "cryptoproject/internal/trading/domain"
+	"cryptoproject/pkg/dates"
+	"errors"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+)
+
+const (
+	defaultHistoryLimit = 50
+	maxHistoryLimit     = 200
+)
+
+// parseTransactionQuery lee los query params del historial:
+// coin, side, from, to (dd-mm-yyyy o RFC3339), min_amount, max_amount, order (asc|desc), limit y after/before (cursores).
+func parseTransactionQuery(c *gin.Context) (tradingDomain.TransactionQuery, error) {
+	query := tradingDomain.TransactionQuery{
+		Filter: tradingDomain.TransactionFilter{Coin: c.Query("coin"), Side: c.Query("side")},
+		Limit:  defaultHistoryLimit,
+	}
+
+	if side := query.Filter.Side; side != "" && side != tradingDomain.SideBuy && side != tradingDomain.SideSell {
+		return query, errors.New("El lado debe ser buy o sell")
+	}
+
+	switch c.DefaultQuery("order", "desc") {
+	case "asc":
+		query.Ascending = true
+	case "desc":
+	default:
+		return query, errors.New("El orden debe ser asc o desc")
+	}
+
+	if value := c.Query("limit"); value != "" {
+		limit, err := strconv.Atoi(value)
+		if err != nil || limit < 1 || limit > maxHistoryLimit {
+			return query, errors.New("El límite debe estar entre 1 y 200")
+		}
+		query.Limit = limit
+	}
+
+	if value := c.Query("from"); value != "" {
+		from, err := dates.Parse(value)
+		if err != nil {
+			return query, errors.New("El formato de la fecha de inicio debe ser dd-mm-yyyy o RFC3339")
+		}
+		query.Filter.From = &from
+	}
+	if value := c.Query("to"); value != "" {
+		// Con dd-mm-yyyy el día de fin se incluye completo.
+		to, err := dates.ParseEnd(value)
+		if err != nil {
+			return query, errors.New("El formato de la fecha de fin debe ser dd-mm-yyyy o RFC3339")
+		}
+		query.Filter.To = &to
+	}
+
+	var ok bool
+	if query.Filter.MinAmount, ok = parseOptionalAmount(c.Query("min_amount")); !ok {
+		return query, errors.New("La cantidad mínima es inválida")
+	}
+	if query.Filter.MaxAmount, ok = parseOptionalAmount(c.Query("max_amount")); !ok {
+		return query, errors.New("La cantidad máxima es inválida")
+	}
+
+	after, before := c.Query("after"), c.Query("before")
+	if after != "" && before != "" {
+		return query, errors.New("Usa after o before, no los dos")
+	}
+	var err error
+	if after != "" {
+		if query.After, err = tradingDomain.DecodeTransactionCursor(after); err != nil {
+			return query, errors.New("El cursor es inválido")
+		}
+	}
+	if before != "" {
+		if query.Before, err = tradingDomain.DecodeTransactionCursor(before); err != nil {
+			return query, errors.New("El cursor es inválido")
+		}
+	}
+	return query, nil
+}
+
+// parseOptionalAmount es como parseAmount pero un valor vacío significa "sin filtro".
+func parseOptionalAmount(value string) (*decimal.Decimal, bool) {
+	if value == "" {
+		return nil, true
+	}
+	amount, ok := parseAmount(value)
+	if !ok {
+		return nil, false
+	}
+	return &amount, true
+}
+
+// swapIDs devuelve los IDs de swap de las transacciones, sin repetir.
+func swapIDs(transactions []tradingDomain.Transaction) []uuid.UUID {
+	seen := make(map[uuid.UUID]bool)
+	ids := make([]uuid.UUID, 0)
+	for _, tx := range transactions {
+		if tx.SwapID != nil && !seen[*tx.SwapID] {
+			seen[*tx.SwapID] = true
+			ids = append(ids, *tx.SwapID)
+		}
+	}
+	return ids
+}
+
+// encodeCursor serializa un cursor para la respuesta; nil se devuelve como null.
+func encodeCursor(cursor *tradingDomain.TransactionCursor) *string {
+	if cursor == nil {
+		return nil
+	}
+	encoded := cursor.Encode()
+	return &encoded
+}
@@ -0,0 +1,166 @@
+package application
+
+import (
+	authDomain "cryptoproject/internal/auth/domain"
+	tradingDomain "cryptoproject/internal/trading/domain"
+	"cryptoproject/pkg/database"
+	"fmt"
+	"sort"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+)
+
+// HoldingDrift describe una diferencia entre la tenencia guardada y la reconstruida desde el historial.
+type HoldingDrift struct {
+	UserID   uuid.UUID
+	Coin     string
+	Stored   decimal.Decimal
+	Expected decimal.Decimal
+}
+
+// Difference devuelve cuánto sobra (positivo) o falta (negativo) en la tabla de tenencias.
+func (d HoldingDrift) Difference() decimal.Decimal {
+	return d.Stored.Sub(d.Expected)
+}
+
+// HoldingsReconciler reconstruye las tenencias desde el log de transacciones y las compara con la tabla holdings.
+type HoldingsReconciler struct {
+	uow             database.UnitOfWork
+	transactionRepo tradingDomain.TransactionRepository
+	holdingRepo     tradingDomain.HoldingRepository
+	userRepo        authDomain.UserRepository
+}
+
+// NewHoldingsReconciler crea una nueva instancia de HoldingsReconciler.
+func NewHoldingsReconciler(
+	uow database.UnitOfWork,
+	transactionRepo tradingDomain.TransactionRepository,
+	holdingRepo tradingDomain.HoldingRepository,
+	userRepo authDomain.UserRepository,
+) *HoldingsReconciler {
+	return &HoldingsReconciler{uow: uow, transactionRepo: transactionRepo, holdingRepo: holdingRepo, userRepo: userRepo}
+}
+
+// ReconcileAll revisa todos los usuarios con transacciones o tenencias. Si fix es true, reescribe
+// las tenencias de los usuarios con drift usando lo reconstruido desde el historial.
+func (r *HoldingsReconciler) ReconcileAll(fix bool) ([]HoldingDrift, error) {
+	userIDs, err := r.userIDs()
+	if err != nil {
+		return nil, err
+	}
+
+	var drifts []HoldingDrift
+	for _, userID := range userIDs {
+		userDrifts, err := r.ReconcileUser(userID, fix)
+		if err != nil {
+			return drifts, err
+		}
+		drifts = append(drifts, userDrifts...)
+	}
+	return drifts, nil
+}
+
+// ReconcileUser compara las tenencias de un usuario y opcionalmente las corrige.
+// Para corregir se bloquea la fila del usuario, igual que al operar, y la lectura, la comparación y la escritura
+// van en la misma transacción: así una compra o venta en curso no se pierde ni se cuenta a medias.
+// Solo se reescriben las monedas con drift; las demás, con su costo base, quedan como están.
+func (r *HoldingsReconciler) ReconcileUser(userID uuid.UUID, fix bool) ([]HoldingDrift, error) {
+	if !fix {
+		drifts, _, err := r.compare(r.transactionRepo, r.holdingRepo, userID)
+		return drifts, err
+	}
+
+	var drifts []HoldingDrift
+	err := r.uow.Do(func(tx *gorm.DB) error {
+		if _, err := r.userRepo.WithTx(tx).FindByIDForUpdate(userID.String()); err != nil {
+			return fmt.Errorf("error al bloquear el usuario %s: %w", userID, err)
+		}
+		holdings := r.holdingRepo.WithTx(tx)
+		var positions map[string]*tradingDomain.Position
+		var err error
+		drifts, positions, err = r.compare(r.transactionRepo.WithTx(tx), holdings, userID)
+		if err != nil {
+			return err
+		}
+
+		for _, drift := range drifts {
+			rebuilt := tradingDomain.Holding{UserID: userID, Coin: drift.Coin}
+			if position, exists := positions[drift.Coin]; exists {
+				rebuilt.Amount, rebuilt.CostBasis = position.Amount, position.CostBasis
+			}
+			if err := holdings.Save(&rebuilt); err != nil {
+				return fmt.Errorf("error al reescribir la tenencia %s de %s: %w", drift.Coin, userID, err)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return drifts, nil
+}
+
+// compare reconstruye las posiciones desde el historial y devuelve las monedas cuya tenencia guardada no coincide.
+func (r *HoldingsReconciler) compare(
+	transactionRepo tradingDomain.TransactionRepository,
+	holdingRepo tradingDomain.HoldingRepository,
+	userID uuid.UUID,
+) ([]HoldingDrift, map[string]*tradingDomain.Position, error) {
+	transactions, err := transactionRepo.FindByUserID(userID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("error al obtener transacciones de %s: %w", userID, err)
+	}
+	stored, err := holdingRepo.FindByUserID(userID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("error al obtener tenencias de %s: %w", userID, err)
+	}
+
+	positions := tradingDomain.BuildPositions(transactions)
+	storedByCoin := tradingDomain.HoldingsToMap(stored)
+
+	coins := make(map[string]struct{})
+	for coin := range positions {
+		coins[coin] = struct{}{}
+	}
+	for coin := range storedByCoin {
+		coins[coin] = struct{}{}
+	}
+
+	var drifts []HoldingDrift
+	for coin := range coins {
+		expected := decimal.Zero
+		if position, exists := positions[coin]; exists {
+			expected = position.Amount
+		}
+		// Con decimales exactos cualquier diferencia es drift real, no ruido de redondeo.
+		if !storedByCoin[coin].Equal(expected) {
+			drifts = append(drifts, HoldingDrift{UserID: userID, Coin: coin, Stored: storedByCoin[coin], Expected: expected})
+		}
+	}
+	sort.Slice(drifts, func(i, j int) bool { return drifts[i].Coin < drifts[j].Coin })
+	return drifts, positions, nil
+}
+
+// userIDs une los usuarios con transacciones y los que tienen tenencias (que podrían ser huérfanas).
+func (r *HoldingsReconciler) userIDs() ([]uuid.UUID, error) {
+	fromTransactions, err := r.transactionRepo.FindUserIDs()
+	if err != nil {
+		return nil, fmt.Errorf("error al listar usuarios con transacciones: %w", err)
+	}
+	fromHoldings, err := r.holdingRepo.FindUserIDs()
+	if err != nil {
+		return nil, fmt.Errorf("error al listar usuarios con tenencias: %w", err)
+	}
+
+	seen := make(map[uuid.UUID]struct{})
+	var userIDs []uuid.UUID
+	for _, userID := range append(fromTransactions, fromHoldings...) {
+		if _, exists := seen[userID]; !exists {
+			seen[userID] = struct{}{}
+			userIDs = append(userIDs, userID)
+		}
+	}
+	return userIDs, nil
+}
@@ -0,0 +1,65 @@
+package application_test
+
+import (
+	authInfra "cryptoproject/internal/auth/infrastructure"
+	tradingApp "cryptoproject/internal/trading/application"
+	tradingDomain "cryptoproject/internal/trading/domain"
+	tradingInfra "cryptoproject/internal/trading/infrastructure"
+	"cryptoproject/pkg/database"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+)
+
+// Con -fix solo se reescribe la moneda con drift: la otra conserva su costo base aunque no coincida con el historial.
+func TestReconcileUserFixesOnlyDrift(t *testing.T) {
+	db := testDB(t)
+	executor := newExecutor(db, nil)
+	user := createUser(t, db, decimal.NewFromInt(1000))
+	userID := uuid.MustParse(user.ID)
+
+	buys := []struct {
+		coin          string
+		amount, price int64
+	}{{"bitcoin", 2, 100}, {"ethereum", 3, 10}}
+	for _, buy := range buys {
+		if _, err := executor.Execute(user.ID, buy.coin, tradingDomain.SideBuy, tradingDomain.LiquidityTaker, decimal.NewFromInt(buy.amount), decimal.NewFromInt(buy.price)); err != nil {
+			t.Fatalf("no se pudo comprar %s: %v", buy.coin, err)
+		}
+	}
+
+	// Se rompe la cantidad de bitcoin y se ajusta a mano el costo base de ethereum.
+	db.Model(&tradingDomain.Holding{}).Where("user_id = ? AND coin = ?", userID, "bitcoin").Update("amount", decimal.NewFromInt(5))
+	db.Model(&tradingDomain.Holding{}).Where("user_id = ? AND coin = ?", userID, "ethereum").Update("cost_basis", decimal.NewFromInt(36))
+
+	reconciler := tradingApp.NewHoldingsReconciler(
+		database.NewUnitOfWork(db),
+		tradingInfra.NewTransactionRepository(db),
+		tradingInfra.NewHoldingRepository(db),
+		authInfra.NewUserRepository(db),
+	)
+	drifts, err := reconciler.ReconcileUser(userID, true)
+	if err != nil {
+		t.Fatalf("ReconcileUser: %v", err)
+	}
+	if len(drifts) != 1 || drifts[0].Coin != "bitcoin" || !drifts[0].Stored.Equal(decimal.NewFromInt(5)) || !drifts[0].Expected.Equal(decimal.NewFromInt(2)) {
+		t.Fatalf("drift = %+v, se esperaba solo bitcoin de 5 a 2", drifts)
+	}
+
+	holdings, err := tradingInfra.NewHoldingRepository(db).FindByUserID(userID)
+	if err != nil {
+		t.Fatalf("no se pudieron leer las tenencias: %v", err)
+	}
+	want := map[string][2]int64{"bitcoin": {2, 200}, "ethereum": {3, 36}}
+	for _, holding := range holdings {
+		expected := want[holding.Coin]
+		if !holding.Amount.Equal(decimal.NewFromInt(expected[0])) || !holding.CostBasis.Equal(decimal.NewFromInt(expected[1])) {
+			t.Fatalf("%s: cantidad %s, costo base %s; se esperaba %d y %d", holding.Coin, holding.Amount, holding.CostBasis, expected[0], expected[1])
+		}
+	}
+
+	if drifts, err := reconciler.ReconcileUser(userID, false); err != nil || len(drifts) != 0 {
+		t.Fatalf("después de corregir quedó drift: %+v, %v", drifts, err)
+	}
+}
@@ -0,0 +1,41 @@
+package application
+
+import (
+	authDomain "cryptoproject/internal/auth/domain"
+	"errors"
+	"testing"
+
+	"gorm.io/gorm"
+)
+
+// failingUsers devuelve siempre err al buscar al usuario.
+type failingUsers struct {
+	authDomain.UserRepository
+	err error
+}
+
+func (f failingUsers) FindByIDForUpdate(id string) (*authDomain.User, error) { return nil, f.err }
+func (f failingUsers) WithTx(tx *gorm.DB) authDomain.UserRepository          { return f }
+
+func TestLoadUserErrors(t *testing.T) {
+	lost := errors.New("conexión perdida")
+	tests := []struct {
+		name     string
+		err      error
+		notFound bool
+	}{
+		{name: "no existe", err: authDomain.ErrUserNotFound, notFound: true},
+		{name: "falla de la base", err: lost},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, _, err := loadUser(failingUsers{err: tt.err}, "u-1")
+			if errors.Is(err, ErrUserNotFound) != tt.notFound {
+				t.Fatalf("error = %v, ErrUserNotFound se esperaba %v", err, tt.notFound)
+			}
+			if !tt.notFound && !errors.Is(err, lost) {
+				t.Fatalf("error = %v, se esperaba que envolviera la falla original", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,155 @@
+package application
+
+import (
+	"context"
+	marketDomain "cryptoproject/internal/market/domain"
+	tradingDomain "cryptoproject/internal/trading/domain"
+	"cryptoproject/pkg/database"
+	"cryptoproject/pkg/logger"
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// OrderController maneja las órdenes límite: crearlas, listarlas y cancelarlas.
+// El llenado lo hace OrderMatcher en segundo plano.
+type OrderController struct {
+	uow       database.UnitOfWork
+	orderRepo tradingDomain.OrderRepository
+	matcher   *OrderMatcher
+	market    marketDomain.PriceProvider
+}
+
+// NewOrderController crea una nueva instancia de OrderController.
+func NewOrderController(
+	uow database.UnitOfWork,
+	orderRepo tradingDomain.OrderRepository,
+	matcher *OrderMatcher,
+	market marketDomain.PriceProvider,
+) *OrderController {
+	return &OrderController{uow: uow, orderRepo: orderRepo, matcher: matcher, market: market}
+}
+
+// errOrderNotFound se usa dentro de la unidad de trabajo para responder 404.
+var errOrderNotFound = errors.New("orden no encontrada")
+
+// HandleCreateOrder registra una orden límite de compra o venta.
+func (oc *OrderController) HandleCreateOrder(c *gin.Context) {
+	userUUID, err := uuid.Parse(c.GetString("user_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID de usuario inválido"})
+		return
+	}
+
+	amount, ok := parseAmount(c.PostForm("amount"))
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "La cantidad ingresada es inválida"})
+		return
+	}
+	limitPrice, ok := parseAmount(c.PostForm("limit_price"))
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "El precio límite es inválido"})
+		return
+	}
+
+	order, err := tradingDomain.NewOrder(
+		userUUID,
+		c.PostForm("coin"),
+		c.PostForm("side"),
+		amount,
+		limitPrice,
+		c.PostForm("time_in_force"),
+	)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := oc.orderRepo.Save(order); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error al registrar la orden"})
+		return
+	}
+
+	// Las IOC se evalúan una sola vez: o se llenan ahora o se vencen.
+	if order.TimeInForce == tradingDomain.TimeInForceIOC {
+		oc.fillImmediateOrCancel(c.Request.Context(), order)
+	}
+
+	c.JSON(http.StatusCreated, order)
+}
+
+// HandleListOrders devuelve las órdenes del usuario. Acepta ?status=open|filled|cancelled|expired|rejected.
+func (oc *OrderController) HandleListOrders(c *gin.Context) {
+	userUUID, err := uuid.Parse(c.GetString("user_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID de usuario inválido"})
+		return
+	}
+
+	orders, err := oc.orderRepo.FindByUserID(userUUID, c.Query("status"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error al obtener las órdenes"})
+		return
+	}
+
+	c.JSON(http.StatusOK, orders)
+}
+
+// HandleCancelOrder cancela una orden abierta del usuario.
+func (oc *OrderController) HandleCancelOrder(c *gin.Context) {
+	orderID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID de orden inválido"})
+		return
+	}
+
+	// Bloqueamos la orden para que el matcher no la llene mientras la cancelamos.
+	var order *tradingDomain.Order
+	err = oc.uow.Do(func(tx *gorm.DB) error {
+		orders := oc.orderRepo.WithTx(tx)
+
+		var err error
+		order, err = orders.FindByIDForUpdate(orderID)
+		// Si la orden es de otro usuario respondemos igual que si no existiera.
+		if err != nil || order.UserID.String() != c.GetString("user_id") {
+			return errOrderNotFound
+		}
+		if err := order.Cancel(); err != nil {
+			return err
+		}
+		return orders.Update(order)
+	})
+	switch {
+	case errors.Is(err, errOrderNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Orden no encontrada"})
+		return
+	case errors.Is(err, tradingDomain.ErrOrderNotOpen):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error al cancelar la orden"})
+		return
+	}
+
+	c.JSON(http.StatusOK, order)
+}
+
+// fillImmediateOrCancel intenta llenar una orden IOC con el precio actual y si no se puede la vence.
+// Si el cliente se va antes de tener el precio, la orden también se vence.
+func (oc *OrderController) fillImmediateOrCancel(ctx context.Context, order *tradingDomain.Order) {
+	price, err := fetchPrice(ctx, oc.market, order.Coin)
+	if err == nil {
+		if err := oc.matcher.TryFill(order, price); err != nil {
+			logger.Error("Error al llenar la orden IOC:", order.ID, err)
+		}
+	}
+
+	if order.Status == tradingDomain.OrderStatusOpen {
+		if err := oc.matcher.CloseIfOpen(order, tradingDomain.OrderStatusExpired, "orden IOC no ejecutable al precio actual"); err != nil {
+			logger.Error("Error al vencer la orden IOC:", order.ID, err)
+		}
+	}
+}
@@ -0,0 +1,156 @@
+package application
+
+import (
+	"context"
+	marketDomain "cryptoproject/internal/market/domain"
+	tradingDomain "cryptoproject/internal/trading/domain"
+	"cryptoproject/pkg/database"
+	"cryptoproject/pkg/logger"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+)
+
+// OrderMatcher es el worker que revisa precios y llena las órdenes límite cuyo precio se cruzó.
+// Pedimos un solo precio por moneda en cada vuelta para no gastar el rate limit de CoinGecko.
+type OrderMatcher struct {
+	uow       database.UnitOfWork
+	orderRepo tradingDomain.OrderRepository
+	executor  *TradeExecutor
+	market    marketDomain.PriceProvider
+	interval  time.Duration
+}
+
+// NewOrderMatcher crea una nueva instancia de OrderMatcher.
+func NewOrderMatcher(
+	uow database.UnitOfWork,
+	orderRepo tradingDomain.OrderRepository,
+	executor *TradeExecutor,
+	market marketDomain.PriceProvider,
+	interval time.Duration,
+) *OrderMatcher {
+	return &OrderMatcher{
+		uow:       uow,
+		orderRepo: orderRepo,
+		executor:  executor,
+		market:    market,
+		interval:  interval,
+	}
+}
+
+// Start corre el ciclo de matching hasta que se cancele el contexto.
+func (m *OrderMatcher) Start(ctx context.Context) {
+	logger.Info("Matcher de órdenes límite iniciado, intervalo:", m.interval)
+	ticker := time.NewTicker(m.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			logger.Info("Matcher de órdenes límite detenido")
+			return
+		case <-ticker.C:
+			m.MatchOpenOrders()
+		}
+	}
+}
+
+// MatchOpenOrders hace una vuelta completa: vence las órdenes viejas y llena las que se cruzaron.
+func (m *OrderMatcher) MatchOpenOrders() {
+	orders, err := m.orderRepo.FindOpen()
+	if err != nil {
+		logger.Error("Error al obtener órdenes abiertas:", err)
+		return
+	}
+
+	now := time.Now()
+	prices := make(map[string]decimal.Decimal)
+	for i := range orders {
+		order := &orders[i]
+
+		if order.IsExpired(now) {
+			if err := m.CloseIfOpen(order, tradingDomain.OrderStatusExpired, "la orden venció sin ejecutarse"); err != nil {
+				logger.Error("Error al vencer la orden:", order.ID, err)
+			}
+			continue
+		}
+
+		price, cached := prices[order.Coin]
+		if !cached {
+			price, err = fetchPrice(context.Background(), m.market, order.Coin)
+			if err != nil {
+				logger.Error(fmt.Sprintf("No se pudo obtener el precio de %s para el matching:", order.Coin), err)
+				continue
+			}
+			prices[order.Coin] = price
+		}
+
+		if err := m.TryFill(order, price); err != nil {
+			logger.Error("Error al llenar la orden:", order.ID, err)
+		}
+	}
+}
+
+// TryFill ejecuta la orden al precio de mercado si el límite se cruzó.
+// La operación y el cambio de estado de la orden se guardan en la misma unidad de trabajo.
+// Si no hay saldo o cripto suficiente al momento de llenarla, la orden queda rechazada.
+func (m *OrderMatcher) TryFill(order *tradingDomain.Order, marketPrice decimal.Decimal) error {
+	if order.Status != tradingDomain.OrderStatusOpen || !order.IsCrossedBy(marketPrice) {
+		return nil
+	}
+
+	err := m.uow.Do(func(tx *gorm.DB) error {
+		orders := m.orderRepo.WithTx(tx)
+
+		// Releemos y bloqueamos la orden por si el usuario la canceló mientras procesábamos la vuelta.
+		current, err := orders.FindByIDForUpdate(order.ID)
+		if err != nil {
+			return err
+		}
+		if current.Status != tradingDomain.OrderStatusOpen {
+			*order = *current
+			return nil
+		}
+
+		result, err := m.executor.ExecuteTx(tx, order.UserID.String(), order.Coin, order.Side, order.Liquidity(), order.Amount, marketPrice)
+		if err != nil {
+			return err
+		}
+		order.MarkFilled(result.Transaction.ID)
+		return orders.Update(order)
+	})
+
+	switch {
+	case errors.Is(err, ErrInsufficientBalance), errors.Is(err, ErrInsufficientHoldings), errors.Is(err, ErrFeeExceedsProceeds), errors.Is(err, ErrUserNotFound):
+		return m.CloseIfOpen(order, tradingDomain.OrderStatusRejected, err.Error())
+	case err != nil:
+		return err
+	}
+
+	if order.Status == tradingDomain.OrderStatusFilled {
+		logger.Info(fmt.Sprintf("Orden %s llenada a %s", order.ID, marketPrice))
+	}
+	return nil
+}
+
+// CloseIfOpen cierra la orden con status y reason, releyéndola con bloqueo en su propia unidad de trabajo.
+// Si mientras tanto el usuario la canceló o se llenó, se deja como está; en los dos casos order queda con lo guardado.
+func (m *OrderMatcher) CloseIfOpen(order *tradingDomain.Order, status, reason string) error {
+	return m.uow.Do(func(tx *gorm.DB) error {
+		orders := m.orderRepo.WithTx(tx)
+
+		current, err := orders.FindByIDForUpdate(order.ID)
+		if err != nil {
+			return err
+		}
+		*order = *current
+		if order.Status != tradingDomain.OrderStatusOpen {
+			return nil
+		}
+		order.Close(status, reason)
+		return orders.Update(order)
+	})
+}
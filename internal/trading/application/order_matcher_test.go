@@ -0,0 +1,91 @@
+package application_test
+
+import (
+	tradingApp "cryptoproject/internal/trading/application"
+	tradingDomain "cryptoproject/internal/trading/domain"
+	tradingInfra "cryptoproject/internal/trading/infrastructure"
+	"cryptoproject/pkg/database"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+)
+
+// El matcher trabaja con la copia de la orden que leyó al empezar la vuelta. Si el usuario la cancela mientras tanto,
+// ni el vencimiento ni un intento de llenarla pueden pisar la cancelación.
+func TestMatcherDoesNotOverwriteCancelledOrder(t *testing.T) {
+	tests := []struct {
+		name  string
+		close func(matcher *tradingApp.OrderMatcher, order *tradingDomain.Order) error
+	}{
+		{name: "vencimiento", close: func(matcher *tradingApp.OrderMatcher, order *tradingDomain.Order) error {
+			return matcher.CloseIfOpen(order, tradingDomain.OrderStatusExpired, "la orden venció sin ejecutarse")
+		}},
+		{name: "intento de llenado", close: func(matcher *tradingApp.OrderMatcher, order *tradingDomain.Order) error {
+			return matcher.TryFill(order, decimal.NewFromInt(90))
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := testDB(t)
+			orderRepo := tradingInfra.NewOrderRepository(db)
+			matcher := tradingApp.NewOrderMatcher(database.NewUnitOfWork(db), orderRepo, newExecutor(db, nil), nil, time.Minute)
+			user := createUser(t, db, decimal.NewFromInt(10)) // No alcanza para la compra: si se evaluara, se rechazaría.
+
+			order, err := tradingDomain.NewOrder(uuid.MustParse(user.ID), "bitcoin", tradingDomain.SideBuy, decimal.NewFromInt(1), decimal.NewFromInt(100), tradingDomain.TimeInForceGTC)
+			if err != nil {
+				t.Fatalf("NewOrder: %v", err)
+			}
+			if err := orderRepo.Save(order); err != nil {
+				t.Fatalf("no se pudo guardar la orden: %v", err)
+			}
+			stale := *order
+
+			if err := order.Cancel(); err != nil {
+				t.Fatalf("Cancel: %v", err)
+			}
+			if err := orderRepo.Update(order); err != nil {
+				t.Fatalf("no se pudo cancelar la orden: %v", err)
+			}
+
+			if err := tt.close(matcher, &stale); err != nil {
+				t.Fatalf("error inesperado: %v", err)
+			}
+			stored, err := orderRepo.FindByID(order.ID)
+			if err != nil {
+				t.Fatalf("no se pudo leer la orden: %v", err)
+			}
+			if stored.Status != tradingDomain.OrderStatusCancelled || stale.Status != tradingDomain.OrderStatusCancelled {
+				t.Fatalf("estado guardado %s, en memoria %s; se esperaba cancelled en los dos", stored.Status, stale.Status)
+			}
+		})
+	}
+}
+
+// Una orden que sigue abierta sí se rechaza si al cruzarse no hay saldo.
+func TestMatcherRejectsOpenOrderWithoutBalance(t *testing.T) {
+	db := testDB(t)
+	orderRepo := tradingInfra.NewOrderRepository(db)
+	matcher := tradingApp.NewOrderMatcher(database.NewUnitOfWork(db), orderRepo, newExecutor(db, nil), nil, time.Minute)
+	user := createUser(t, db, decimal.NewFromInt(10))
+
+	order, err := tradingDomain.NewOrder(uuid.MustParse(user.ID), "bitcoin", tradingDomain.SideBuy, decimal.NewFromInt(1), decimal.NewFromInt(100), tradingDomain.TimeInForceGTC)
+	if err != nil {
+		t.Fatalf("NewOrder: %v", err)
+	}
+	if err := orderRepo.Save(order); err != nil {
+		t.Fatalf("no se pudo guardar la orden: %v", err)
+	}
+
+	if err := matcher.TryFill(order, decimal.NewFromInt(90)); err != nil {
+		t.Fatalf("TryFill: %v", err)
+	}
+	stored, err := orderRepo.FindByID(order.ID)
+	if err != nil {
+		t.Fatalf("no se pudo leer la orden: %v", err)
+	}
+	if stored.Status != tradingDomain.OrderStatusRejected {
+		t.Fatalf("estado guardado %s, se esperaba rejected", stored.Status)
+	}
+}
@@ -0,0 +1,114 @@
+package application
+
+import (
+	"context"
+	marketDomain "cryptoproject/internal/market/domain"
+	tradingDomain "cryptoproject/internal/trading/domain"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// ErrStalePrice se devuelve cuando el proveedor solo tiene un precio vencido (más viejo que el TTL de su caché).
+// Sirve para mostrarlo, pero no para operar.
+var ErrStalePrice = errors.New("el precio de mercado está desactualizado")
+
+// fetchQuote pide la cotización en USD de una moneda y rechaza las vencidas. ctx es el del pedido HTTP
+// (o context.Background() en los workers): si el cliente se va, se deja de esperar al proveedor.
+func fetchQuote(ctx context.Context, market marketDomain.PriceProvider, coin string) (marketDomain.PriceQuote, error) {
+	quote, err := market.GetQuoteContext(ctx, coin, tradingDomain.QuoteAsset)
+	if err != nil {
+		return marketDomain.PriceQuote{}, err
+	}
+	if quote.Stale {
+		return marketDomain.PriceQuote{}, fmt.Errorf("%w: %s tiene %s", ErrStalePrice, coin, quote.Age(time.Now()).Round(time.Second))
+	}
+	return quote, nil
+}
+
+// fetchPrice pide el precio en USD de una moneda y lo pasa a decimal con la precisión de precios.
+// Los proveedores de mercado entregan float64; esta es la única frontera donde convertimos.
+func fetchPrice(ctx context.Context, market marketDomain.PriceProvider, coin string) (decimal.Decimal, error) {
+	quote, err := fetchQuote(ctx, market, coin)
+	if err != nil {
+		return decimal.Zero, err
+	}
+	return tradingDomain.PriceFromFloat(quote.Price), nil
+}
+
+// marketFill es el precio al que se ejecuta una orden a mercado después de aplicar el deslizamiento.
+type marketFill struct {
+	MarketPrice decimal.Decimal // Precio del proveedor de mercado.
+	Price       decimal.Decimal // Precio de ejecución.
+	Slippage    decimal.Decimal // Deslizamiento como fracción del precio de mercado.
+}
+
+// SlippagePct devuelve el deslizamiento en porcentaje, que es como lo ve el usuario.
+func (f *marketFill) SlippagePct() decimal.Decimal {
+	return f.Slippage.Mul(decimal.NewFromInt(100))
+}
+
+// fetchFillPrice calcula el precio de ejecución de una orden a mercado según su tamaño frente al volumen de 24h.
+// Si el modelo está apagado no pide el volumen y ejecuta al precio de mercado.
+func fetchFillPrice(ctx context.Context, market marketDomain.PriceProvider, model tradingDomain.SlippageModel, coin, side string, amount decimal.Decimal) (*marketFill, error) {
+	return fetchFill(ctx, market, model, coin, side, func(marketPrice decimal.Decimal) decimal.Decimal {
+		return marketPrice.Mul(amount)
+	})
+}
+
+// fetchFillPriceForNotional es igual que fetchFillPrice pero para órdenes que se definen por monto en USD
+// (las compras recurrentes), donde la cantidad recién se conoce después de tener el precio.
+func fetchFillPriceForNotional(ctx context.Context, market marketDomain.PriceProvider, model tradingDomain.SlippageModel, coin, side string, notional decimal.Decimal) (*marketFill, error) {
+	return fetchFill(ctx, market, model, coin, side, func(decimal.Decimal) decimal.Decimal {
+		return notional
+	})
+}
+
+func fetchFill(ctx context.Context, market marketDomain.PriceProvider, model tradingDomain.SlippageModel, coin, side string, notional func(marketPrice decimal.Decimal) decimal.Decimal) (*marketFill, error) {
+	quote, err := fetchQuote(ctx, market, coin)
+	if err != nil {
+		return nil, err
+	}
+	return fillAt(model, quote, side, notional), nil
+}
+
+// fillAt aplica el deslizamiento a una cotización ya obtenida. Sirve cuando la cantidad recién se conoce
+// dentro de la transacción (ej. una regla de salida que vende lo que quede); si el modelo está apagado
+// se ejecuta al precio de mercado.
+func fillAt(model tradingDomain.SlippageModel, quote marketDomain.PriceQuote, side string, notional func(marketPrice decimal.Decimal) decimal.Decimal) *marketFill {
+	marketPrice := tradingDomain.PriceFromFloat(quote.Price)
+	if !model.Enabled() {
+		return &marketFill{MarketPrice: marketPrice, Price: marketPrice, Slippage: decimal.Zero}
+	}
+	impact := model.Impact(notional(marketPrice), decimal.NewFromFloat(quote.Volume24h))
+	return &marketFill{
+		MarketPrice: marketPrice,
+		Price:       tradingDomain.ExecutionPrice(marketPrice, impact, side),
+		Slippage:    impact,
+	}
+}
+
+// parseMaxSlippage lee la tolerancia opcional del usuario, en porcentaje. Vacío (nil) significa sin tolerancia;
+// 0 es válido y significa que no se acepta ningún deslizamiento. Los negativos no tienen sentido y se rechazan.
+func parseMaxSlippage(value string) (*decimal.Decimal, bool) {
+	if value == "" {
+		return nil, true
+	}
+	maxSlippage, err := decimal.NewFromString(value)
+	if err != nil || maxSlippage.IsNegative() {
+		return nil, false
+	}
+	return &maxSlippage, true
+}
+
+// parseAmount convierte un valor de formulario a decimal y valida que sea positivo.
+// No usamos strconv.ParseFloat para no meter error de representación antes de empezar.
+func parseAmount(value string) (decimal.Decimal, bool) {
+	amount, err := decimal.NewFromString(value)
+	if err != nil || !amount.IsPositive() {
+		return decimal.Zero, false
+	}
+	return amount, true
+}
@@ -0,0 +1,69 @@
+package application
+
+import (
+	marketDomain "cryptoproject/internal/market/domain"
+	tradingDomain "cryptoproject/internal/trading/domain"
+	"errors"
+	"testing"
+
+	"github.com/shopspring/decimal"
+)
+
+func TestParseMaxSlippage(t *testing.T) {
+	tests := []struct {
+		value    string
+		ok       bool
+		impact   string // Deslizamiento estimado como fracción.
+		exceeded bool
+	}{
+		{value: "", ok: true, impact: "0.5"},
+		{value: "0", ok: true, impact: "0"},
+		{value: "0", ok: true, impact: "0.0001", exceeded: true},
+		{value: "0.5", ok: true, impact: "0.005"},
+		{value: "0.5", ok: true, impact: "0.006", exceeded: true},
+		{value: "-0.1", ok: false},
+		{value: "abc", ok: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.value+"/"+tt.impact, func(t *testing.T) {
+			maxSlippage, ok := parseMaxSlippage(tt.value)
+			if ok != tt.ok {
+				t.Fatalf("parseMaxSlippage(%q) ok = %v, se esperaba %v", tt.value, ok, tt.ok)
+			}
+			if !ok {
+				return
+			}
+			err := tradingDomain.CheckSlippageTolerance(decimal.RequireFromString(tt.impact), maxSlippage)
+			if exceeded := errors.Is(err, tradingDomain.ErrSlippageExceeded); exceeded != tt.exceeded {
+				t.Fatalf("con tolerancia %q e impacto %s, superada = %v; se esperaba %v", tt.value, tt.impact, exceeded, tt.exceeded)
+			}
+		})
+	}
+}
+
+// fillAt es lo que usan las reglas de salida: con el modelo apagado se ejecuta al precio de mercado.
+func TestFillAt(t *testing.T) {
+	model := tradingDomain.SlippageModel{ImpactFactor: decimal.RequireFromString("0.1"), MaxImpact: decimal.RequireFromString("0.1")}
+	tests := []struct {
+		name   string
+		model  tradingDomain.SlippageModel
+		volume float64
+		side   string
+		price  string
+	}{
+		{name: "modelo apagado", volume: 1000000, side: tradingDomain.SideSell, price: "100"},
+		{name: "venta", model: model, volume: 1000000, side: tradingDomain.SideSell, price: "99"},
+		{name: "compra", model: model, volume: 1000000, side: tradingDomain.SideBuy, price: "101"},
+		{name: "sin volumen se usa el tope", model: model, side: tradingDomain.SideSell, price: "90"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			quote := marketDomain.PriceQuote{Price: 100, Volume24h: tt.volume}
+			// 100 unidades a 100 son el 1% del volumen: el impacto es 0.1 * raíz(0.01) = 1%.
+			fill := fillAt(tt.model, quote, tt.side, func(price decimal.Decimal) decimal.Decimal { return price.Mul(decimal.NewFromInt(100)) })
+			if !fill.MarketPrice.Equal(decimal.NewFromInt(100)) || !fill.Price.Equal(decimal.RequireFromString(tt.price)) {
+				t.Fatalf("precio de mercado %s, de ejecución %s; se esperaba 100 y %s", fill.MarketPrice, fill.Price, tt.price)
+			}
+		})
+	}
+}
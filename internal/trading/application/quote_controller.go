@@ -0,0 +1,197 @@
+package application
+
+import (
+	marketDomain "cryptoproject/internal/market/domain"
+	tradingDomain "cryptoproject/internal/trading/domain"
+	"cryptoproject/pkg/database"
+	"cryptoproject/pkg/logger"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// errQuoteNotFound se usa dentro de la unidad de trabajo para responder 404.
+var errQuoteNotFound = errors.New("cotización no encontrada")
+
+// QuoteController maneja el flujo cotizar-y-ejecutar: el usuario ve el precio antes de operar
+// y, si ejecuta a tiempo, se le respeta ese precio.
+type QuoteController struct {
+	uow       database.UnitOfWork
+	quoteRepo tradingDomain.QuoteRepository
+	executor  *TradeExecutor
+	market    marketDomain.PriceProvider
+	slippage  tradingDomain.SlippageModel
+	ttl       time.Duration
+}
+
+// NewQuoteController crea una nueva instancia de QuoteController. ttl es lo que dura vigente cada cotización.
+func NewQuoteController(
+	uow database.UnitOfWork,
+	quoteRepo tradingDomain.QuoteRepository,
+	executor *TradeExecutor,
+	market marketDomain.PriceProvider,
+	slippage tradingDomain.SlippageModel,
+	ttl time.Duration,
+) *QuoteController {
+	return &QuoteController{uow: uow, quoteRepo: quoteRepo, executor: executor, market: market, slippage: slippage, ttl: ttl}
+}
+
+// HandleCreateQuote cotiza una compra o venta al precio actual.
+func (qc *QuoteController) HandleCreateQuote(c *gin.Context) {
+	userUUID, err := uuid.Parse(c.GetString("user_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID de usuario inválido"})
+		return
+	}
+
+	amount, ok := parseAmount(c.PostForm("amount"))
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "La cantidad ingresada es inválida"})
+		return
+	}
+
+	coin := c.PostForm("coin")
+	side := c.DefaultPostForm("side", tradingDomain.SideBuy)
+	// Igual que en compra y venta, se rechaza lo inválido antes de pedir el precio al proveedor.
+	if err := tradingDomain.ValidateQuoteRequest(coin, side, amount); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	maxSlippage, ok := parseMaxSlippage(c.PostForm("max_slippage"))
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "La tolerancia de deslizamiento es inválida"})
+		return
+	}
+
+	// El precio cotizado ya incluye el deslizamiento por el tamaño de la orden.
+	fill, err := fetchFillPrice(c.Request.Context(), qc.market, qc.slippage, coin, side, amount)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "No se pudo obtener el precio actual"})
+		return
+	}
+	if err := tradingDomain.CheckSlippageTolerance(fill.Slippage, maxSlippage); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":        "El deslizamiento estimado supera la tolerancia indicada",
+			"slippage_pct": fill.SlippagePct(),
+		})
+		return
+	}
+
+	quote, err := tradingDomain.NewQuote(userUUID, coin, side, amount, fill.Price, qc.ttl)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	quote.MarketPrice = fill.MarketPrice
+
+	// El precio queda fijo; la comisión es una estimación con el volumen de hoy y se recalcula al ejecutar.
+	quote.Fee, err = qc.executor.EstimateFee(userUUID, quote.Total, tradingDomain.LiquidityTaker)
+	if err != nil {
+		logger.Error("Error al estimar la comisión:", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error al registrar la cotización"})
+		return
+	}
+	if err := qc.quoteRepo.Save(quote); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error al registrar la cotización"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"quote_id":     quote.ID,
+		"coin":         quote.Coin,
+		"side":         quote.Side,
+		"amount":       quote.Amount,
+		"price":        quote.Price,
+		"market_price": quote.MarketPrice,
+		"slippage_pct": fill.SlippagePct(),
+		"total":        quote.Total,
+		"fee":          quote.Fee,
+		"fee_asset":    tradingDomain.QuoteAsset,
+		"expires_at":   quote.ExpiresAt,
+	})
+}
+
+// HandleExecuteQuote ejecuta una cotización al precio cotizado si todavía está vigente y no se usó.
+// La cotización se bloquea y se marca como ejecutada en la misma unidad de trabajo que la operación.
+func (qc *QuoteController) HandleExecuteQuote(c *gin.Context) {
+	quoteID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID de cotización inválido"})
+		return
+	}
+	userID := c.GetString("user_id")
+
+	var (
+		quote   *tradingDomain.Quote
+		result  *TradeResult
+		expired bool
+	)
+	err = qc.uow.Do(func(tx *gorm.DB) error {
+		quotes := qc.quoteRepo.WithTx(tx)
+
+		var err error
+		quote, err = quotes.FindByIDForUpdate(quoteID)
+		// Si la cotización es de otro usuario respondemos igual que si no existiera.
+		if err != nil || quote.UserID.String() != userID {
+			return errQuoteNotFound
+		}
+
+		if err := quote.CheckExecutable(time.Now()); err != nil {
+			if !errors.Is(err, tradingDomain.ErrQuoteExpired) || quote.Status != tradingDomain.QuoteStatusOpen {
+				return err
+			}
+			// Dejamos registrado que venció sin usarse; la unidad de trabajo tiene que confirmar este cambio.
+			quote.Expire()
+			expired = true
+			return quotes.Update(quote)
+		}
+
+		result, err = qc.executor.ExecuteTx(tx, userID, quote.Coin, quote.Side, tradingDomain.LiquidityTaker, quote.Amount, quote.Price)
+		if err != nil {
+			return err
+		}
+		quote.MarkExecuted(result.Transaction.ID)
+		return quotes.Update(quote)
+	})
+	if expired {
+		err = tradingDomain.ErrQuoteExpired
+	}
+
+	switch {
+	case errors.Is(err, errQuoteNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Cotización no encontrada"})
+		return
+	case errors.Is(err, tradingDomain.ErrQuoteExpired), errors.Is(err, tradingDomain.ErrQuoteUsed):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	case err != nil:
+		respondTradeError(c, err)
+		return
+	}
+
+	response := gin.H{
+		"message": "Cotización ejecutada con éxito",
+		"user": gin.H{
+			"balance":        result.User.Balance,
+			"crypto_balance": result.User.CryptoHoldings,
+		},
+		"quote_id":    quote.ID,
+		"transaction": result.Transaction,
+		"fee":         result.Fee,
+		"fee_asset":   result.Transaction.FeeAsset,
+	}
+	if quote.Side == tradingDomain.SideSell {
+		response["proceeds"] = result.Total
+		response["net_proceeds"] = result.Net
+		response["cost_basis"] = result.CostBasis
+		response["realized_pnl"] = result.RealizedPnL
+	} else {
+		response["cost"] = result.Total
+		response["total_paid"] = result.Net
+	}
+	c.JSON(http.StatusOK, response)
+}
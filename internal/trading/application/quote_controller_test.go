@@ -0,0 +1,45 @@
+package application_test
+
+import (
+	tradingApp "cryptoproject/internal/trading/application"
+	tradingDomain "cryptoproject/internal/trading/domain"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// Un pedido inválido se rechaza con 400 antes de pedir el precio: el proveedor falla, así que si se lo llamara sería un 500.
+func TestCreateQuoteValidatesBeforePricing(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	controller := tradingApp.NewQuoteController(nil, nil, nil, &stubMarket{err: errors.New("proveedor caído")}, tradingDomain.SlippageModel{}, time.Minute)
+	router := gin.New()
+	router.POST("/quotes", func(c *gin.Context) { c.Set("user_id", uuid.NewString()) }, controller.HandleCreateQuote)
+
+	tests := []struct {
+		name string
+		form url.Values
+	}{
+		{name: "lado inválido", form: url.Values{"coin": {"bitcoin"}, "side": {"hold"}, "amount": {"1"}}},
+		{name: "demasiados decimales", form: url.Values{"coin": {"bitcoin"}, "side": {"sell"}, "amount": {"0.000000001"}}},
+		{name: "sin moneda", form: url.Values{"amount": {"1"}}},
+		{name: "cantidad inválida", form: url.Values{"coin": {"bitcoin"}, "amount": {"abc"}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/quotes", strings.NewReader(tt.form.Encode()))
+			req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+			if w.Code != http.StatusBadRequest {
+				t.Fatalf("estado = %d %s, se esperaba 400", w.Code, w.Body)
+			}
+		})
+	}
+}
@@ -0,0 +1,221 @@
+package application
+
+import (
+	tradingDomain "cryptoproject/internal/trading/domain"
+	"cryptoproject/pkg/database"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// recurringRunsLimit es cuántas ejecuciones devuelve el historial de una compra recurrente.
+const recurringRunsLimit = 100
+
+// errRecurringNotFound se usa dentro de la unidad de trabajo para responder 404.
+var errRecurringNotFound = errors.New("compra recurrente no encontrada")
+
+// RecurringController maneja las compras recurrentes (DCA): crearlas, listarlas, modificarlas y cancelarlas.
+// La ejecución la hace RecurringScheduler en segundo plano.
+type RecurringController struct {
+	uow           database.UnitOfWork
+	recurringRepo tradingDomain.RecurringOrderRepository
+}
+
+// NewRecurringController crea una nueva instancia de RecurringController.
+func NewRecurringController(uow database.UnitOfWork, recurringRepo tradingDomain.RecurringOrderRepository) *RecurringController {
+	return &RecurringController{uow: uow, recurringRepo: recurringRepo}
+}
+
+// HandleCreateRecurring registra una compra recurrente. schedule es una expresión cron de 5 campos en UTC
+// (ej. "0 9 * * 1" para los lunes a las 9) o un atajo como @daily.
+func (rc *RecurringController) HandleCreateRecurring(c *gin.Context) {
+	userUUID, err := uuid.Parse(c.GetString("user_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID de usuario inválido"})
+		return
+	}
+
+	quoteAmount, ok := parseAmount(c.PostForm("amount_usd"))
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "El monto ingresado es inválido"})
+		return
+	}
+
+	order, err := tradingDomain.NewRecurringOrder(userUUID, c.PostForm("coin"), quoteAmount, c.PostForm("schedule"), time.Now())
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := rc.recurringRepo.Save(order); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error al registrar la compra recurrente"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, order)
+}
+
+// HandleListRecurring devuelve las compras recurrentes del usuario.
+func (rc *RecurringController) HandleListRecurring(c *gin.Context) {
+	userUUID, err := uuid.Parse(c.GetString("user_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID de usuario inválido"})
+		return
+	}
+
+	orders, err := rc.recurringRepo.FindByUserID(userUUID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error al obtener las compras recurrentes"})
+		return
+	}
+
+	c.JSON(http.StatusOK, orders)
+}
+
+// HandleUpdateRecurring cambia el monto, la programación o el estado (active|paused) de una compra recurrente.
+// Todos los campos son opcionales.
+func (rc *RecurringController) HandleUpdateRecurring(c *gin.Context) {
+	orderID, ok := parseRecurringID(c)
+	if !ok {
+		return
+	}
+
+	quoteAmountStr := c.PostForm("amount_usd")
+	schedule := c.PostForm("schedule")
+	status := c.PostForm("status")
+	if status == tradingDomain.RecurringStatusCancelled {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Para cancelar la compra recurrente usa DELETE"})
+		return
+	}
+
+	order, err := rc.modify(c, orderID, func(order *tradingDomain.RecurringOrder, now time.Time) error {
+		if order.Status == tradingDomain.RecurringStatusCancelled {
+			return tradingDomain.ErrRecurringCancelled
+		}
+		if quoteAmountStr != "" {
+			quoteAmount, ok := parseAmount(quoteAmountStr)
+			if !ok {
+				return invalidInputError{errors.New("el monto ingresado es inválido")}
+			}
+			if err := order.SetQuoteAmount(quoteAmount); err != nil {
+				return invalidInputError{err}
+			}
+		}
+		if schedule != "" {
+			if err := order.SetSchedule(schedule, now); err != nil {
+				return invalidInputError{err}
+			}
+		}
+		if status != "" {
+			if err := order.SetStatus(status, now); err != nil {
+				return invalidInputError{err}
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		rc.respondError(c, err, "Error al actualizar la compra recurrente")
+		return
+	}
+
+	c.JSON(http.StatusOK, order)
+}
+
+// HandleCancelRecurring cancela una compra recurrente. Las ejecuciones pasadas se conservan.
+func (rc *RecurringController) HandleCancelRecurring(c *gin.Context) {
+	orderID, ok := parseRecurringID(c)
+	if !ok {
+		return
+	}
+
+	order, err := rc.modify(c, orderID, func(order *tradingDomain.RecurringOrder, now time.Time) error {
+		return order.SetStatus(tradingDomain.RecurringStatusCancelled, now)
+	})
+	if err != nil {
+		rc.respondError(c, err, "Error al cancelar la compra recurrente")
+		return
+	}
+
+	c.JSON(http.StatusOK, order)
+}
+
+// HandleRecurringRuns devuelve las últimas ejecuciones de una compra recurrente, con su resultado y motivo.
+func (rc *RecurringController) HandleRecurringRuns(c *gin.Context) {
+	orderID, ok := parseRecurringID(c)
+	if !ok {
+		return
+	}
+
+	order, err := rc.recurringRepo.FindByID(orderID)
+	// Si la compra es de otro usuario respondemos igual que si no existiera.
+	if err != nil || order.UserID.String() != c.GetString("user_id") {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Compra recurrente no encontrada"})
+		return
+	}
+
+	runs, err := rc.recurringRepo.FindRuns(order.ID, recurringRunsLimit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error al obtener las ejecuciones"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"recurring": order,
+		"runs":      runs,
+	})
+}
+
+// modify bloquea la compra recurrente, le aplica change y la guarda.
+// El bloqueo evita pisar una ejecución del scheduler que esté en curso.
+func (rc *RecurringController) modify(c *gin.Context, orderID uuid.UUID, change func(order *tradingDomain.RecurringOrder, now time.Time) error) (*tradingDomain.RecurringOrder, error) {
+	var order *tradingDomain.RecurringOrder
+	err := rc.uow.Do(func(tx *gorm.DB) error {
+		recurring := rc.recurringRepo.WithTx(tx)
+
+		var err error
+		order, err = recurring.FindByIDForUpdate(orderID)
+		// Si la compra es de otro usuario respondemos igual que si no existiera.
+		if err != nil || order.UserID.String() != c.GetString("user_id") {
+			return errRecurringNotFound
+		}
+		if err := change(order, time.Now()); err != nil {
+			return err
+		}
+		return recurring.Update(order)
+	})
+	return order, err
+}
+
+// invalidInputError marca los errores de validación que ocurren dentro de la unidad de trabajo para responder 400.
+type invalidInputError struct {
+	error
+}
+
+// respondError traduce los errores de modify a respuestas HTTP.
+func (rc *RecurringController) respondError(c *gin.Context, err error, message string) {
+	var invalid invalidInputError
+	switch {
+	case errors.As(err, &invalid):
+		c.JSON(http.StatusBadRequest, gin.H{"error": invalid.Error()})
+	case errors.Is(err, errRecurringNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Compra recurrente no encontrada"})
+	case errors.Is(err, tradingDomain.ErrRecurringCancelled):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": message})
+	}
+}
+
+// parseRecurringID lee el parámetro :id. Si es inválido ya deja escrita la respuesta.
+func parseRecurringID(c *gin.Context) (uuid.UUID, bool) {
+	orderID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID de compra recurrente inválido"})
+		return uuid.Nil, false
+	}
+	return orderID, true
+}
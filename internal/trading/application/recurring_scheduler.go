@@ -0,0 +1,188 @@
+package application
+
+import (
+	"context"
+	marketDomain "cryptoproject/internal/market/domain"
+	tradingDomain "cryptoproject/internal/trading/domain"
+	"cryptoproject/pkg/database"
+	"cryptoproject/pkg/logger"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// ErrRecurringAmountTooSmall se registra cuando el monto de la compra recurrente no alcanza ni para la unidad mínima.
+var ErrRecurringAmountTooSmall = errors.New("el monto no alcanza para comprar la cantidad mínima de la criptomoneda")
+
+// recurringClaimLease es cuánto tiempo queda tomada una compra mientras se pide su precio. Si la réplica que la tomó
+// se cae, pasado este tiempo la toma otra.
+const recurringClaimLease = 5 * time.Minute
+
+// RecurringScheduler es el worker que ejecuta las compras recurrentes vencidas.
+// Puede correr en varias réplicas a la vez: cada compra se marca como tomada (ClaimedUntil) antes de pedir el precio,
+// así las demás réplicas la saltean, y se vuelve a bloquear para ejecutarla comprobando que nadie la ejecutó
+// mientras tanto, así que dos réplicas nunca ejecutan la misma.
+type RecurringScheduler struct {
+	uow           database.UnitOfWork
+	recurringRepo tradingDomain.RecurringOrderRepository
+	executor      *TradeExecutor
+	market        marketDomain.PriceProvider
+	slippage      tradingDomain.SlippageModel
+	interval      time.Duration
+}
+
+// NewRecurringScheduler crea una nueva instancia de RecurringScheduler.
+func NewRecurringScheduler(
+	uow database.UnitOfWork,
+	recurringRepo tradingDomain.RecurringOrderRepository,
+	executor *TradeExecutor,
+	market marketDomain.PriceProvider,
+	slippage tradingDomain.SlippageModel,
+	interval time.Duration,
+) *RecurringScheduler {
+	return &RecurringScheduler{
+		uow:           uow,
+		recurringRepo: recurringRepo,
+		executor:      executor,
+		market:        market,
+		slippage:      slippage,
+		interval:      interval,
+	}
+}
+
+// Start corre el ciclo del scheduler hasta que se cancele el contexto.
+func (s *RecurringScheduler) Start(ctx context.Context) {
+	logger.Info("Scheduler de compras recurrentes iniciado, intervalo:", s.interval)
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			logger.Info("Scheduler de compras recurrentes detenido")
+			return
+		case <-ticker.C:
+			s.RunDue(ctx)
+		}
+	}
+}
+
+// RunDue ejecuta una por una las compras vencidas hasta que no quede ninguna libre.
+func (s *RecurringScheduler) RunDue(ctx context.Context) {
+	for ctx.Err() == nil {
+		ran, err := s.RunNext(time.Now())
+		if err != nil {
+			logger.Error("Error al ejecutar una compra recurrente:", err)
+			return
+		}
+		if !ran {
+			return
+		}
+	}
+}
+
+// RunNext toma una compra vencida, la ejecuta y registra el resultado. Devuelve false si no había nada para ejecutar.
+// El precio se pide fuera de la transacción, así la fila no queda bloqueada mientras se espera al proveedor.
+// Si el proveedor falla la compra no se da por fallida: se posterga una vuelta del scheduler (ver postpone).
+func (s *RecurringScheduler) RunNext(now time.Time) (bool, error) {
+	var claimed *tradingDomain.RecurringOrder
+	err := s.uow.Do(func(tx *gorm.DB) error {
+		recurring := s.recurringRepo.WithTx(tx)
+		var err error
+		claimed, err = recurring.ClaimDue(now)
+		if err != nil || claimed == nil {
+			return err
+		}
+		// El bloqueo de ClaimDue se suelta al confirmar; lo que mantiene a las demás réplicas afuera es la marca.
+		claimed.Claim(now.Add(recurringClaimLease))
+		return recurring.Update(claimed)
+	})
+	if err != nil || claimed == nil {
+		return false, err
+	}
+
+	fill, priceErr := fetchFillPriceForNotional(context.Background(), s.market, s.slippage, claimed.Coin, tradingDomain.SideBuy, claimed.QuoteAmount)
+	if priceErr != nil && !errors.Is(priceErr, marketDomain.ErrUnsupportedCoin) {
+		logger.Warn(fmt.Sprintf("Sin precio para la compra recurrente %s, se reintenta en %s: %s", claimed.ID, s.interval, priceErr))
+		return true, s.postpone(claimed, now.Add(s.interval))
+	}
+	if priceErr != nil {
+		priceErr = fmt.Errorf("no se pudo obtener el precio actual: %w", priceErr)
+	}
+
+	return true, s.uow.Do(func(tx *gorm.DB) error {
+		recurring := s.recurringRepo.WithTx(tx)
+
+		order, ok, err := lockClaimed(recurring, claimed)
+		if err != nil || !ok {
+			return err
+		}
+
+		// La compra corre en un savepoint: si falla (ej. saldo insuficiente) se deshace solo la compra
+		// y queda guardada la ejecución fallida con su motivo.
+		var transactionID *uuid.UUID
+		buyErr := priceErr
+		if buyErr == nil {
+			buyErr = tx.Transaction(func(savepoint *gorm.DB) error {
+				result, err := s.buy(savepoint, order, fill)
+				if err != nil {
+					return err
+				}
+				transactionID = &result.Transaction.ID
+				return nil
+			})
+		}
+
+		run := order.RecordRun(now, transactionID, buyErr)
+		if err := recurring.SaveRun(run); err != nil {
+			return err
+		}
+		if buyErr != nil {
+			logger.Info(fmt.Sprintf("Compra recurrente %s fallida: %s", order.ID, buyErr))
+		} else {
+			logger.Info(fmt.Sprintf("Compra recurrente %s ejecutada, transacción %s", order.ID, transactionID))
+		}
+		return recurring.Update(order)
+	})
+}
+
+// postpone corre la compra hasta at sin registrar una ejecución: una caída del proveedor no hace perder la compra.
+func (s *RecurringScheduler) postpone(claimed *tradingDomain.RecurringOrder, at time.Time) error {
+	return s.uow.Do(func(tx *gorm.DB) error {
+		recurring := s.recurringRepo.WithTx(tx)
+
+		order, ok, err := lockClaimed(recurring, claimed)
+		if err != nil || !ok {
+			return err
+		}
+		order.Postpone(at)
+		return recurring.Update(order)
+	})
+}
+
+// lockClaimed vuelve a leer con bloqueo la compra tomada por ClaimDue. Devuelve false si mientras se pedía
+// el precio otra réplica la ejecutó o el usuario la modificó, en cuyo caso no hay nada que hacer.
+func lockClaimed(recurring tradingDomain.RecurringOrderRepository, claimed *tradingDomain.RecurringOrder) (*tradingDomain.RecurringOrder, bool, error) {
+	order, err := recurring.FindByIDForUpdate(claimed.ID)
+	if err != nil {
+		return nil, false, err
+	}
+	if order.Status != tradingDomain.RecurringStatusActive || !order.NextRunAt.Equal(claimed.NextRunAt) {
+		return nil, false, nil
+	}
+	return order, true, nil
+}
+
+// buy compra por el monto en USD de la orden al precio ya obtenido (taker), con el deslizamiento que corresponda.
+// La cantidad se redondea hacia abajo para no gastar más de lo configurado.
+func (s *RecurringScheduler) buy(tx *gorm.DB, order *tradingDomain.RecurringOrder, fill *marketFill) (*TradeResult, error) {
+	amount := order.QuoteAmount.Div(fill.Price).RoundFloor(tradingDomain.AssetPlaces(order.Coin))
+	if !amount.IsPositive() {
+		return nil, ErrRecurringAmountTooSmall
+	}
+
+	return s.executor.ExecuteTx(tx, order.UserID.String(), order.Coin, tradingDomain.SideBuy, tradingDomain.LiquidityTaker, amount, fill.Price)
+}
@@ -0,0 +1,152 @@
+package application_test
+
+import (
+	"context"
+	marketDomain "cryptoproject/internal/market/domain"
+	tradingApp "cryptoproject/internal/trading/application"
+	tradingDomain "cryptoproject/internal/trading/domain"
+	tradingInfra "cryptoproject/internal/trading/infrastructure"
+	"cryptoproject/pkg/database"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+)
+
+// stubMarket responde siempre el mismo precio o el mismo error.
+type stubMarket struct {
+	price float64
+	err   error
+}
+
+func (m *stubMarket) Name() string { return "stub" }
+func (m *stubMarket) GetCurrentPrice(crypto, currency string) (float64, error) {
+	return m.price, m.err
+}
+func (m *stubMarket) GetPriceWithVolume(crypto, currency string) (float64, float64, error) {
+	return m.price, 0, m.err
+}
+func (m *stubMarket) GetQuote(crypto, currency string) (marketDomain.PriceQuote, error) {
+	return marketDomain.PriceQuote{Price: m.price, FetchedAt: time.Now(), Provider: "stub"}, m.err
+}
+func (m *stubMarket) GetQuoteContext(ctx context.Context, crypto, currency string) (marketDomain.PriceQuote, error) {
+	return m.GetQuote(crypto, currency)
+}
+func (m *stubMarket) GetPrices(cryptos, currencies []string) (map[string]map[string]float64, error) {
+	return nil, m.err
+}
+func (m *stubMarket) GetPricesContext(ctx context.Context, cryptos, currencies []string) (map[string]map[string]float64, error) {
+	return nil, m.err
+}
+func (m *stubMarket) GetHistoricalPrices(crypto, start, end string) ([]map[string]interface{}, error) {
+	return nil, m.err
+}
+func (m *stubMarket) GetHistoricalPricesContext(ctx context.Context, crypto, start, end string) ([]map[string]interface{}, error) {
+	return nil, m.err
+}
+func (m *stubMarket) CheckAPIStatus() bool { return m.err == nil }
+
+// Si el proveedor está caído la compra se posterga sin registrar una ejecución fallida,
+// y en la vuelta siguiente, con precio, se ejecuta.
+func TestRecurringSchedulerPostponesOnProviderError(t *testing.T) {
+	db := testDB(t)
+	user := createUser(t, db, decimal.NewFromInt(1000))
+	recurringRepo := tradingInfra.NewRecurringOrderRepository(db)
+
+	start := time.Date(2024, 1, 1, 9, 0, 0, 0, time.UTC)
+	order, err := tradingDomain.NewRecurringOrder(uuid.MustParse(user.ID), "bitcoin", decimal.NewFromInt(50), "@hourly", start.Add(-time.Minute))
+	if err != nil {
+		t.Fatalf("NewRecurringOrder: %v", err)
+	}
+	if err := recurringRepo.Save(order); err != nil {
+		t.Fatalf("no se pudo guardar la compra recurrente: %v", err)
+	}
+
+	market := &stubMarket{err: errors.New("proveedor caído")}
+	scheduler := tradingApp.NewRecurringScheduler(database.NewUnitOfWork(db), recurringRepo, newExecutor(db, nil), market, tradingDomain.SlippageModel{}, time.Minute)
+
+	ran, err := scheduler.RunNext(start)
+	if err != nil || !ran {
+		t.Fatalf("RunNext = %v, %v; se esperaba true, nil", ran, err)
+	}
+	runs, err := recurringRepo.FindRuns(order.ID, 10)
+	if err != nil {
+		t.Fatalf("FindRuns: %v", err)
+	}
+	if len(runs) != 0 {
+		t.Fatalf("se registraron %d ejecuciones con el proveedor caído, se esperaba ninguna", len(runs))
+	}
+	stored, err := recurringRepo.FindByID(order.ID)
+	if err != nil {
+		t.Fatalf("FindByID: %v", err)
+	}
+	if !stored.NextRunAt.Equal(start.Add(time.Minute)) {
+		t.Fatalf("próxima ejecución %v, se esperaba %v", stored.NextRunAt, start.Add(time.Minute))
+	}
+
+	// Antes de la vuelta siguiente no hay nada vencido.
+	if ran, err := scheduler.RunNext(start.Add(30 * time.Second)); err != nil || ran {
+		t.Fatalf("RunNext = %v, %v; se esperaba false, nil", ran, err)
+	}
+
+	market.price, market.err = 25000, nil
+	if ran, err := scheduler.RunNext(start.Add(time.Minute)); err != nil || !ran {
+		t.Fatalf("RunNext = %v, %v; se esperaba true, nil", ran, err)
+	}
+	runs, err = recurringRepo.FindRuns(order.ID, 10)
+	if err != nil {
+		t.Fatalf("FindRuns: %v", err)
+	}
+	if len(runs) != 1 || runs[0].Status != tradingDomain.RecurringRunSucceeded || runs[0].TransactionID == nil {
+		t.Fatalf("ejecuciones = %+v, se esperaba una exitosa", runs)
+	}
+}
+
+// Una compra tomada por una réplica que todavía está pidiendo el precio no la toma otra, aunque el bloqueo
+// de ClaimDue ya se haya soltado; si esa réplica se cae, la toma otra cuando vence la marca.
+func TestRecurringClaimSkipsClaimedOrders(t *testing.T) {
+	db := testDB(t)
+	user := createUser(t, db, decimal.NewFromInt(1000))
+	recurringRepo := tradingInfra.NewRecurringOrderRepository(db)
+
+	start := time.Date(2024, 1, 1, 9, 0, 0, 0, time.UTC)
+	order, err := tradingDomain.NewRecurringOrder(uuid.MustParse(user.ID), "bitcoin", decimal.NewFromInt(50), "@hourly", start.Add(-time.Minute))
+	if err != nil {
+		t.Fatalf("NewRecurringOrder: %v", err)
+	}
+	if err := recurringRepo.Save(order); err != nil {
+		t.Fatalf("no se pudo guardar la compra recurrente: %v", err)
+	}
+
+	// Una réplica la toma y se cae antes de ejecutarla.
+	err = db.Transaction(func(tx *gorm.DB) error {
+		claimed, err := recurringRepo.WithTx(tx).ClaimDue(start)
+		if err != nil || claimed == nil {
+			t.Fatalf("ClaimDue = %v, %v; se esperaba la compra", claimed, err)
+		}
+		claimed.Claim(start.Add(5 * time.Minute))
+		return recurringRepo.WithTx(tx).Update(claimed)
+	})
+	if err != nil {
+		t.Fatalf("no se pudo tomar la compra: %v", err)
+	}
+
+	market := &stubMarket{price: 25000}
+	scheduler := tradingApp.NewRecurringScheduler(database.NewUnitOfWork(db), recurringRepo, newExecutor(db, nil), market, tradingDomain.SlippageModel{}, time.Minute)
+	if ran, err := scheduler.RunNext(start.Add(time.Minute)); err != nil || ran {
+		t.Fatalf("RunNext = %v, %v; la compra tomada no se tenía que ejecutar", ran, err)
+	}
+	if ran, err := scheduler.RunNext(start.Add(5 * time.Minute)); err != nil || !ran {
+		t.Fatalf("RunNext = %v, %v; vencida la marca se tenía que ejecutar", ran, err)
+	}
+	stored, err := recurringRepo.FindByID(order.ID)
+	if err != nil {
+		t.Fatalf("FindByID: %v", err)
+	}
+	if stored.ClaimedUntil != nil || stored.LastRunAt == nil {
+		t.Fatalf("compra = %+v, se esperaba ejecutada y sin marca", stored)
+	}
+}
@@ -0,0 +1,112 @@
+package application_test
+
+import (
+	authDomain "cryptoproject/internal/auth/domain"
+	authInfra "cryptoproject/internal/auth/infrastructure"
+	ledgerApp "cryptoproject/internal/ledger/application"
+	ledgerDomain "cryptoproject/internal/ledger/domain"
+	ledgerInfra "cryptoproject/internal/ledger/infrastructure"
+	tradingApp "cryptoproject/internal/trading/application"
+	tradingDomain "cryptoproject/internal/trading/domain"
+	tradingInfra "cryptoproject/internal/trading/infrastructure"
+	"cryptoproject/pkg/database"
+	"cryptoproject/pkg/logger"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	gormLogger "gorm.io/gorm/logger"
+)
+
+// testDB abre la base de TEST_DATABASE_URL en un schema nuevo, con las tablas que usa el trading, y lo borra al terminar.
+// Sin TEST_DATABASE_URL el test se salta: los bloqueos de fila solo se pueden probar contra PostgreSQL de verdad.
+func testDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	dsn := os.Getenv("TEST_DATABASE_URL")
+	if dsn == "" {
+		t.Skip("TEST_DATABASE_URL no está definida, se salta el test contra PostgreSQL")
+	}
+	logger.InitLogger()
+	config := &gorm.Config{Logger: gormLogger.Default.LogMode(gormLogger.Silent)}
+
+	admin, err := gorm.Open(postgres.Open(dsn), config)
+	if err != nil {
+		t.Fatalf("no se pudo conectar a la base de test: %v", err)
+	}
+	schema := "test_" + strings.ReplaceAll(uuid.NewString(), "-", "")
+	if err := admin.Exec("CREATE SCHEMA " + schema).Error; err != nil {
+		t.Fatalf("no se pudo crear el schema de test: %v", err)
+	}
+
+	separator := " "
+	if strings.Contains(dsn, "://") {
+		separator = "?"
+		if strings.Contains(dsn, "?") {
+			separator = "&"
+		}
+	}
+	db, err := gorm.Open(postgres.Open(dsn+separator+"search_path="+schema), config)
+	if err != nil {
+		t.Fatalf("no se pudo conectar al schema de test: %v", err)
+	}
+	t.Cleanup(func() {
+		if sqlDB, err := db.DB(); err == nil {
+			sqlDB.Close()
+		}
+		admin.Exec("DROP SCHEMA " + schema + " CASCADE")
+		if sqlDB, err := admin.DB(); err == nil {
+			sqlDB.Close()
+		}
+	})
+
+	err = db.AutoMigrate(
+		&authDomain.User{},
+		&tradingDomain.Transaction{},
+		&tradingDomain.Holding{},
+		&tradingDomain.Order{},
+		&tradingDomain.RecurringOrder{},
+		&tradingDomain.RecurringRun{},
+		&ledgerDomain.Account{},
+		&ledgerDomain.Journal{},
+		&ledgerDomain.Entry{},
+	)
+	if err != nil {
+		t.Fatalf("no se pudieron crear las tablas: %v", err)
+	}
+	return db
+}
+
+// createUser guarda un usuario con el saldo dado.
+func createUser(t *testing.T, db *gorm.DB, balance decimal.Decimal) *authDomain.User {
+	t.Helper()
+	user, err := authDomain.NewUser("user-"+uuid.NewString()[:8], "secret")
+	if err != nil {
+		t.Fatalf("no se pudo armar el usuario: %v", err)
+	}
+	user.Balance = balance
+	if err := db.Create(user).Error; err != nil {
+		t.Fatalf("no se pudo guardar el usuario: %v", err)
+	}
+	return user
+}
+
+// newExecutor arma el ejecutor como en cmd/server, sin comisiones para que los montos sean redondos.
+// transactionRepo permite cambiar el repositorio de transacciones (por ejemplo, por uno que falla).
+func newExecutor(db *gorm.DB, transactionRepo tradingDomain.TransactionRepository) *tradingApp.TradeExecutor {
+	if transactionRepo == nil {
+		transactionRepo = tradingInfra.NewTransactionRepository(db)
+	}
+	ledger := ledgerApp.NewLedger(ledgerInfra.NewLedgerRepository(db), ledgerInfra.NewBalanceProjection())
+	return tradingApp.NewTradeExecutor(
+		database.NewUnitOfWork(db),
+		transactionRepo,
+		tradingInfra.NewHoldingRepository(db),
+		authInfra.NewUserRepository(db),
+		ledger,
+		tradingDomain.FeeSchedule{},
+	)
+}
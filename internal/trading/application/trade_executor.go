@@ -0,0 +1,448 @@
+package application
+
+import (
+	authDomain "cryptoproject/internal/auth/domain"
+	ledgerApp "cryptoproject/internal/ledger/application"
+	ledgerDomain "cryptoproject/internal/ledger/domain"
+	tradingDomain "cryptoproject/internal/trading/domain"
+	"cryptoproject/pkg/database"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+)
+
+// Errores de negocio que devuelve el ejecutor. Los controladores los traducen a códigos HTTP.
+var (
+	ErrUserNotFound         = errors.New("usuario no encontrado")
+	ErrInsufficientBalance  = errors.New("saldo insuficiente")
+	ErrInsufficientHoldings = errors.New("cantidad insuficiente de la criptomoneda")
+	ErrFeeExceedsProceeds   = errors.New("lo recibido por la venta no cubre la comisión")
+	ErrSwapTooSmall         = errors.New("el monto no alcanza para comprar la moneda destino")
+	ErrNoOpenPosition       = errors.New("no hay posición abierta para vender")
+)
+
+// TradeResult agrupa todo lo que produce una operación ejecutada.
+type TradeResult struct {
+	User        *authDomain.User
+	Transaction *tradingDomain.Transaction
+	Total       decimal.Decimal // price * amount en USD, ya redondeado a centavos, sin la comisión.
+	Fee         decimal.Decimal // Comisión en USD, aparte de Total.
+	Net         decimal.Decimal // Lo que realmente se debitó (compra: Total + Fee) o acreditó (venta: Total - Fee).
+	CostBasis   decimal.Decimal // Solo ventas: costo promedio de las unidades vendidas.
+	RealizedPnL decimal.Decimal // Solo ventas.
+}
+
+// SwapResult agrupa lo que produce un swap: la venta de la moneda de origen y la compra de la destino.
+type SwapResult struct {
+	User        *authDomain.User
+	SwapID      uuid.UUID
+	Sell        *tradingDomain.Transaction
+	Buy         *tradingDomain.Transaction
+	Proceeds    decimal.Decimal // USD que dejó la venta, antes de la comisión.
+	Fee         decimal.Decimal // Comisión del swap; se cobra una sola vez, en la venta.
+	Cost        decimal.Decimal // USD usados en la compra.
+	Remainder   decimal.Decimal // Centavos que no alcanzan para una unidad mínima de la moneda destino; quedan en el saldo USD.
+	RealizedPnL decimal.Decimal // P&L realizado de la moneda de origen.
+}
+
+// TradeExecutor ejecuta compras y ventas a un precio ya conocido.
+// Lo comparten el controlador (órdenes a mercado) y los workers de órdenes límite y reglas de salida.
+// Cada operación corre dentro de una unidad de trabajo: asiento contable, tenencia y transacción se guardan juntos o no se guarda nada.
+// El saldo en USD y la cantidad de cada tenencia los escribe el libro mayor; aquí solo se guarda el costo base.
+type TradeExecutor struct {
+	uow             database.UnitOfWork
+	transactionRepo tradingDomain.TransactionRepository
+	holdingRepo     tradingDomain.HoldingRepository
+	userRepo        authDomain.UserRepository
+	ledger          *ledgerApp.Ledger
+	fees            tradingDomain.FeeSchedule
+}
+
+// NewTradeExecutor crea una nueva instancia de TradeExecutor.
+func NewTradeExecutor(
+	uow database.UnitOfWork,
+	transactionRepo tradingDomain.TransactionRepository,
+	holdingRepo tradingDomain.HoldingRepository,
+	userRepo authDomain.UserRepository,
+	ledger *ledgerApp.Ledger,
+	fees tradingDomain.FeeSchedule,
+) *TradeExecutor {
+	return &TradeExecutor{
+		uow:             uow,
+		transactionRepo: transactionRepo,
+		holdingRepo:     holdingRepo,
+		userRepo:        userRepo,
+		ledger:          ledger,
+		fees:            fees,
+	}
+}
+
+// Buy debita el costo en USD y registra la compra a mercado (taker) en una sola transacción de base de datos.
+func (e *TradeExecutor) Buy(userID, coin string, amount, price decimal.Decimal) (*TradeResult, error) {
+	return e.Execute(userID, coin, tradingDomain.SideBuy, tradingDomain.LiquidityTaker, amount, price)
+}
+
+// Sell verifica las tenencias, acredita los USD y calcula el P&L de una venta a mercado (taker) en una sola transacción de base de datos.
+func (e *TradeExecutor) Sell(userID, coin string, amount, price decimal.Decimal) (*TradeResult, error) {
+	return e.Execute(userID, coin, tradingDomain.SideSell, tradingDomain.LiquidityTaker, amount, price)
+}
+
+// Execute despacha a compra o venta según el lado, abriendo su propia unidad de trabajo.
+func (e *TradeExecutor) Execute(userID, coin, side, liquidity string, amount, price decimal.Decimal) (*TradeResult, error) {
+	var result *TradeResult
+	err := e.uow.Do(func(tx *gorm.DB) error {
+		var err error
+		result, err = e.ExecuteTx(tx, userID, coin, side, liquidity, amount, price)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// ExecuteTx ejecuta la operación dentro de una transacción ya abierta.
+// Sirve para que quien llama guarde sus propios cambios (ej. el estado de una orden) en la misma unidad de trabajo.
+// liquidity (maker o taker) decide qué tasa de comisión se cobra.
+func (e *TradeExecutor) ExecuteTx(tx *gorm.DB, userID, coin, side, liquidity string, amount, price decimal.Decimal) (*TradeResult, error) {
+	repos := tradeRepos{
+		tx:           tx,
+		users:        e.userRepo.WithTx(tx),
+		transactions: e.transactionRepo.WithTx(tx),
+		holdings:     e.holdingRepo.WithTx(tx),
+		ledger:       e.ledger,
+		fees:         e.fees,
+		liquidity:    liquidity,
+	}
+	if side == tradingDomain.SideSell {
+		return repos.sell(userID, coin, amount, price)
+	}
+	return repos.buy(userID, coin, amount, price)
+}
+
+// SellUpToTx vende amount de coin a mercado dentro de una transacción ya abierta, o lo que quede si el usuario
+// tiene menos; amount cero vende toda la posición. La cantidad se decide después de bloquear al usuario y la tenencia,
+// así una venta a mano que llega al mismo tiempo no la deja desactualizada. Como el deslizamiento depende de
+// la cantidad, price da el precio de ejecución para la cantidad que finalmente se vende.
+func (e *TradeExecutor) SellUpToTx(tx *gorm.DB, userID, coin, liquidity string, amount decimal.Decimal, price func(amount decimal.Decimal) decimal.Decimal) (*TradeResult, error) {
+	repos := tradeRepos{
+		tx:           tx,
+		users:        e.userRepo.WithTx(tx),
+		transactions: e.transactionRepo.WithTx(tx),
+		holdings:     e.holdingRepo.WithTx(tx),
+		ledger:       e.ledger,
+		fees:         e.fees,
+		liquidity:    liquidity,
+		sellUpTo:     price,
+	}
+	return repos.sell(userID, coin, amount, decimal.Zero)
+}
+
+// Swap cambia amount de fromCoin por toCoin en una sola unidad de trabajo.
+// Se ejecuta como una venta y una compra a mercado (taker) a través de USD, unidas por el mismo SwapID.
+func (e *TradeExecutor) Swap(userID, fromCoin, toCoin string, amount, fromPrice, toPrice decimal.Decimal) (*SwapResult, error) {
+	var result *SwapResult
+	err := e.uow.Do(func(tx *gorm.DB) error {
+		repos := tradeRepos{
+			tx:           tx,
+			users:        e.userRepo.WithTx(tx),
+			transactions: e.transactionRepo.WithTx(tx),
+			holdings:     e.holdingRepo.WithTx(tx),
+			ledger:       e.ledger,
+			fees:         e.fees,
+			liquidity:    tradingDomain.LiquidityTaker,
+		}
+		var err error
+		result, err = repos.swap(userID, fromCoin, toCoin, amount, fromPrice, toPrice)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// EstimateFee calcula la comisión que se cobraría hoy por una operación de notional USD.
+// Sirve para mostrarla antes de ejecutar (ej. en una cotización); al ejecutar se vuelve a calcular.
+func (e *TradeExecutor) EstimateFee(userID uuid.UUID, notional decimal.Decimal, liquidity string) (decimal.Decimal, error) {
+	repos := tradeRepos{transactions: e.transactionRepo, fees: e.fees, liquidity: liquidity}
+	return repos.fee(userID, notional)
+}
+
+// tradeRepos agrupa los repositorios ya unidos a la transacción en curso.
+type tradeRepos struct {
+	tx           *gorm.DB
+	users        authDomain.UserRepository
+	transactions tradingDomain.TransactionRepository
+	holdings     tradingDomain.HoldingRepository
+	ledger       *ledgerApp.Ledger
+	fees         tradingDomain.FeeSchedule
+	liquidity    string
+	// sellUpTo, si está, recorta la venta a lo que tenga el usuario en vez de fallar y da el precio para
+	// la cantidad resultante (ver SellUpToTx).
+	sellUpTo func(amount decimal.Decimal) decimal.Decimal
+}
+
+func (r tradeRepos) buy(userID, coin string, amount, price decimal.Decimal) (*TradeResult, error) {
+	user, userUUID, err := loadUser(r.users, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	totalCost := tradingDomain.BuyCost(price, amount)
+	fee, err := r.fee(userUUID, totalCost)
+	if err != nil {
+		return nil, err
+	}
+	debit := totalCost.Add(fee)
+	if !user.IsBalanceSufficient(debit) {
+		return nil, ErrInsufficientBalance
+	}
+	if err := user.AdjustBalance(debit.Neg()); err != nil {
+		return nil, ErrInsufficientBalance
+	}
+
+	transaction := tradingDomain.NewTransaction(userUUID, coin, tradingDomain.SideBuy, amount, price)
+	transaction.SetFee(fee, r.liquidity)
+	holding, err := r.holdings.FindForUpdate(userUUID, coin)
+	if err != nil {
+		return nil, fmt.Errorf("error al obtener la tenencia: %w", err)
+	}
+	if _, err := holding.Apply(*transaction); err != nil {
+		return nil, ErrInsufficientHoldings
+	}
+	if err := r.post(transaction, totalCost, ErrInsufficientBalance); err != nil {
+		return nil, err
+	}
+	if err := r.holdings.Save(holding); err != nil {
+		return nil, fmt.Errorf("error al actualizar la tenencia: %w", err)
+	}
+	if err := r.transactions.Save(transaction); err != nil {
+		return nil, fmt.Errorf("error al registrar la transacción: %w", err)
+	}
+
+	if err := r.loadHoldings(user, userUUID); err != nil {
+		return nil, err
+	}
+	return &TradeResult{User: user, Transaction: transaction, Total: totalCost, Fee: fee, Net: debit}, nil
+}
+
+func (r tradeRepos) sell(userID, coin string, amount, price decimal.Decimal) (*TradeResult, error) {
+	user, userUUID, err := loadUser(r.users, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	holding, err := r.holdings.FindForUpdate(userUUID, coin)
+	if err != nil {
+		return nil, fmt.Errorf("error al obtener la tenencia: %w", err)
+	}
+	if r.sellUpTo != nil {
+		if amount.IsZero() || amount.GreaterThan(holding.Amount) {
+			amount = holding.Amount
+		}
+		if !amount.IsPositive() {
+			return nil, ErrNoOpenPosition
+		}
+		price = r.sellUpTo(amount)
+	}
+	if holding.Amount.LessThan(amount) {
+		return nil, ErrInsufficientHoldings
+	}
+
+	proceeds := tradingDomain.SellProceeds(price, amount)
+	fee, err := r.fee(userUUID, proceeds)
+	if err != nil {
+		return nil, err
+	}
+	if fee.GreaterThan(proceeds) {
+		return nil, ErrFeeExceedsProceeds
+	}
+	credit := proceeds.Sub(fee)
+
+	transaction := tradingDomain.NewTransaction(userUUID, coin, tradingDomain.SideSell, amount, price)
+	transaction.SetFee(fee, r.liquidity)
+	costBasis := holding.CostBasisFor(amount)
+	realizedPnL, err := holding.Apply(*transaction)
+	if err != nil {
+		return nil, ErrInsufficientHoldings
+	}
+
+	if err := user.AdjustBalance(credit); err != nil {
+		return nil, fmt.Errorf("error ajustando el saldo: %w", err)
+	}
+	if err := r.post(transaction, proceeds, ErrInsufficientHoldings); err != nil {
+		return nil, err
+	}
+	if err := r.holdings.Save(holding); err != nil {
+		return nil, fmt.Errorf("error al actualizar la tenencia: %w", err)
+	}
+	if err := r.transactions.Save(transaction); err != nil {
+		return nil, fmt.Errorf("error al registrar la transacción: %w", err)
+	}
+
+	if err := r.loadHoldings(user, userUUID); err != nil {
+		return nil, err
+	}
+	return &TradeResult{
+		User:        user,
+		Transaction: transaction,
+		Total:       proceeds,
+		Fee:         fee,
+		Net:         credit,
+		CostBasis:   costBasis,
+		RealizedPnL: realizedPnL,
+	}, nil
+}
+
+func (r tradeRepos) swap(userID, fromCoin, toCoin string, amount, fromPrice, toPrice decimal.Decimal) (*SwapResult, error) {
+	user, userUUID, err := loadUser(r.users, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	from, err := r.holdings.FindForUpdate(userUUID, fromCoin)
+	if err != nil {
+		return nil, fmt.Errorf("error al obtener la tenencia: %w", err)
+	}
+	if from.Amount.LessThan(amount) {
+		return nil, ErrInsufficientHoldings
+	}
+	to, err := r.holdings.FindForUpdate(userUUID, toCoin)
+	if err != nil {
+		return nil, fmt.Errorf("error al obtener la tenencia: %w", err)
+	}
+
+	proceeds := tradingDomain.SellProceeds(fromPrice, amount)
+	fee, err := r.fee(userUUID, proceeds)
+	if err != nil {
+		return nil, err
+	}
+	if fee.GreaterThanOrEqual(proceeds) {
+		return nil, ErrFeeExceedsProceeds
+	}
+	net := proceeds.Sub(fee)
+	toAmount := tradingDomain.SwapAmount(net, toPrice, toCoin)
+	if !toAmount.IsPositive() {
+		return nil, ErrSwapTooSmall
+	}
+	cost := tradingDomain.BuyCost(toPrice, toAmount)
+	remainder := net.Sub(cost)
+
+	swapID := uuid.New()
+	sellTx := tradingDomain.NewTransaction(userUUID, fromCoin, tradingDomain.SideSell, amount, fromPrice)
+	sellTx.SetFee(fee, r.liquidity)
+	sellTx.SwapID = &swapID
+	buyTx := tradingDomain.NewTransaction(userUUID, toCoin, tradingDomain.SideBuy, toAmount, toPrice)
+	buyTx.SetFee(decimal.Zero, r.liquidity)
+	buyTx.SwapID = &swapID
+	buyTx.Timestamp = sellTx.Timestamp
+
+	realizedPnL, err := from.Apply(*sellTx)
+	if err != nil {
+		return nil, ErrInsufficientHoldings
+	}
+	if _, err := to.Apply(*buyTx); err != nil {
+		return nil, ErrInsufficientHoldings
+	}
+
+	// Ambos asientos van antes de guardar las tenencias, igual que en una compra o venta sueltas.
+	if err := r.post(sellTx, proceeds, ErrInsufficientHoldings); err != nil {
+		return nil, err
+	}
+	if err := r.post(buyTx, cost, ErrInsufficientBalance); err != nil {
+		return nil, err
+	}
+	for _, holding := range []*tradingDomain.Holding{from, to} {
+		if err := r.holdings.Save(holding); err != nil {
+			return nil, fmt.Errorf("error al actualizar la tenencia: %w", err)
+		}
+	}
+	for _, transaction := range []*tradingDomain.Transaction{sellTx, buyTx} {
+		if err := r.transactions.Save(transaction); err != nil {
+			return nil, fmt.Errorf("error al registrar la transacción: %w", err)
+		}
+	}
+
+	if err := user.AdjustBalance(remainder); err != nil {
+		return nil, fmt.Errorf("error ajustando el saldo: %w", err)
+	}
+	if err := r.loadHoldings(user, userUUID); err != nil {
+		return nil, err
+	}
+	return &SwapResult{
+		User:        user,
+		SwapID:      swapID,
+		Sell:        sellTx,
+		Buy:         buyTx,
+		Proceeds:    proceeds,
+		Fee:         fee,
+		Cost:        cost,
+		Remainder:   remainder,
+		RealizedPnL: realizedPnL,
+	}, nil
+}
+
+// post registra el asiento de la operación en el libro mayor, que a su vez proyecta el saldo en USD y la cantidad de la tenencia.
+// Se llama antes de guardar la tenencia: si el libro mayor abre la cuenta de la moneda, lo hace con la cantidad previa a la operación.
+func (r tradeRepos) post(transaction *tradingDomain.Transaction, total decimal.Decimal, insufficient error) error {
+	journal := ledgerDomain.TradeJournal(
+		transaction.UserID,
+		transaction.Side == tradingDomain.SideBuy,
+		transaction.Coin,
+		transaction.Amount,
+		tradingDomain.QuoteAsset,
+		total,
+		transaction.Fee,
+		transaction.ID.String(),
+	)
+	if err := r.ledger.Post(r.tx, journal); err != nil {
+		if errors.Is(err, ledgerDomain.ErrInsufficientFunds) {
+			return insufficient
+		}
+		return fmt.Errorf("error al registrar el asiento: %w", err)
+	}
+	return nil
+}
+
+// fee calcula la comisión según el volumen del usuario en los últimos FeeWindowDays días, sin contar esta operación.
+func (r tradeRepos) fee(userID uuid.UUID, notional decimal.Decimal) (decimal.Decimal, error) {
+	since := time.Now().AddDate(0, 0, -tradingDomain.FeeWindowDays)
+	volume, err := r.transactions.SumVolumeSince(userID, since)
+	if err != nil {
+		return decimal.Zero, fmt.Errorf("error al calcular el volumen operado: %w", err)
+	}
+	return r.fees.Fee(notional, volume, r.liquidity), nil
+}
+
+// loadHoldings llena user.CryptoHoldings con lo que quedó guardado, para devolverlo en la respuesta.
+func (r tradeRepos) loadHoldings(user *authDomain.User, userID uuid.UUID) error {
+	holdings, err := r.holdings.FindByUserID(userID)
+	if err != nil {
+		return fmt.Errorf("error al obtener las tenencias: %w", err)
+	}
+	user.CryptoHoldings = tradingDomain.HoldingsToMap(holdings)
+	return nil
+}
+
+// loadUser busca y bloquea al usuario, y parsea su ID, que necesitamos como UUID para las transacciones.
+// El bloqueo serializa las operaciones concurrentes del mismo usuario, así nadie gasta dos veces el mismo saldo.
+// Solo la fila inexistente es ErrUserNotFound; una falla de la base se devuelve envuelta para que no pase por un 404.
+func loadUser(users authDomain.UserRepository, userID string) (*authDomain.User, uuid.UUID, error) {
+	user, err := users.FindByIDForUpdate(userID)
+	if errors.Is(err, authDomain.ErrUserNotFound) {
+		return nil, uuid.Nil, ErrUserNotFound
+	}
+	if err != nil {
+		return nil, uuid.Nil, fmt.Errorf("error al obtener el usuario: %w", err)
+	}
+	userUUID, err := uuid.Parse(user.ID)
+	if err != nil {
+		return nil, uuid.Nil, fmt.Errorf("ID de usuario inválido: %w", err)
+	}
+	return user, userUUID, nil
+}
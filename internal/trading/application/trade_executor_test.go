@@ -0,0 +1,206 @@
+package application_test
+
+import (
+	authDomain "cryptoproject/internal/auth/domain"
+	tradingApp "cryptoproject/internal/trading/application"
+	tradingDomain "cryptoproject/internal/trading/domain"
+	tradingInfra "cryptoproject/internal/trading/infrastructure"
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+)
+
+// Compras en paralelo contra un mismo saldo: el bloqueo del usuario tiene que serializarlas,
+// así se ejecutan exactamente las que alcanzan y el saldo nunca queda negativo.
+func TestExecuteConcurrentBuysNeverOverspend(t *testing.T) {
+	db := testDB(t)
+	executor := newExecutor(db, nil)
+
+	balance := decimal.NewFromInt(1050)
+	price := decimal.NewFromInt(100)
+	amount := decimal.NewFromInt(1)
+	user := createUser(t, db, balance)
+
+	const buyers = 25
+	expected := balance.Div(price.Mul(amount)).Floor().IntPart() // 10 compras de 100 USD.
+
+	var wg sync.WaitGroup
+	errs := make(chan error, buyers)
+	for i := 0; i < buyers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := executor.Execute(user.ID, "bitcoin", tradingDomain.SideBuy, tradingDomain.LiquidityTaker, amount, price)
+			errs <- err
+		}()
+	}
+	wg.Wait()
+	close(errs)
+
+	var succeeded int64
+	for err := range errs {
+		switch {
+		case err == nil:
+			succeeded++
+		case errors.Is(err, tradingApp.ErrInsufficientBalance):
+		default:
+			t.Fatalf("error inesperado en una compra: %v", err)
+		}
+	}
+	if succeeded != expected {
+		t.Fatalf("se ejecutaron %d compras, se esperaban %d", succeeded, expected)
+	}
+
+	var stored authDomain.User
+	if err := db.First(&stored, "id = ?", user.ID).Error; err != nil {
+		t.Fatalf("no se pudo leer el usuario: %v", err)
+	}
+	wantBalance := balance.Sub(price.Mul(amount).Mul(decimal.NewFromInt(expected)))
+	if stored.Balance.IsNegative() || !stored.Balance.Equal(wantBalance) {
+		t.Fatalf("saldo final %s, se esperaba %s", stored.Balance, wantBalance)
+	}
+
+	var holding tradingDomain.Holding
+	if err := db.First(&holding, "user_id = ? AND coin = ?", user.ID, "bitcoin").Error; err != nil {
+		t.Fatalf("no se pudo leer la tenencia: %v", err)
+	}
+	if !holding.Amount.Equal(decimal.NewFromInt(expected)) {
+		t.Fatalf("tenencia final %s, se esperaba %d", holding.Amount, expected)
+	}
+
+	var transactions int64
+	db.Model(&tradingDomain.Transaction{}).Where("user_id = ?", user.ID).Count(&transactions)
+	if transactions != expected {
+		t.Fatalf("se registraron %d transacciones, se esperaban %d", transactions, expected)
+	}
+
+	// El saldo corrido del libro mayor tampoco pasó nunca por debajo de cero.
+	var negative int64
+	db.Table("ledger_entries").Where("balance_after < 0").Count(&negative)
+	if negative != 0 {
+		t.Fatalf("hay %d movimientos con saldo negativo en el libro mayor", negative)
+	}
+}
+
+// failingTransactions deja pasar todo salvo Save, para que la operación falle después de mover el saldo y la tenencia.
+type failingTransactions struct {
+	tradingDomain.TransactionRepository
+}
+
+var errSaveFailed = errors.New("falla forzada al guardar la transacción")
+
+func (r failingTransactions) Save(*tradingDomain.Transaction) error {
+	return errSaveFailed
+}
+
+func (r failingTransactions) WithTx(tx *gorm.DB) tradingDomain.TransactionRepository {
+	return failingTransactions{r.TransactionRepository.WithTx(tx)}
+}
+
+// Si algo falla a mitad de la operación, la unidad de trabajo revierte todo: saldo, tenencia, transacciones y libro mayor.
+func TestExecuteRollsBackWhenTransactionInsertFails(t *testing.T) {
+	db := testDB(t)
+	user := createUser(t, db, decimal.NewFromInt(1000))
+	price := decimal.NewFromInt(100)
+
+	// Una compra que sale bien, para que haya tenencia y asientos previos que no se tienen que tocar.
+	if _, err := newExecutor(db, nil).Buy(user.ID, "bitcoin", decimal.NewFromInt(2), price); err != nil {
+		t.Fatalf("la primera compra falló: %v", err)
+	}
+	before := snapshotTrading(t, db, user.ID)
+
+	failing := newExecutor(db, failingTransactions{tradingInfra.NewTransactionRepository(db)})
+	_, err := failing.Buy(user.ID, "bitcoin", decimal.NewFromInt(3), price)
+	if !errors.Is(err, errSaveFailed) {
+		t.Fatalf("se esperaba la falla forzada, llegó: %v", err)
+	}
+
+	if after := snapshotTrading(t, db, user.ID); after != before {
+		t.Fatalf("la operación fallida dejó cambios:\nantes:   %+v\ndespués: %+v", before, after)
+	}
+}
+
+// tradingState es lo que una operación toca en la base, para comparar antes y después.
+type tradingState struct {
+	Balance      string
+	Holding      string
+	CostBasis    string
+	Transactions int64
+	Entries      int64
+}
+
+func snapshotTrading(t *testing.T, db *gorm.DB, userID string) tradingState {
+	t.Helper()
+	var user authDomain.User
+	if err := db.First(&user, "id = ?", userID).Error; err != nil {
+		t.Fatalf("no se pudo leer el usuario: %v", err)
+	}
+	var holding tradingDomain.Holding
+	if err := db.First(&holding, "user_id = ? AND coin = ?", userID, "bitcoin").Error; err != nil {
+		t.Fatalf("no se pudo leer la tenencia: %v", err)
+	}
+	state := tradingState{Balance: user.Balance.String(), Holding: holding.Amount.String(), CostBasis: holding.CostBasis.String()}
+	db.Model(&tradingDomain.Transaction{}).Where("user_id = ?", userID).Count(&state.Transactions)
+	db.Table("ledger_entries").Count(&state.Entries)
+	return state
+}
+
+// SellUpToTx recorta la venta a lo que queda de la posición, que se lee ya bloqueada dentro de la misma transacción.
+func TestSellUpToTx(t *testing.T) {
+	tests := []struct {
+		name    string
+		held    int64 // Cantidad comprada antes de vender.
+		amount  int64 // Lo que pide la venta; cero es todo.
+		sold    int64
+		wantErr error
+	}{
+		{name: "menos de lo que hay", held: 3, amount: 2, sold: 2},
+		{name: "más de lo que hay", held: 3, amount: 5, sold: 3},
+		{name: "todo", held: 3, amount: 0, sold: 3},
+		{name: "sin posición", held: 0, amount: 1, wantErr: tradingApp.ErrNoOpenPosition},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := testDB(t)
+			executor := newExecutor(db, nil)
+			user := createUser(t, db, decimal.NewFromInt(1000))
+			price := decimal.NewFromInt(100)
+			if tt.held > 0 {
+				if _, err := executor.Buy(user.ID, "bitcoin", decimal.NewFromInt(tt.held), price); err != nil {
+					t.Fatalf("no se pudo comprar: %v", err)
+				}
+			}
+
+			// El precio de ejecución se pide para la cantidad que finalmente se vende.
+			var priced decimal.Decimal
+			fill := func(amount decimal.Decimal) decimal.Decimal {
+				priced = amount
+				return price
+			}
+			var result *tradingApp.TradeResult
+			err := db.Transaction(func(tx *gorm.DB) error {
+				var err error
+				result, err = executor.SellUpToTx(tx, user.ID, "bitcoin", tradingDomain.LiquidityTaker, decimal.NewFromInt(tt.amount), fill)
+				return err
+			})
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("error = %v, se esperaba %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("SellUpToTx: %v", err)
+			}
+			if !result.Transaction.Amount.Equal(decimal.NewFromInt(tt.sold)) || !priced.Equal(decimal.NewFromInt(tt.sold)) {
+				t.Fatalf("se vendieron %s (precio pedido para %s), se esperaban %d", result.Transaction.Amount, priced, tt.sold)
+			}
+			if left := result.User.CryptoHoldings["bitcoin"]; !left.Equal(decimal.NewFromInt(tt.held - tt.sold)) {
+				t.Fatalf("quedaron %s, se esperaban %d", left, tt.held-tt.sold)
+			}
+		})
+	}
+}
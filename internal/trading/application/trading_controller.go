@@ -2,32 +2,43 @@ package application
 
 import (
 	authDomain "cryptoproject/internal/auth/domain"
-	marketInfra "cryptoproject/internal/market/infrastructure"
+	marketDomain "cryptoproject/internal/market/domain"
 	tradingDomain "cryptoproject/internal/trading/domain"
+	"cryptoproject/pkg/logger"
+	"errors"
 	"net/http"
-	"strconv"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
 )
 
 // TradingController maneja operaciones simuladas de trading.
 type TradingController struct {
 	transactionRepo tradingDomain.TransactionRepository
+	holdingRepo     tradingDomain.HoldingRepository
 	userRepo        authDomain.UserRepository
-	coingecko       marketInfra.CoingeckoServiceInterface
+	market          marketDomain.PriceProvider
+	executor        *TradeExecutor
+	slippage        tradingDomain.SlippageModel
 }
 
 // NewTradingController crea una nueva instancia de TradingController.
 func NewTradingController(
 	transactionRepo tradingDomain.TransactionRepository,
+	holdingRepo tradingDomain.HoldingRepository,
 	userRepo authDomain.UserRepository,
-	coingecko marketInfra.CoingeckoServiceInterface,
+	market marketDomain.PriceProvider,
+	executor *TradeExecutor,
+	slippage tradingDomain.SlippageModel,
 ) *TradingController {
 	return &TradingController{
 		transactionRepo: transactionRepo,
+		holdingRepo:     holdingRepo,
 		userRepo:        userRepo,
-		coingecko:       coingecko,
+		market:          market,
+		executor:        executor,
+		slippage:        slippage,
 	}
 }
 
@@ -38,77 +49,206 @@ func (tc *TradingController) HandleBuy(c *gin.Context) {
 	amountStr := c.PostForm("amount")
 
 	// Validar la cantidad
-	amount, err := strconv.ParseFloat(amountStr, 64)
-	if err != nil || amount <= 0 {
+	amount, ok := parseAmount(amountStr)
+	if !ok {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "La cantidad ingresada es inválida"})
 		return
 	}
+	if err := tradingDomain.CheckPrecision(coin, amount); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Precio actual más el deslizamiento que corresponde al tamaño de la orden
+	fill, ok := tc.marketFill(c, coin, tradingDomain.SideBuy, amount)
+	if !ok {
+		return
+	}
 
-	// Obtener el precio actual de la criptomoneda
-	price, err := tc.coingecko.GetCurrentPrice(coin, "usd")
+	result, err := tc.executor.Buy(userID, coin, amount, fill.Price)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "No se pudo obtener el precio actual"})
+		respondTradeError(c, err)
 		return
 	}
 
-	// Calcular el costo total de la transacción
-	totalCost := price * amount
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Compra realizada con éxito",
+		"user": gin.H{
+			"balance":        result.User.Balance,
+			"crypto_balance": result.User.CryptoHoldings,
+		},
+		"transaction":  result.Transaction,
+		"market_price": fill.MarketPrice,
+		"slippage_pct": fill.SlippagePct(),
+		"cost":         result.Total,
+		"fee":          result.Fee,
+		"fee_asset":    result.Transaction.FeeAsset,
+		"total_paid":   result.Net,
+	})
+}
 
-	// Recuperar el usuario
-	user, err := tc.userRepo.FindByID(userID)
-	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Usuario no encontrado"})
+// HandleSell maneja la venta simulada de criptomonedas y calcula el P&L realizado.
+func (tc *TradingController) HandleSell(c *gin.Context) {
+	userID := c.GetString("user_id") // Recuperar ID del usuario desde el contexto JWT
+	coin := c.PostForm("coin")
+	amountStr := c.PostForm("amount")
+
+	// Validar la cantidad
+	amount, ok := parseAmount(amountStr)
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "La cantidad ingresada es inválida"})
+		return
+	}
+	if err := tradingDomain.CheckPrecision(coin, amount); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
-	// Verificar si el usuario tiene saldo suficiente
-	if !user.IsBalanceSufficient(totalCost) {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Saldo insuficiente"})
+	// Precio actual menos el deslizamiento que corresponde al tamaño de la orden
+	fill, ok := tc.marketFill(c, coin, tradingDomain.SideSell, amount)
+	if !ok {
 		return
 	}
 
-	// Actualizar saldo del usuario
-	err = user.AdjustBalance(-totalCost)
+	result, err := tc.executor.Sell(userID, coin, amount, fill.Price)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Error ajustando el saldo"})
+		respondTradeError(c, err)
 		return
 	}
-	if err := tc.userRepo.Update(user); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error al actualizar el usuario"})
-		return
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Venta realizada con éxito",
+		"user": gin.H{
+			"balance":        result.User.Balance,
+			"crypto_balance": result.User.CryptoHoldings,
+		},
+		"transaction":  result.Transaction,
+		"market_price": fill.MarketPrice,
+		"slippage_pct": fill.SlippagePct(),
+		"proceeds":     result.Total,
+		"fee":          result.Fee,
+		"fee_asset":    result.Transaction.FeeAsset,
+		"net_proceeds": result.Net,
+		"cost_basis":   result.CostBasis,
+		"realized_pnl": result.RealizedPnL,
+	})
+}
+
+// marketFill obtiene el precio de ejecución de una orden a mercado y aplica la tolerancia de deslizamiento
+// que el usuario haya enviado en max_slippage (porcentaje). Si algo falla ya deja escrita la respuesta.
+func (tc *TradingController) marketFill(c *gin.Context, coin, side string, amount decimal.Decimal) (*marketFill, bool) {
+	maxSlippage, ok := parseMaxSlippage(c.PostForm("max_slippage"))
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "La tolerancia de deslizamiento es inválida"})
+		return nil, false
+	}
+
+	fill, err := fetchFillPrice(c.Request.Context(), tc.market, tc.slippage, coin, side, amount)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "No se pudo obtener el precio actual"})
+		return nil, false
 	}
+	return fill, withinTolerance(c, fill, maxSlippage)
+}
 
-	// Convertir el userID a uuid.UUID
-	userUUID, err := uuid.Parse(user.ID)
+// swapFills obtiene el precio de ejecución de las dos patas de un swap: la venta de amount de fromCoin y la compra
+// de toCoin con lo que da esa venta. Cada pata tiene su deslizamiento y las dos tienen que estar dentro de max_slippage.
+// Si algo falla ya deja escrita la respuesta.
+func (tc *TradingController) swapFills(c *gin.Context, fromCoin, toCoin string, amount decimal.Decimal) (*marketFill, *marketFill, bool) {
+	maxSlippage, ok := parseMaxSlippage(c.PostForm("max_slippage"))
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "La tolerancia de deslizamiento es inválida"})
+		return nil, nil, false
+	}
+
+	sellFill, err := fetchFillPrice(c.Request.Context(), tc.market, tc.slippage, fromCoin, tradingDomain.SideSell, amount)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "ID de usuario inválido"})
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "No se pudo obtener el precio actual"})
+		return nil, nil, false
+	}
+	proceeds := tradingDomain.SellProceeds(sellFill.Price, amount)
+	buyFill, err := fetchFillPriceForNotional(c.Request.Context(), tc.market, tc.slippage, toCoin, tradingDomain.SideBuy, proceeds)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "No se pudo obtener el precio actual"})
+		return nil, nil, false
+	}
+	if !withinTolerance(c, sellFill, maxSlippage) || !withinTolerance(c, buyFill, maxSlippage) {
+		return nil, nil, false
+	}
+	return sellFill, buyFill, true
+}
+
+// withinTolerance revisa el deslizamiento estimado contra la tolerancia del usuario; si la supera responde 400.
+func withinTolerance(c *gin.Context, fill *marketFill, maxSlippage *decimal.Decimal) bool {
+	if err := tradingDomain.CheckSlippageTolerance(fill.Slippage, maxSlippage); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":        "El deslizamiento estimado supera la tolerancia indicada",
+			"slippage_pct": fill.SlippagePct(),
+		})
+		return false
+	}
+	return true
+}
+
+// HandleSwap cambia una criptomoneda por otra sin pasar a mano por USD.
+// Las dos patas se valoran en USD con el precio actual, cada una con su deslizamiento como una orden a mercado,
+// y se registran juntas como un swap.
+func (tc *TradingController) HandleSwap(c *gin.Context) {
+	userID := c.GetString("user_id") // Recuperar ID del usuario desde el contexto JWT
+	fromCoin := c.PostForm("from_coin")
+	toCoin := c.PostForm("to_coin")
+
+	if err := tradingDomain.ValidateSwap(fromCoin, toCoin); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
-	// Actualizar balance de criptomonedas
-	if err := user.AdjustCryptoBalance(coin, amount); err != nil {
+	// amount es la cantidad de la moneda de origen que se entrega
+	amount, ok := parseAmount(c.PostForm("amount"))
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "La cantidad ingresada es inválida"})
+		return
+	}
+	if err := tradingDomain.CheckPrecision(fromCoin, amount); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
-	// Registrar la transacción
-	transaction := tradingDomain.NewTransaction(userUUID, coin, amount, price)
-	if err := tc.transactionRepo.Save(transaction); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error al registrar la transacción"})
+	sellFill, buyFill, ok := tc.swapFills(c, fromCoin, toCoin, amount)
+	if !ok {
+		return
+	}
+
+	result, err := tc.executor.Swap(userID, fromCoin, toCoin, amount, sellFill.Price, buyFill.Price)
+	if err != nil {
+		respondTradeError(c, err)
 		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{
-		"message": "Compra realizada con éxito",
+		"message": "Swap realizado con éxito",
 		"user": gin.H{
-			"balance":        user.Balance,
-			"crypto_balance": user.CryptoHoldings,
+			"balance":        result.User.Balance,
+			"crypto_balance": result.User.CryptoHoldings,
 		},
-		"transaction": transaction,
+		"swap_id":           result.SwapID,
+		"sell":              result.Sell,
+		"buy":               result.Buy,
+		"sell_market_price": sellFill.MarketPrice,
+		"sell_slippage_pct": sellFill.SlippagePct(),
+		"buy_market_price":  buyFill.MarketPrice,
+		"buy_slippage_pct":  buyFill.SlippagePct(),
+		"proceeds":          result.Proceeds,
+		"fee":               result.Fee,
+		"fee_asset":         result.Sell.FeeAsset,
+		"cost":              result.Cost,
+		"remainder":         result.Remainder,
+		"realized_pnl":      result.RealizedPnL,
 	})
 }
 
-// HandleTransactionHistory devuelve el historial de transacciones de un usuario.
+// HandleTransactionHistory devuelve el historial de transacciones de un usuario paginado por cursor.
+// Las dos patas de cada swap se devuelven juntas como una sola entrada.
 func (tc *TradingController) HandleTransactionHistory(c *gin.Context) {
 	userID := c.GetString("user_id") // ID del usuario desde el contexto JWT
 
@@ -118,13 +258,31 @@ func (tc *TradingController) HandleTransactionHistory(c *gin.Context) {
 		return
 	}
 
-	transactions, err := tc.transactionRepo.FindByUserID(userUUID)
+	query, err := parseTransactionQuery(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	rows, err := tc.transactionRepo.FindPage(userUUID, query)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error al obtener el historial de transacciones"})
+		return
+	}
+	page := tradingDomain.NewTransactionPage(query, rows)
+
+	// Si una pata de un swap quedó fuera de la página buscamos su pareja para mostrar el swap completo.
+	partners, err := tc.transactionRepo.FindBySwapIDs(userUUID, swapIDs(page.Transactions))
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error al obtener el historial de transacciones"})
 		return
 	}
 
-	c.JSON(http.StatusOK, transactions)
+	c.JSON(http.StatusOK, gin.H{
+		"transactions": tradingDomain.BuildHistoryPage(query, page.Transactions, partners),
+		"next_cursor":  encodeCursor(page.Next),
+		"prev_cursor":  encodeCursor(page.Prev),
+	})
 }
 
 // HandleBalance devuelve el balance actual del usuario.
@@ -137,21 +295,35 @@ func (tc *TradingController) HandleBalance(c *gin.Context) {
 		return
 	}
 
-	// Reconstruir balances de criptomonedas a partir de transacciones
-	transactions, err := tc.transactionRepo.FindByUserID(uuid.MustParse(userID))
+	// Las tenencias salen de la tabla holdings, que se actualiza junto con cada operación
+	holdings, err := tc.holdingRepo.FindByUserID(uuid.MustParse(userID))
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error al obtener el historial de transacciones"})
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error al obtener las tenencias"})
 		return
 	}
-
-	cryptoBalances := make(map[string]float64)
-	for _, tx := range transactions {
-		cryptoBalances[tx.Coin] += tx.Amount
-	}
-	user.CryptoHoldings = cryptoBalances
+	user.CryptoHoldings = tradingDomain.HoldingsToMap(holdings)
 
 	c.JSON(http.StatusOK, gin.H{
 		"usd_balance":     user.Balance,
 		"crypto_holdings": user.CryptoHoldings,
 	})
 }
+
+// respondTradeError traduce los errores del ejecutor a respuestas HTTP.
+func respondTradeError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, ErrUserNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Usuario no encontrado"})
+	case errors.Is(err, ErrInsufficientBalance):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Saldo insuficiente"})
+	case errors.Is(err, ErrInsufficientHoldings):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Cantidad insuficiente de la criptomoneda"})
+	case errors.Is(err, ErrFeeExceedsProceeds):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Lo recibido por la venta no cubre la comisión"})
+	case errors.Is(err, ErrSwapTooSmall):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "El monto no alcanza para comprar la moneda destino"})
+	default:
+		logger.Error("Error ejecutando la operación:", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "No se pudo completar la operación"})
+	}
+}
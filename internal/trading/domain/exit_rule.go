@@ -0,0 +1,150 @@
+package domain
+
+import (
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+)
+
+// Tipos de reglas de salida.
+const (
+	ExitRuleStopLoss   = "stop_loss"   // Vende si el precio cae hasta el disparador.
+	ExitRuleTakeProfit = "take_profit" // Vende si el precio sube hasta el disparador.
+)
+
+// Estados posibles de una regla de salida.
+const (
+	ExitRuleStatusActive    = "active"
+	ExitRuleStatusTriggered = "triggered"
+	ExitRuleStatusFailed    = "failed" // Se disparó pero la venta no se pudo ejecutar.
+	ExitRuleStatusCancelled = "cancelled"
+)
+
+// ExitRule es una salida protectora (stop-loss o take-profit) sobre la posición de un usuario en una moneda.
+type ExitRule struct {
+	ID            uuid.UUID       `gorm:"type:uuid;primaryKey"`
+	UserID        uuid.UUID       `gorm:"type:uuid;not null;index"`
+	Coin          string          `gorm:"type:text;not null"`
+	Type          string          `gorm:"type:varchar(20);not null"`
+	TriggerPrice  decimal.Decimal `gorm:"type:numeric;not null"`
+	Amount        decimal.Decimal `gorm:"type:numeric;not null;default:0"` // 0 significa vender toda la posición.
+	Status        string          `gorm:"type:varchar(20);not null;index"`
+	StatusReason  string          `gorm:"type:text"`
+	TransactionID *uuid.UUID      `gorm:"type:uuid"` // Venta generada al dispararse.
+	TriggeredAt   *time.Time
+	CreatedAt     time.Time `gorm:"autoCreateTime"`
+	UpdatedAt     time.Time `gorm:"autoUpdateTime"`
+}
+
+// ExitRuleEvent guarda cada cambio de estado de una regla para poder auditarla después.
+type ExitRuleEvent struct {
+	ID            uuid.UUID       `gorm:"type:uuid;primaryKey"`
+	RuleID        uuid.UUID       `gorm:"type:uuid;not null;index"`
+	Status        string          `gorm:"type:varchar(20);not null"`
+	Price         decimal.Decimal `gorm:"type:numeric"` // Precio de mercado que provocó el cambio, si aplica.
+	Reason        string          `gorm:"type:text"`
+	TransactionID *uuid.UUID      `gorm:"type:uuid"`
+	CreatedAt     time.Time       `gorm:"autoCreateTime"`
+}
+
+// NewExitRule valida los datos y crea una regla activa junto con su primer evento.
+func NewExitRule(userID uuid.UUID, coin, ruleType string, triggerPrice, amount decimal.Decimal) (*ExitRule, *ExitRuleEvent, error) {
+	if coin == "" {
+		return nil, nil, errors.New("la criptomoneda es obligatoria")
+	}
+	if ruleType != ExitRuleStopLoss && ruleType != ExitRuleTakeProfit {
+		return nil, nil, errors.New("el tipo debe ser stop_loss o take_profit")
+	}
+	if !triggerPrice.IsPositive() {
+		return nil, nil, errors.New("el precio disparador debe ser positivo")
+	}
+	if amount.IsNegative() {
+		return nil, nil, errors.New("la cantidad no puede ser negativa")
+	}
+	if err := CheckPrecision(coin, amount); err != nil {
+		return nil, nil, err
+	}
+
+	rule := &ExitRule{
+		ID:           uuid.New(),
+		UserID:       userID,
+		Coin:         coin,
+		Type:         ruleType,
+		TriggerPrice: triggerPrice,
+		Amount:       amount,
+	}
+	event := rule.transition(ExitRuleStatusActive, "regla creada", decimal.Zero, nil)
+	return rule, event, nil
+}
+
+// IsTriggeredBy indica si el precio de mercado dispara la regla.
+func (r *ExitRule) IsTriggeredBy(marketPrice decimal.Decimal) bool {
+	if r.Type == ExitRuleStopLoss {
+		return marketPrice.LessThanOrEqual(r.TriggerPrice)
+	}
+	return marketPrice.GreaterThanOrEqual(r.TriggerPrice)
+}
+
+// MarkTriggered marca la regla como ejecutada por la venta dada.
+func (r *ExitRule) MarkTriggered(marketPrice decimal.Decimal, transactionID uuid.UUID) *ExitRuleEvent {
+	now := time.Now()
+	r.TriggeredAt = &now
+	r.TransactionID = &transactionID
+	return r.transition(ExitRuleStatusTriggered, "venta a mercado ejecutada", marketPrice, &transactionID)
+}
+
+// MarkFailed marca la regla como fallida: se disparó pero no se pudo vender.
+func (r *ExitRule) MarkFailed(marketPrice decimal.Decimal, reason string) *ExitRuleEvent {
+	now := time.Now()
+	r.TriggeredAt = &now
+	return r.transition(ExitRuleStatusFailed, reason, marketPrice, nil)
+}
+
+// ErrExitRuleNotActive se devuelve al cancelar una regla que ya se disparó, falló o se canceló.
+var ErrExitRuleNotActive = errors.New("solo se pueden cancelar reglas activas")
+
+// Cancel cancela la regla si todavía está activa.
+func (r *ExitRule) Cancel() (*ExitRuleEvent, error) {
+	if r.Status != ExitRuleStatusActive {
+		return nil, ErrExitRuleNotActive
+	}
+	return r.transition(ExitRuleStatusCancelled, "cancelada por el usuario", decimal.Zero, nil), nil
+}
+
+// transition cambia el estado y devuelve el evento que lo documenta.
+func (r *ExitRule) transition(status, reason string, price decimal.Decimal, transactionID *uuid.UUID) *ExitRuleEvent {
+	r.Status = status
+	r.StatusReason = reason
+	return &ExitRuleEvent{
+		ID:            uuid.New(),
+		RuleID:        r.ID,
+		Status:        status,
+		Price:         price,
+		Reason:        reason,
+		TransactionID: transactionID,
+		CreatedAt:     time.Now(),
+	}
+}
+
+// BeforeCreate es un hook de GORM que genera el ID si no viene.
+func (r *ExitRule) BeforeCreate(tx *gorm.DB) (err error) {
+	if r.ID == uuid.Nil {
+		r.ID = uuid.New()
+	}
+	return
+}
+
+// ExitRuleRepository define las operaciones para trabajar con reglas de salida y su historial.
+type ExitRuleRepository interface {
+	Save(rule *ExitRule, event *ExitRuleEvent) error
+	Update(rule *ExitRule, event *ExitRuleEvent) error
+	FindByID(id uuid.UUID) (*ExitRule, error)
+	FindByIDForUpdate(id uuid.UUID) (*ExitRule, error)
+	FindByUserID(userID uuid.UUID, status string) ([]ExitRule, error)
+	FindActive() ([]ExitRule, error)
+	FindEvents(ruleID uuid.UUID) ([]ExitRuleEvent, error)
+	WithTx(tx *gorm.DB) ExitRuleRepository
+}
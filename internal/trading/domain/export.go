@@ -0,0 +1,106 @@
+package domain
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+)
+
+// ExportColumns son las columnas del CSV de exportación, en el mismo orden que ExportRow.CSV.
+var ExportColumns = []string{
+	"transaction_id", "timestamp", "coin", "side", "amount", "price",
+	"total_usd", "fee", "fee_asset", "net_usd", "liquidity", "swap_id",
+	"holding_after", "cost_basis_after", "realized_pnl",
+}
+
+// ExportRow es una transacción con los campos calculados que piden las planillas y los reportes de impuestos.
+type ExportRow struct {
+	TransactionID  uuid.UUID       `json:"transaction_id"`
+	Timestamp      time.Time       `json:"timestamp"`
+	Coin           string          `json:"coin"`
+	Side           string          `json:"side"`
+	Amount         decimal.Decimal `json:"amount"`
+	Price          decimal.Decimal `json:"price"`
+	TotalUSD       decimal.Decimal `json:"total_usd"` // Lo cobrado o acreditado por price * amount, sin la comisión.
+	Fee            decimal.Decimal `json:"fee"`
+	FeeAsset       string          `json:"fee_asset"`
+	NetUSD         decimal.Decimal `json:"net_usd"` // Compra: total + comisión. Venta: total - comisión.
+	Liquidity      string          `json:"liquidity,omitempty"`
+	SwapID         *uuid.UUID      `json:"swap_id,omitempty"`
+	HoldingAfter   decimal.Decimal `json:"holding_after"`    // Tenencia de la moneda después de la transacción.
+	CostBasisAfter decimal.Decimal `json:"cost_basis_after"` // Costo base de esa tenencia, a costo promedio.
+	RealizedPnL    decimal.Decimal `json:"realized_pnl"`     // Solo ventas.
+}
+
+// CSV devuelve la fila como columnas de texto, en el orden de ExportColumns.
+func (r ExportRow) CSV() []string {
+	swapID := ""
+	if r.SwapID != nil {
+		swapID = r.SwapID.String()
+	}
+	return []string{
+		r.TransactionID.String(),
+		r.Timestamp.UTC().Format(time.RFC3339),
+		r.Coin,
+		r.Side,
+		r.Amount.String(),
+		r.Price.String(),
+		r.TotalUSD.StringFixed(AssetPlaces(QuoteAsset)),
+		r.Fee.StringFixed(AssetPlaces(QuoteAsset)),
+		r.FeeAsset,
+		r.NetUSD.StringFixed(AssetPlaces(QuoteAsset)),
+		r.Liquidity,
+		swapID,
+		r.HoldingAfter.String(),
+		r.CostBasisAfter.String(),
+		r.RealizedPnL.String(),
+	}
+}
+
+// HistoryExporter arma las filas de exportación una por una, llevando las posiciones corridas.
+// Las transacciones tienen que llegar ordenadas por fecha, como las entrega StreamByUserID.
+type HistoryExporter struct {
+	positions map[string]*Position
+}
+
+// NewHistoryExporter crea un exportador sin posiciones abiertas.
+func NewHistoryExporter() *HistoryExporter {
+	return &HistoryExporter{positions: make(map[string]*Position)}
+}
+
+// Row aplica la transacción a su posición y devuelve la fila con la tenencia resultante.
+func (e *HistoryExporter) Row(tx Transaction) ExportRow {
+	position, exists := e.positions[tx.Coin]
+	if !exists {
+		position = &Position{Coin: tx.Coin}
+		e.positions[tx.Coin] = position
+	}
+	// Igual que en BuildPositions, una venta inconsistente deja la posición como está.
+	realized, _ := position.Apply(tx)
+
+	total := BuyCost(tx.Price, tx.Amount)
+	net := total.Add(tx.Fee)
+	if tx.Side == SideSell {
+		total = SellProceeds(tx.Price, tx.Amount)
+		net = total.Sub(tx.Fee)
+	}
+
+	return ExportRow{
+		TransactionID:  tx.ID,
+		Timestamp:      tx.Timestamp,
+		Coin:           tx.Coin,
+		Side:           tx.Side,
+		Amount:         tx.Amount,
+		Price:          tx.Price,
+		TotalUSD:       total,
+		Fee:            tx.Fee,
+		FeeAsset:       tx.FeeAsset,
+		NetUSD:         net,
+		Liquidity:      tx.Liquidity,
+		SwapID:         tx.SwapID,
+		HoldingAfter:   position.Amount,
+		CostBasisAfter: position.CostBasis,
+		RealizedPnL:    realized,
+	}
+}
@@ -0,0 +1,95 @@
+package domain
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/shopspring/decimal"
+)
+
+// Liquidez de una operación. Las órdenes límite que esperan en el libro aportan liquidez (maker);
+// las de mercado, las IOC y las que disparan las reglas de salida la toman (taker).
+const (
+	LiquidityMaker = "maker"
+	LiquidityTaker = "taker"
+)
+
+// FeeWindowDays es la ventana de volumen con la que se elige el tramo de comisiones.
+const FeeWindowDays = 30
+
+// FeeTier es un tramo de comisiones. Aplica desde MinVolume USD operados en la ventana.
+// Las tasas son porcentajes: 0.1 significa 0.1% del monto operado.
+type FeeTier struct {
+	MinVolume decimal.Decimal
+	MakerRate decimal.Decimal
+	TakerRate decimal.Decimal
+}
+
+// FeeSchedule es la tabla de comisiones: un porcentaje según tramo de volumen más un monto fijo por operación.
+type FeeSchedule struct {
+	Tiers []FeeTier // Ordenados por MinVolume ascendente.
+	Flat  decimal.Decimal
+}
+
+// ParseFeeSchedule arma la tabla desde la configuración. tiers tiene la forma
+// "volumen_minimo:maker%:taker%,..." (ej. "0:0.10:0.20,50000:0.08:0.15") y flat es el monto fijo en USD.
+func ParseFeeSchedule(tiers, flat string) (FeeSchedule, error) {
+	var schedule FeeSchedule
+
+	flatFee, err := decimal.NewFromString(strings.TrimSpace(flat))
+	if err != nil || flatFee.IsNegative() {
+		return schedule, fmt.Errorf("comisión fija inválida: %q", flat)
+	}
+	if err := CheckPrecision(QuoteAsset, flatFee); err != nil {
+		return schedule, err
+	}
+	schedule.Flat = flatFee
+
+	for _, raw := range strings.Split(tiers, ",") {
+		raw = strings.TrimSpace(raw)
+		if raw == "" {
+			continue
+		}
+		parts := strings.Split(raw, ":")
+		if len(parts) != 3 {
+			return schedule, fmt.Errorf("tramo de comisiones inválido: %q", raw)
+		}
+		values := make([]decimal.Decimal, 3)
+		for i, part := range parts {
+			values[i], err = decimal.NewFromString(strings.TrimSpace(part))
+			if err != nil || values[i].IsNegative() {
+				return schedule, fmt.Errorf("tramo de comisiones inválido: %q", raw)
+			}
+		}
+		schedule.Tiers = append(schedule.Tiers, FeeTier{MinVolume: values[0], MakerRate: values[1], TakerRate: values[2]})
+	}
+
+	sort.Slice(schedule.Tiers, func(i, j int) bool {
+		return schedule.Tiers[i].MinVolume.LessThan(schedule.Tiers[j].MinVolume)
+	})
+	return schedule, nil
+}
+
+// TierFor devuelve el tramo que corresponde al volumen dado. Si no hay tramos, todo es cero.
+func (s FeeSchedule) TierFor(volume decimal.Decimal) FeeTier {
+	tier := FeeTier{MinVolume: decimal.Zero, MakerRate: decimal.Zero, TakerRate: decimal.Zero}
+	for _, candidate := range s.Tiers {
+		if volume.LessThan(candidate.MinVolume) {
+			break
+		}
+		tier = candidate
+	}
+	return tier
+}
+
+// Fee calcula la comisión en USD de una operación de notional USD, redondeada hacia arriba a centavos.
+func (s FeeSchedule) Fee(notional, volume decimal.Decimal, liquidity string) decimal.Decimal {
+	tier := s.TierFor(volume)
+	rate := tier.TakerRate
+	if liquidity == LiquidityMaker {
+		rate = tier.MakerRate
+	}
+	fee := notional.Mul(rate).Div(decimal.NewFromInt(100)).Add(s.Flat)
+	return fee.RoundCeil(AssetPlaces(QuoteAsset))
+}
@@ -0,0 +1,67 @@
+package domain
+
+import (
+	"testing"
+
+	"github.com/shopspring/decimal"
+)
+
+func TestFeeSchedule(t *testing.T) {
+	// Los tramos vienen desordenados a propósito: ParseFeeSchedule los ordena por volumen.
+	schedule, err := ParseFeeSchedule("50000:0.08:0.15, 0:0.10:0.20, 1000000:0:0.05", "0.25")
+	if err != nil {
+		t.Fatalf("ParseFeeSchedule: %v", err)
+	}
+	tests := []struct {
+		name      string
+		volume    string
+		notional  string
+		liquidity string
+		fee       string
+	}{
+		{name: "sin volumen, taker", volume: "0", notional: "1000", liquidity: LiquidityTaker, fee: "2.25"},
+		{name: "sin volumen, maker", volume: "0", notional: "1000", liquidity: LiquidityMaker, fee: "1.25"},
+		{name: "justo antes del segundo tramo", volume: "49999.99", notional: "1000", liquidity: LiquidityTaker, fee: "2.25"},
+		{name: "justo en el segundo tramo", volume: "50000", notional: "1000", liquidity: LiquidityTaker, fee: "1.75"},
+		{name: "último tramo, maker sin porcentaje", volume: "2000000", notional: "1000", liquidity: LiquidityMaker, fee: "0.25"},
+		{name: "redondea hacia arriba a centavos", volume: "0", notional: "33.33", liquidity: LiquidityTaker, fee: "0.32"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fee := schedule.Fee(decimal.RequireFromString(tt.notional), decimal.RequireFromString(tt.volume), tt.liquidity)
+			if !fee.Equal(decimal.RequireFromString(tt.fee)) {
+				t.Fatalf("comisión = %s, se esperaba %s", fee, tt.fee)
+			}
+		})
+	}
+}
+
+func TestParseFeeSchedule(t *testing.T) {
+	tests := []struct {
+		name  string
+		tiers string
+		flat  string
+		ok    bool
+	}{
+		{name: "sin tramos", tiers: "", flat: "0", ok: true},
+		{name: "un tramo", tiers: "0:0.1:0.2", flat: "0", ok: true},
+		{name: "faltan tasas", tiers: "0:0.1", flat: "0"},
+		{name: "tasa negativa", tiers: "0:-0.1:0.2", flat: "0"},
+		{name: "tasa no numérica", tiers: "0:abc:0.2", flat: "0"},
+		{name: "comisión fija negativa", tiers: "", flat: "-1"},
+		{name: "comisión fija con fracción de centavo", tiers: "", flat: "0.001"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := ParseFeeSchedule(tt.tiers, tt.flat); (err == nil) != tt.ok {
+				t.Fatalf("ParseFeeSchedule(%q, %q) = %v, se esperaba ok = %v", tt.tiers, tt.flat, err, tt.ok)
+			}
+		})
+	}
+
+	// Sin tramos no se cobra porcentaje, solo el fijo.
+	schedule, _ := ParseFeeSchedule("", "0.5")
+	if fee := schedule.Fee(decimal.NewFromInt(1000), decimal.Zero, LiquidityTaker); !fee.Equal(decimal.RequireFromString("0.5")) {
+		t.Fatalf("comisión sin tramos = %s, se esperaba 0.5", fee)
+	}
+}
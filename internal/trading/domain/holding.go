@@ -0,0 +1,65 @@
+package domain
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+)
+
+// Holding es la tenencia persistida de un usuario en una moneda.
+// Se actualiza en la misma transacción de base de datos que cada operación, así que siempre
+// debería coincidir con lo que se reconstruye desde el historial (ver HoldingsReconciler).
+type Holding struct {
+	UserID    uuid.UUID       `gorm:"type:uuid;primaryKey"`
+	Coin      string          `gorm:"type:text;primaryKey"`
+	Amount    decimal.Decimal `gorm:"type:numeric;not null;default:0"`
+	CostBasis decimal.Decimal `gorm:"type:numeric;not null;default:0"` // Costo total en USD de las unidades abiertas.
+	UpdatedAt time.Time       `gorm:"autoUpdateTime"`
+}
+
+// AverageCost devuelve el precio medio de entrada de la tenencia.
+func (h *Holding) AverageCost() decimal.Decimal {
+	return h.position().AverageCost()
+}
+
+// CostBasisFor devuelve la parte proporcional del costo base que corresponde a amount unidades.
+func (h *Holding) CostBasisFor(amount decimal.Decimal) decimal.Decimal {
+	return h.position().CostBasisFor(amount)
+}
+
+// Apply suma una transacción a la tenencia y devuelve el P&L realizado, con las mismas reglas que Position.
+func (h *Holding) Apply(tx Transaction) (decimal.Decimal, error) {
+	position := h.position()
+	realizedPnL, err := position.Apply(tx)
+	if err != nil {
+		return decimal.Zero, err
+	}
+	h.Amount = position.Amount
+	h.CostBasis = position.CostBasis
+	return realizedPnL, nil
+}
+
+func (h *Holding) position() *Position {
+	return &Position{Coin: h.Coin, Amount: h.Amount, CostBasis: h.CostBasis}
+}
+
+// HoldingsToMap convierte las tenencias en el mapa moneda -> cantidad que usamos en las respuestas.
+func HoldingsToMap(holdings []Holding) map[string]decimal.Decimal {
+	result := make(map[string]decimal.Decimal, len(holdings))
+	for _, holding := range holdings {
+		result[holding.Coin] = holding.Amount
+	}
+	return result
+}
+
+// HoldingRepository define las operaciones para leer y actualizar las tenencias.
+type HoldingRepository interface {
+	FindByUserID(userID uuid.UUID) ([]Holding, error)
+	// FindForUpdate bloquea la tenencia hasta el final de la transacción. Si no existe devuelve una en cero.
+	FindForUpdate(userID uuid.UUID, coin string) (*Holding, error)
+	Save(holding *Holding) error
+	FindUserIDs() ([]uuid.UUID, error)
+	WithTx(tx *gorm.DB) HoldingRepository
+}
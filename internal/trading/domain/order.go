@@ -0,0 +1,165 @@
+package domain
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+)
+
+// Estados posibles de una orden límite.
+const (
+	OrderStatusOpen      = "open"
+	OrderStatusFilled    = "filled"
+	OrderStatusCancelled = "cancelled"
+	OrderStatusExpired   = "expired"
+	OrderStatusRejected  = "rejected" // Se cruzó el precio pero no había saldo o cripto suficiente.
+)
+
+// Vigencias (time-in-force) soportadas.
+const (
+	TimeInForceGTC = "GTC" // Good-til-cancelled: queda abierta hasta que se llene o se cancele.
+	TimeInForceIOC = "IOC" // Immediate-or-cancel: se evalúa una sola vez al crearla.
+	TimeInForceDay = "DAY" // Vence 24 horas después de crearse.
+)
+
+// ErrOrderNotOpen se devuelve al intentar cancelar una orden que ya no está abierta.
+var ErrOrderNotOpen = errors.New("solo se pueden cancelar órdenes abiertas")
+
+// dayOrderTTL es lo que dura abierta una orden DAY.
+const dayOrderTTL = 24 * time.Hour
+
+// Order representa una orden límite de compra o venta pendiente de ejecución.
+type Order struct {
+	ID            uuid.UUID       `gorm:"type:uuid;primaryKey"`
+	UserID        uuid.UUID       `gorm:"type:uuid;not null;index"`
+	Coin          string          `gorm:"type:text;not null"`
+	Side          string          `gorm:"type:varchar(10);not null"`
+	Amount        decimal.Decimal `gorm:"type:numeric;not null"`
+	LimitPrice    decimal.Decimal `gorm:"type:numeric;not null"`
+	TimeInForce   string          `gorm:"type:varchar(10);not null"`
+	Status        string          `gorm:"type:varchar(20);not null;index"`
+	StatusReason  string          `gorm:"type:text"`
+	TransactionID *uuid.UUID      `gorm:"type:uuid"` // Transacción generada al llenarse.
+	ExpiresAt     *time.Time
+	CreatedAt     time.Time `gorm:"autoCreateTime"`
+	UpdatedAt     time.Time `gorm:"autoUpdateTime"`
+}
+
+// NewOrder valida los datos y crea una orden abierta.
+func NewOrder(userID uuid.UUID, coin, side string, amount, limitPrice decimal.Decimal, timeInForce string) (*Order, error) {
+	if coin == "" {
+		return nil, errors.New("la criptomoneda es obligatoria")
+	}
+	if side != SideBuy && side != SideSell {
+		return nil, errors.New("el lado de la orden debe ser buy o sell")
+	}
+	if !amount.IsPositive() {
+		return nil, errors.New("la cantidad debe ser positiva")
+	}
+	if err := CheckPrecision(coin, amount); err != nil {
+		return nil, err
+	}
+	if !limitPrice.IsPositive() {
+		return nil, errors.New("el precio límite debe ser positivo")
+	}
+	if !limitPrice.Equal(limitPrice.Truncate(PricePlaces)) {
+		return nil, errors.New("el precio límite admite como máximo 8 decimales")
+	}
+
+	timeInForce = strings.ToUpper(timeInForce)
+	if timeInForce == "" {
+		timeInForce = TimeInForceGTC
+	}
+
+	now := time.Now()
+	var expiresAt *time.Time
+	switch timeInForce {
+	case TimeInForceGTC, TimeInForceIOC:
+	case TimeInForceDay:
+		expiry := now.Add(dayOrderTTL)
+		expiresAt = &expiry
+	default:
+		return nil, errors.New("time_in_force debe ser GTC, IOC o DAY")
+	}
+
+	return &Order{
+		ID:          uuid.New(),
+		UserID:      userID,
+		Coin:        coin,
+		Side:        side,
+		Amount:      amount,
+		LimitPrice:  limitPrice,
+		TimeInForce: timeInForce,
+		Status:      OrderStatusOpen,
+		ExpiresAt:   expiresAt,
+		CreatedAt:   now,
+	}, nil
+}
+
+// IsCrossedBy indica si el precio de mercado permite ejecutar la orden.
+// Compra: mercado <= límite. Venta: mercado >= límite.
+func (o *Order) IsCrossedBy(marketPrice decimal.Decimal) bool {
+	if o.Side == SideBuy {
+		return marketPrice.LessThanOrEqual(o.LimitPrice)
+	}
+	return marketPrice.GreaterThanOrEqual(o.LimitPrice)
+}
+
+// IsExpired indica si la orden ya pasó su fecha de vencimiento.
+func (o *Order) IsExpired(now time.Time) bool {
+	return o.ExpiresAt != nil && !now.Before(*o.ExpiresAt)
+}
+
+// Liquidity indica qué comisión paga la orden al llenarse. Las IOC se ejecutan en el momento, así que toman liquidez;
+// las demás quedan esperando en el libro hasta que el precio las cruza, así que la aportan.
+func (o *Order) Liquidity() string {
+	if o.TimeInForce == TimeInForceIOC {
+		return LiquidityTaker
+	}
+	return LiquidityMaker
+}
+
+// MarkFilled marca la orden como llenada por la transacción dada.
+func (o *Order) MarkFilled(transactionID uuid.UUID) {
+	o.Status = OrderStatusFilled
+	o.TransactionID = &transactionID
+	o.StatusReason = ""
+}
+
+// Close cierra la orden con un estado final y el motivo.
+func (o *Order) Close(status, reason string) {
+	o.Status = status
+	o.StatusReason = reason
+}
+
+// Cancel cancela la orden si todavía está abierta.
+func (o *Order) Cancel() error {
+	if o.Status != OrderStatusOpen {
+		return ErrOrderNotOpen
+	}
+	o.Close(OrderStatusCancelled, "cancelada por el usuario")
+	return nil
+}
+
+// BeforeCreate es un hook de GORM que genera el ID si no viene.
+func (o *Order) BeforeCreate(tx *gorm.DB) (err error) {
+	if o.ID == uuid.Nil {
+		o.ID = uuid.New()
+	}
+	return
+}
+
+// OrderRepository define las operaciones para trabajar con órdenes límite.
+type OrderRepository interface {
+	Save(order *Order) error
+	Update(order *Order) error
+	FindByID(id uuid.UUID) (*Order, error)
+	FindByIDForUpdate(id uuid.UUID) (*Order, error) // Bloquea la fila hasta el final de la transacción.
+	FindByUserID(userID uuid.UUID, status string) ([]Order, error)
+	FindOpen() ([]Order, error)
+	WithTx(tx *gorm.DB) OrderRepository
+}
@@ -0,0 +1,88 @@
+package domain
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/shopspring/decimal"
+)
+
+// Position resume lo que un usuario tiene de una moneda, reconstruido desde su historial.
+// Usamos costo promedio: cada venta saca unidades al precio medio de lo que queda abierto.
+type Position struct {
+	Coin      string
+	Amount    decimal.Decimal // Unidades abiertas.
+	CostBasis decimal.Decimal // Costo total en USD de las unidades abiertas.
+}
+
+// AverageCost devuelve el precio medio de entrada de la posición.
+func (p *Position) AverageCost() decimal.Decimal {
+	if !p.Amount.IsPositive() {
+		return decimal.Zero
+	}
+	return p.CostBasis.Div(p.Amount).Round(PricePlaces)
+}
+
+// CostBasisFor devuelve la parte proporcional del costo base que corresponde a amount unidades.
+func (p *Position) CostBasisFor(amount decimal.Decimal) decimal.Decimal {
+	if !p.Amount.IsPositive() {
+		return decimal.Zero
+	}
+	if amount.Equal(p.Amount) {
+		return p.CostBasis // Cerrar la posición se lleva todo el costo, sin residuos de redondeo.
+	}
+	return p.CostBasis.Mul(amount).Div(p.Amount).Round(CostBasisPlaces)
+}
+
+// Apply suma una transacción a la posición y devuelve el P&L realizado (solo las ventas realizan).
+// La comisión de una compra se suma al costo base y la de una venta se descuenta de lo recibido.
+func (p *Position) Apply(tx Transaction) (decimal.Decimal, error) {
+	switch tx.Side {
+	case SideSell:
+		if tx.Amount.GreaterThan(p.Amount) {
+			return decimal.Zero, fmt.Errorf("cantidad insuficiente de %s: disponible %s, venta %s", p.Coin, p.Amount, tx.Amount)
+		}
+		costBasis := p.CostBasisFor(tx.Amount)
+		p.Amount = p.Amount.Sub(tx.Amount)
+		p.CostBasis = p.CostBasis.Sub(costBasis)
+		return SellProceeds(tx.Price, tx.Amount).Sub(tx.Fee).Sub(costBasis), nil
+	default:
+		// Las transacciones viejas no tienen lado, así que todo lo que no es venta cuenta como compra.
+		p.Amount = p.Amount.Add(tx.Amount)
+		p.CostBasis = p.CostBasis.Add(BuyCost(tx.Price, tx.Amount)).Add(tx.Fee)
+		return decimal.Zero, nil
+	}
+}
+
+// BuildPositions reconstruye las posiciones por moneda a partir del historial de transacciones.
+// Ojo: ordenamos por fecha porque el costo promedio depende del orden de las operaciones.
+func BuildPositions(transactions []Transaction) map[string]*Position {
+	sorted := make([]Transaction, len(transactions))
+	copy(sorted, transactions)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].Timestamp.Before(sorted[j].Timestamp)
+	})
+
+	positions := make(map[string]*Position)
+	for _, tx := range sorted {
+		position, exists := positions[tx.Coin]
+		if !exists {
+			position = &Position{Coin: tx.Coin}
+			positions[tx.Coin] = position
+		}
+		if _, err := position.Apply(tx); err != nil {
+			// Un historial inconsistente no debería tumbar el cálculo; dejamos la posición como está.
+			continue
+		}
+	}
+	return positions
+}
+
+// HoldingsFromPositions convierte las posiciones en el mapa moneda -> cantidad que usamos en las respuestas.
+func HoldingsFromPositions(positions map[string]*Position) map[string]decimal.Decimal {
+	holdings := make(map[string]decimal.Decimal, len(positions))
+	for coin, position := range positions {
+		holdings[coin] = position.Amount
+	}
+	return holdings
+}
@@ -0,0 +1,67 @@
+package domain
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/shopspring/decimal"
+)
+
+// Reglas de precisión y redondeo. Todo cálculo de dinero o cantidades pasa por aquí
+// para que nunca dependa de cómo redondea float64.
+
+// QuoteAsset es la moneda en la que se liquidan las operaciones.
+const QuoteAsset = "usd"
+
+const (
+	// PricePlaces son los decimales con los que guardamos precios (igual que DECIMAL(18,8)).
+	PricePlaces int32 = 8
+	// CostBasisPlaces son los decimales internos del costo base; más que USD para no perder centavos al vender en partes.
+	CostBasisPlaces int32 = 8
+	// defaultCryptoPlaces aplica a cualquier cripto que no tenga una regla propia.
+	defaultCryptoPlaces int32 = 8
+)
+
+// assetPlaces define los decimales de los activos que no usan el valor por defecto.
+var assetPlaces = map[string]int32{
+	"usd": 2, // Igual que users.balance numeric(15,2).
+}
+
+// AssetPlaces devuelve cuántos decimales admite un activo.
+func AssetPlaces(asset string) int32 {
+	if places, exists := assetPlaces[strings.ToLower(asset)]; exists {
+		return places
+	}
+	return defaultCryptoPlaces
+}
+
+// CheckPrecision falla si la cantidad tiene más decimales de los que admite el activo.
+// Preferimos rechazar antes que redondear en silencio una cantidad que pidió el usuario.
+func CheckPrecision(asset string, amount decimal.Decimal) error {
+	places := AssetPlaces(asset)
+	if !amount.Equal(amount.Truncate(places)) {
+		return fmt.Errorf("la cantidad de %s admite como máximo %d decimales", asset, places)
+	}
+	return nil
+}
+
+// PriceFromFloat convierte un precio de mercado (CoinGecko lo entrega como float64) a decimal con 8 decimales.
+func PriceFromFloat(price float64) decimal.Decimal {
+	return decimal.NewFromFloat(price).Round(PricePlaces)
+}
+
+// BuyCost calcula lo que paga el usuario por una compra: price * amount redondeado hacia arriba a centavos.
+// Redondeamos a favor de la casa para que el saldo nunca quede debiendo fracciones de centavo.
+func BuyCost(price, amount decimal.Decimal) decimal.Decimal {
+	return price.Mul(amount).RoundCeil(AssetPlaces(QuoteAsset))
+}
+
+// SellProceeds calcula lo que recibe el usuario por una venta: price * amount redondeado hacia abajo a centavos.
+func SellProceeds(price, amount decimal.Decimal) decimal.Decimal {
+	return price.Mul(amount).RoundFloor(AssetPlaces(QuoteAsset))
+}
+
+// RoundQuote redondea un monto en USD a centavos con redondeo bancario (para reportes, no para cobros).
+func RoundQuote(amount decimal.Decimal) decimal.Decimal {
+	return amount.RoundBank(AssetPlaces(QuoteAsset))
+}
@@ -0,0 +1,83 @@
+package domain
+
+import (
+	"testing"
+
+	"github.com/shopspring/decimal"
+)
+
+func TestCheckPrecision(t *testing.T) {
+	tests := []struct {
+		asset  string
+		amount string
+		ok     bool
+	}{
+		{asset: "usd", amount: "10.25", ok: true},
+		{asset: "USD", amount: "10.25", ok: true},
+		{asset: "usd", amount: "10.255", ok: false},
+		{asset: "bitcoin", amount: "0.00000001", ok: true},
+		{asset: "bitcoin", amount: "0.000000001", ok: false},
+		{asset: "monedanueva", amount: "1.12345678", ok: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.asset+"/"+tt.amount, func(t *testing.T) {
+			err := CheckPrecision(tt.asset, decimal.RequireFromString(tt.amount))
+			if (err == nil) != tt.ok {
+				t.Fatalf("CheckPrecision(%s, %s) = %v, se esperaba ok = %v", tt.asset, tt.amount, err, tt.ok)
+			}
+		})
+	}
+}
+
+func TestRounding(t *testing.T) {
+	tests := []struct {
+		name  string
+		round func() decimal.Decimal
+		want  string
+	}{
+		{name: "la compra redondea hacia arriba", round: func() decimal.Decimal {
+			return BuyCost(decimal.RequireFromString("33.33333333"), decimal.NewFromInt(3))
+		}, want: "100"},
+		{name: "la venta redondea hacia abajo", round: func() decimal.Decimal {
+			return SellProceeds(decimal.RequireFromString("33.33333333"), decimal.NewFromInt(3))
+		}, want: "99.99"},
+		{name: "compra exacta", round: func() decimal.Decimal {
+			return BuyCost(decimal.RequireFromString("0.1"), decimal.RequireFromString("0.2"))
+		}, want: "0.02"},
+		{name: "reportes con redondeo bancario hacia el par", round: func() decimal.Decimal {
+			return RoundQuote(decimal.RequireFromString("0.125"))
+		}, want: "0.12"},
+		{name: "reportes con redondeo bancario hacia arriba", round: func() decimal.Decimal {
+			return RoundQuote(decimal.RequireFromString("0.135"))
+		}, want: "0.14"},
+		{name: "precio de mercado a 8 decimales", round: func() decimal.Decimal {
+			return PriceFromFloat(0.1 + 0.2)
+		}, want: "0.3"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.round(); !got.Equal(decimal.RequireFromString(tt.want)) {
+				t.Fatalf("se obtuvo %s, se esperaba %s", got, tt.want)
+			}
+		})
+	}
+}
+
+// Vender en partes no pierde centavos del costo base: la última venta se lleva lo que quede.
+func TestPositionSellInParts(t *testing.T) {
+	position := &Position{Coin: "bitcoin"}
+	if _, err := position.Apply(Transaction{Side: SideBuy, Amount: decimal.NewFromInt(3), Price: decimal.RequireFromString("33.33333333")}); err != nil {
+		t.Fatalf("compra: %v", err)
+	}
+
+	released := decimal.Zero
+	for i := 0; i < 3; i++ {
+		released = released.Add(position.CostBasisFor(decimal.NewFromInt(1)))
+		if _, err := position.Apply(Transaction{Side: SideSell, Amount: decimal.NewFromInt(1), Price: decimal.NewFromInt(40)}); err != nil {
+			t.Fatalf("venta %d: %v", i+1, err)
+		}
+	}
+	if !released.Equal(decimal.NewFromInt(100)) || !position.CostBasis.IsZero() || !position.Amount.IsZero() {
+		t.Fatalf("costo liberado %s, queda %s de costo y %s unidades; se esperaba 100, 0 y 0", released, position.CostBasis, position.Amount)
+	}
+}
@@ -0,0 +1,125 @@
+package domain
+
+import (
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+)
+
+// Estados posibles de una cotización.
+const (
+	QuoteStatusOpen     = "open"
+	QuoteStatusExecuted = "executed"
+	QuoteStatusExpired  = "expired"
+)
+
+// Errores al ejecutar una cotización.
+var (
+	ErrQuoteExpired = errors.New("la cotización venció")
+	ErrQuoteUsed    = errors.New("la cotización ya fue ejecutada")
+)
+
+// Quote es un precio garantizado por unos segundos para una compra o venta.
+// Se guardan todas, ejecutadas o no, para poder auditar qué precio se le ofreció a cada usuario.
+type Quote struct {
+	ID            uuid.UUID       `gorm:"type:uuid;primaryKey"`
+	UserID        uuid.UUID       `gorm:"type:uuid;not null;index"`
+	Coin          string          `gorm:"type:text;not null"`
+	Side          string          `gorm:"type:varchar(10);not null"`
+	Amount        decimal.Decimal `gorm:"type:numeric;not null"`
+	Price         decimal.Decimal `gorm:"type:numeric;not null"` // Precio garantizado, con el deslizamiento incluido.
+	MarketPrice   decimal.Decimal `gorm:"type:numeric"`          // Precio de mercado al cotizar.
+	Total         decimal.Decimal `gorm:"type:numeric;not null"` // price * amount en USD, sin la comisión.
+	Fee           decimal.Decimal `gorm:"type:numeric;not null"` // Comisión estimada al cotizar.
+	Status        string          `gorm:"type:varchar(20);not null;index"`
+	TransactionID *uuid.UUID      `gorm:"type:uuid"` // Transacción generada al ejecutarla.
+	ExpiresAt     time.Time       `gorm:"not null"`
+	ExecutedAt    *time.Time
+	CreatedAt     time.Time `gorm:"autoCreateTime"`
+	UpdatedAt     time.Time `gorm:"autoUpdateTime"`
+}
+
+// ValidateQuoteRequest revisa lo que pide el usuario. El controlador lo llama antes de salir a buscar el precio.
+func ValidateQuoteRequest(coin, side string, amount decimal.Decimal) error {
+	if coin == "" {
+		return errors.New("la criptomoneda es obligatoria")
+	}
+	if side != SideBuy && side != SideSell {
+		return errors.New("el lado de la cotización debe ser buy o sell")
+	}
+	if !amount.IsPositive() {
+		return errors.New("la cantidad debe ser positiva")
+	}
+	return CheckPrecision(coin, amount)
+}
+
+// NewQuote valida los datos y crea una cotización abierta que vence después de ttl. La comisión se completa después.
+func NewQuote(userID uuid.UUID, coin, side string, amount, price decimal.Decimal, ttl time.Duration) (*Quote, error) {
+	if err := ValidateQuoteRequest(coin, side, amount); err != nil {
+		return nil, err
+	}
+
+	total := BuyCost(price, amount)
+	if side == SideSell {
+		total = SellProceeds(price, amount)
+	}
+
+	now := time.Now()
+	return &Quote{
+		ID:          uuid.New(),
+		UserID:      userID,
+		Coin:        coin,
+		Side:        side,
+		Amount:      amount,
+		Price:       price,
+		MarketPrice: price,
+		Total:       total,
+		Fee:         decimal.Zero,
+		Status:      QuoteStatusOpen,
+		ExpiresAt:   now.Add(ttl),
+		CreatedAt:   now,
+	}, nil
+}
+
+// CheckExecutable falla si la cotización ya se usó o ya venció.
+func (q *Quote) CheckExecutable(now time.Time) error {
+	switch {
+	case q.Status == QuoteStatusExecuted:
+		return ErrQuoteUsed
+	case q.Status == QuoteStatusExpired, !now.Before(q.ExpiresAt):
+		return ErrQuoteExpired
+	}
+	return nil
+}
+
+// MarkExecuted marca la cotización como usada por la transacción dada.
+func (q *Quote) MarkExecuted(transactionID uuid.UUID) {
+	now := time.Now()
+	q.Status = QuoteStatusExecuted
+	q.TransactionID = &transactionID
+	q.ExecutedAt = &now
+}
+
+// Expire marca la cotización como vencida.
+func (q *Quote) Expire() {
+	q.Status = QuoteStatusExpired
+}
+
+// BeforeCreate es un hook de GORM que genera el ID si no viene.
+func (q *Quote) BeforeCreate(tx *gorm.DB) (err error) {
+	if q.ID == uuid.Nil {
+		q.ID = uuid.New()
+	}
+	return
+}
+
+// QuoteRepository define las operaciones para trabajar con cotizaciones.
+type QuoteRepository interface {
+	Save(quote *Quote) error
+	Update(quote *Quote) error
+	FindByIDForUpdate(id uuid.UUID) (*Quote, error)
+	WithTx(tx *gorm.DB) QuoteRepository
+}
@@ -0,0 +1,189 @@
+package domain
+
+import (
+	"cryptoproject/pkg/cron"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+)
+
+// Estados posibles de una compra recurrente.
+const (
+	RecurringStatusActive    = "active"
+	RecurringStatusPaused    = "paused"
+	RecurringStatusCancelled = "cancelled"
+)
+
+// Resultados de cada ejecución.
+const (
+	RecurringRunSucceeded = "succeeded"
+	RecurringRunFailed    = "failed"
+)
+
+// ErrRecurringCancelled se devuelve al intentar modificar una compra recurrente cancelada.
+var ErrRecurringCancelled = errors.New("la compra recurrente está cancelada")
+
+// RecurringOrder es una compra periódica de un monto fijo en USD (dollar-cost averaging),
+// por ejemplo "50 USD de bitcoin todos los lunes" con Schedule "0 9 * * 1".
+type RecurringOrder struct {
+	ID          uuid.UUID       `gorm:"type:uuid;primaryKey"`
+	UserID      uuid.UUID       `gorm:"type:uuid;not null;index"`
+	Coin        string          `gorm:"type:text;not null"`
+	QuoteAmount decimal.Decimal `gorm:"type:numeric;not null"`      // USD a invertir en cada ejecución, sin la comisión.
+	Schedule    string          `gorm:"type:varchar(100);not null"` // Expresión cron en UTC.
+	Status      string          `gorm:"type:varchar(20);not null;index:idx_recurring_orders_due,priority:1"`
+	NextRunAt   time.Time       `gorm:"not null;index:idx_recurring_orders_due,priority:2"`
+	LastRunAt   *time.Time
+	// ClaimedUntil es hasta cuándo la tiene tomada una réplica del scheduler mientras pide el precio.
+	// Vencido, otra réplica la puede volver a tomar (por ejemplo si la primera se cayó).
+	ClaimedUntil *time.Time `json:"-"`
+	CreatedAt    time.Time  `gorm:"autoCreateTime"`
+	UpdatedAt    time.Time  `gorm:"autoUpdateTime"`
+}
+
+// RecurringRun registra cada ejecución de una compra recurrente, haya salido bien o no.
+type RecurringRun struct {
+	ID               uuid.UUID  `gorm:"type:uuid;primaryKey"`
+	RecurringOrderID uuid.UUID  `gorm:"type:uuid;not null;index"`
+	Status           string     `gorm:"type:varchar(20);not null"`
+	Reason           string     `gorm:"type:text"`
+	TransactionID    *uuid.UUID `gorm:"type:uuid"`
+	ScheduledAt      time.Time  `gorm:"not null"` // Cuándo tocaba ejecutarla.
+	CreatedAt        time.Time  `gorm:"autoCreateTime"`
+}
+
+// NewRecurringOrder valida los datos y crea una compra recurrente activa con su primera ejecución programada.
+func NewRecurringOrder(userID uuid.UUID, coin string, quoteAmount decimal.Decimal, schedule string, now time.Time) (*RecurringOrder, error) {
+	if coin == "" {
+		return nil, errors.New("la criptomoneda es obligatoria")
+	}
+	order := &RecurringOrder{
+		ID:        uuid.New(),
+		UserID:    userID,
+		Coin:      coin,
+		Status:    RecurringStatusActive,
+		CreatedAt: now,
+	}
+	if err := order.SetQuoteAmount(quoteAmount); err != nil {
+		return nil, err
+	}
+	if err := order.SetSchedule(schedule, now); err != nil {
+		return nil, err
+	}
+	return order, nil
+}
+
+// SetQuoteAmount cambia el monto en USD de cada ejecución.
+func (o *RecurringOrder) SetQuoteAmount(quoteAmount decimal.Decimal) error {
+	if !quoteAmount.IsPositive() {
+		return errors.New("el monto debe ser positivo")
+	}
+	if err := CheckPrecision(QuoteAsset, quoteAmount); err != nil {
+		return err
+	}
+	o.QuoteAmount = quoteAmount
+	return nil
+}
+
+// SetSchedule valida la expresión cron y reprograma la próxima ejecución.
+func (o *RecurringOrder) SetSchedule(schedule string, now time.Time) error {
+	parsed, err := cron.Parse(schedule)
+	if err != nil {
+		return err
+	}
+	next := parsed.Next(now)
+	if next.IsZero() {
+		return errors.New("la expresión cron nunca se cumple")
+	}
+	o.Schedule = schedule
+	o.NextRunAt = next
+	return nil
+}
+
+// SetStatus pausa, reanuda o cancela la compra recurrente. Al reanudar se reprograma desde ahora,
+// así no se ejecutan de golpe las fechas que pasaron mientras estaba pausada.
+func (o *RecurringOrder) SetStatus(status string, now time.Time) error {
+	if o.Status == RecurringStatusCancelled {
+		return ErrRecurringCancelled
+	}
+	switch status {
+	case RecurringStatusActive:
+		if o.Status != RecurringStatusActive {
+			if err := o.SetSchedule(o.Schedule, now); err != nil {
+				return err
+			}
+		}
+	case RecurringStatusPaused, RecurringStatusCancelled:
+	default:
+		return errors.New("el estado debe ser active, paused o cancelled")
+	}
+	o.Status = status
+	return nil
+}
+
+// Claim marca la compra como tomada hasta until, para que las demás réplicas del scheduler no la tomen.
+func (o *RecurringOrder) Claim(until time.Time) {
+	o.ClaimedUntil = &until
+}
+
+// Postpone corre la próxima ejecución hasta at sin registrar una ejecución, por ejemplo si no hubo precio.
+// No es una fecha del cron: cuando se ejecute, la siguiente se programa desde ese momento como siempre.
+func (o *RecurringOrder) Postpone(at time.Time) {
+	o.NextRunAt = at
+	o.ClaimedUntil = nil
+}
+
+// RecordRun registra una ejecución y programa la siguiente a partir de now.
+// Si el servidor estuvo caído no se recuperan las fechas perdidas: se ejecuta una vez y se sigue con la próxima.
+func (o *RecurringOrder) RecordRun(now time.Time, transactionID *uuid.UUID, runErr error) *RecurringRun {
+	run := &RecurringRun{
+		ID:               uuid.New(),
+		RecurringOrderID: o.ID,
+		Status:           RecurringRunSucceeded,
+		TransactionID:    transactionID,
+		ScheduledAt:      o.NextRunAt,
+		CreatedAt:        now,
+	}
+	if runErr != nil {
+		run.Status = RecurringRunFailed
+		run.Reason = runErr.Error()
+	}
+
+	o.LastRunAt = &now
+	o.ClaimedUntil = nil
+	parsed, err := cron.Parse(o.Schedule)
+	if err == nil {
+		o.NextRunAt = parsed.Next(now)
+	}
+	// Una expresión que dejó de cumplirse no debe quedar vencida para siempre.
+	if err != nil || o.NextRunAt.IsZero() {
+		o.Status = RecurringStatusCancelled
+	}
+	return run
+}
+
+// BeforeCreate es un hook de GORM que genera el ID si no viene.
+func (o *RecurringOrder) BeforeCreate(tx *gorm.DB) (err error) {
+	if o.ID == uuid.Nil {
+		o.ID = uuid.New()
+	}
+	return
+}
+
+// RecurringOrderRepository define las operaciones para trabajar con compras recurrentes.
+type RecurringOrderRepository interface {
+	Save(order *RecurringOrder) error
+	Update(order *RecurringOrder) error
+	FindByID(id uuid.UUID) (*RecurringOrder, error)
+	FindByIDForUpdate(id uuid.UUID) (*RecurringOrder, error)
+	FindByUserID(userID uuid.UUID) ([]RecurringOrder, error)
+	// ClaimDue busca y bloquea una compra activa vencida que ninguna otra réplica tenga tomada: ni bloqueada
+	// en este momento (SKIP LOCKED) ni con ClaimedUntil sin vencer. Devuelve nil, nil si no hay ninguna.
+	ClaimDue(now time.Time) (*RecurringOrder, error)
+	SaveRun(run *RecurringRun) error
+	FindRuns(orderID uuid.UUID, limit int) ([]RecurringRun, error)
+	WithTx(tx *gorm.DB) RecurringOrderRepository
+}
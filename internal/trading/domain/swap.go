@@ -0,0 +1,92 @@
+package domain
+
+import (
+	"errors"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+)
+
+// Tipos de entrada del historial.
+const (
+	HistoryTrade = "trade"
+	HistorySwap  = "swap"
+)
+
+// ValidateSwap revisa que el swap sea entre dos monedas distintas.
+func ValidateSwap(fromCoin, toCoin string) error {
+	if fromCoin == "" || toCoin == "" {
+		return errors.New("las monedas de origen y destino son obligatorias")
+	}
+	if strings.EqualFold(fromCoin, toCoin) {
+		return errors.New("las monedas de origen y destino deben ser distintas")
+	}
+	return nil
+}
+
+// SwapAmount calcula cuánto de la moneda destino se compra con net USD al precio dado.
+// Se trunca a los decimales de la moneda para que el costo nunca supere lo que dejó la venta.
+func SwapAmount(net, toPrice decimal.Decimal, toCoin string) decimal.Decimal {
+	if !toPrice.IsPositive() {
+		return decimal.Zero
+	}
+	return net.Div(toPrice).RoundFloor(AssetPlaces(toCoin))
+}
+
+// SwapEntry es la vista de un swap en el historial: la venta y la compra que lo componen.
+type SwapEntry struct {
+	ID        uuid.UUID
+	Timestamp time.Time
+	From      Transaction // Venta de la moneda de origen.
+	To        Transaction // Compra de la moneda destino.
+}
+
+// HistoryEntry es una línea del historial. Las operaciones sueltas conservan los campos de la transacción;
+// los swaps se muestran una sola vez con sus dos patas en Swap.
+type HistoryEntry struct {
+	Type string `json:"type"`
+	*Transaction
+	Swap *SwapEntry `json:"swap,omitempty"`
+}
+
+// BuildHistory agrupa las patas de cada swap en una sola entrada. Mantiene el orden de las transacciones recibidas,
+// con el swap en la posición de su primera pata. Una pata sin su pareja se muestra como operación suelta.
+func BuildHistory(transactions []Transaction) []HistoryEntry {
+	return buildHistory(transactions, nil)
+}
+
+// buildHistory hace el trabajo de BuildHistory. partners son patas de swap que no se listan solas
+// pero completan los swaps de transactions.
+func buildHistory(transactions, partners []Transaction) []HistoryEntry {
+	legs := make(map[uuid.UUID][]Transaction)
+	for _, tx := range append(append([]Transaction{}, transactions...), partners...) {
+		if tx.SwapID != nil {
+			legs[*tx.SwapID] = append(legs[*tx.SwapID], tx)
+		}
+	}
+
+	entries := make([]HistoryEntry, 0, len(transactions))
+	seen := make(map[uuid.UUID]bool)
+	for i := range transactions {
+		tx := transactions[i]
+		if tx.SwapID == nil || len(legs[*tx.SwapID]) != 2 {
+			entries = append(entries, HistoryEntry{Type: HistoryTrade, Transaction: &tx})
+			continue
+		}
+		if seen[*tx.SwapID] {
+			continue
+		}
+		seen[*tx.SwapID] = true
+
+		pair := legs[*tx.SwapID]
+		sort.SliceStable(pair, func(a, b int) bool { return pair[a].Side == SideSell && pair[b].Side != SideSell })
+		entries = append(entries, HistoryEntry{
+			Type: HistorySwap,
+			Swap: &SwapEntry{ID: *tx.SwapID, Timestamp: pair[0].Timestamp, From: pair[0], To: pair[1]},
+		})
+	}
+	return entries
+}
@@ -4,31 +4,53 @@ import (
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
 	"gorm.io/gorm"
 )
 
+// Lados posibles de una transacción.
+const (
+	SideBuy  = "buy"
+	SideSell = "sell"
+)
+
 // Transaction representa una operación de compra o venta.
 type Transaction struct {
-	ID        uuid.UUID `gorm:"type:uuid;primaryKey"`
-	UserID    uuid.UUID `gorm:"type:uuid;not null"`
-	Coin      string    `gorm:"type:text;not null"` // Ejemplo: BTC, SOL
-	Amount    float64   `gorm:"type:numeric;not null"`
-	Price     float64   `gorm:"type:numeric;not null"`
-	Timestamp time.Time `gorm:"autoCreateTime"`
+	ID        uuid.UUID       `gorm:"type:uuid;primaryKey"`
+	UserID    uuid.UUID       `gorm:"type:uuid;not null;index:idx_transactions_user_timestamp,priority:1"`
+	Coin      string          `gorm:"type:text;not null"`                    // Ejemplo: BTC, SOL
+	Side      string          `gorm:"type:varchar(10);not null;default:buy"` // buy o sell. Amount siempre es positivo.
+	Amount    decimal.Decimal `gorm:"type:numeric;not null"`
+	Price     decimal.Decimal `gorm:"type:numeric;not null"`
+	Fee       decimal.Decimal `gorm:"type:numeric;not null;default:0"`                                 // Comisión cobrada, aparte de price * amount.
+	FeeAsset  string          `gorm:"type:varchar(10);not null;default:usd"`                           // Activo en el que se cobró la comisión.
+	Liquidity string          `gorm:"type:varchar(10)"`                                                // maker o taker; vacío en transacciones viejas.
+	SwapID    *uuid.UUID      `gorm:"type:uuid;index"`                                                 // Une la venta y la compra de un swap.
+	Timestamp time.Time       `gorm:"autoCreateTime;index:idx_transactions_user_timestamp,priority:2"` // Respalda el historial paginado.
 }
 
-// NewTransaction crea una nueva transacción.
-func NewTransaction(userID uuid.UUID, coin string, amount, price float64) *Transaction {
+// NewTransaction crea una nueva transacción sin comisión; el ejecutor la completa con SetFee.
+func NewTransaction(userID uuid.UUID, coin, side string, amount, price decimal.Decimal) *Transaction {
 	return &Transaction{
 		ID:        uuid.New(),
 		UserID:    userID,
 		Coin:      coin,
+		Side:      side,
 		Amount:    amount,
 		Price:     price,
+		Fee:       decimal.Zero,
+		FeeAsset:  QuoteAsset,
 		Timestamp: time.Now(),
 	}
 }
 
+// SetFee registra la comisión cobrada en USD y si la operación fue maker o taker.
+func (t *Transaction) SetFee(fee decimal.Decimal, liquidity string) {
+	t.Fee = fee
+	t.FeeAsset = QuoteAsset
+	t.Liquidity = liquidity
+}
+
 // BeforeCreate es un hook de GORM que se ejecuta antes de insertar una nueva transacción.
 func (t *Transaction) BeforeCreate(tx *gorm.DB) (err error) {
 	if t.ID == uuid.Nil {
@@ -41,31 +63,16 @@ func (t *Transaction) BeforeCreate(tx *gorm.DB) (err error) {
 type TransactionRepository interface {
 	Save(transaction *Transaction) error
 	FindByUserID(userID uuid.UUID) ([]Transaction, error)
-}
-
-// GormTransactionRepository implementa TransactionRepository usando GORM.
-type GormTransactionRepository struct {
-	DB *gorm.DB
-}
-
-// NewTransactionRepository crea una nueva instancia de GormTransactionRepository.
-func NewTransactionRepository(db *gorm.DB) TransactionRepository {
-	return &GormTransactionRepository{DB: db}
-}
-
-// Save guarda una nueva transacción en la base de datos.
-func (r *GormTransactionRepository) Save(transaction *Transaction) error {
-	if err := r.DB.Create(transaction).Error; err != nil {
-		return err
-	}
-	return nil
-}
-
-// FindByUserID encuentra todas las transacciones asociadas a un usuario dado.
-func (r *GormTransactionRepository) FindByUserID(userID uuid.UUID) ([]Transaction, error) {
-	var transactions []Transaction
-	if err := r.DB.Where("user_id = ?", userID).Find(&transactions).Error; err != nil {
-		return nil, err
-	}
-	return transactions, nil
+	// FindPage devuelve hasta q.Limit+1 transacciones después del cursor de q, en el orden de q.ScanAscending().
+	// Con NewTransactionPage se arma la página final.
+	FindPage(userID uuid.UUID, q TransactionQuery) ([]Transaction, error)
+	// FindBySwapIDs devuelve todas las patas de los swaps dados, sin filtros.
+	FindBySwapIDs(userID uuid.UUID, swapIDs []uuid.UUID) ([]Transaction, error)
+	// StreamByUserID recorre las transacciones del usuario de la más vieja a la más nueva sin cargarlas todas en memoria.
+	// Si fn devuelve error se corta el recorrido y se devuelve ese error.
+	StreamByUserID(userID uuid.UUID, fn func(Transaction) error) error
+	FindUserIDs() ([]uuid.UUID, error)
+	// SumVolumeSince devuelve el volumen operado en USD (amount * price) por el usuario desde la fecha dada.
+	SumVolumeSince(userID uuid.UUID, since time.Time) (decimal.Decimal, error)
+	WithTx(tx *gorm.DB) TransactionRepository
 }
@@ -0,0 +1,182 @@
+package domain
+
+import (
+	"encoding/base64"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+)
+
+// ErrInvalidCursor se devuelve cuando el cursor de paginación no se puede leer.
+var ErrInvalidCursor = errors.New("el cursor es inválido")
+
+// TransactionFilter son los filtros opcionales del historial. Los campos vacíos no filtran.
+type TransactionFilter struct {
+	Coin      string
+	Side      string
+	From      *time.Time       // Inclusivo.
+	To        *time.Time       // Exclusivo.
+	MinAmount *decimal.Decimal // Cantidad de cripto, inclusivo.
+	MaxAmount *decimal.Decimal // Cantidad de cripto, inclusivo.
+}
+
+// Matches indica si una transacción pasa los filtros. Es el mismo criterio que aplica el repositorio en SQL.
+func (f TransactionFilter) Matches(tx Transaction) bool {
+	switch {
+	case f.Coin != "" && tx.Coin != f.Coin:
+		return false
+	case f.Side != "" && tx.Side != f.Side:
+		return false
+	case f.From != nil && tx.Timestamp.Before(*f.From):
+		return false
+	case f.To != nil && !tx.Timestamp.Before(*f.To):
+		return false
+	case f.MinAmount != nil && tx.Amount.LessThan(*f.MinAmount):
+		return false
+	case f.MaxAmount != nil && tx.Amount.GreaterThan(*f.MaxAmount):
+		return false
+	}
+	return true
+}
+
+// TransactionCursor marca una posición en el historial: el timestamp y el ID de una transacción.
+// El ID desempata las transacciones con el mismo timestamp.
+type TransactionCursor struct {
+	Timestamp time.Time
+	ID        uuid.UUID
+}
+
+// CursorFor devuelve el cursor que apunta a la transacción dada.
+func CursorFor(tx Transaction) *TransactionCursor {
+	return &TransactionCursor{Timestamp: tx.Timestamp, ID: tx.ID}
+}
+
+// Encode serializa el cursor como texto opaco para el cliente.
+func (c *TransactionCursor) Encode() string {
+	raw := strconv.FormatInt(c.Timestamp.UnixMicro(), 10) + "_" + c.ID.String()
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+// DecodeTransactionCursor lee un cursor generado por Encode.
+func DecodeTransactionCursor(value string) (*TransactionCursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	micros, id, found := strings.Cut(string(raw), "_")
+	if !found {
+		return nil, ErrInvalidCursor
+	}
+	unixMicro, err := strconv.ParseInt(micros, 10, 64)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	parsedID, err := uuid.Parse(id)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	return &TransactionCursor{Timestamp: time.UnixMicro(unixMicro).UTC(), ID: parsedID}, nil
+}
+
+// TransactionQuery describe una página del historial. After pide la página siguiente al cursor y Before la anterior;
+// como mucho uno de los dos. Ascending ordena de la más vieja a la más nueva (por defecto es al revés).
+type TransactionQuery struct {
+	Filter    TransactionFilter
+	Ascending bool
+	After     *TransactionCursor
+	Before    *TransactionCursor
+	Limit     int
+}
+
+// ScanAscending indica en qué sentido tiene que recorrer el índice el repositorio.
+// Para la página anterior se recorre al revés desde el cursor y después se da vuelta el resultado.
+func (q TransactionQuery) ScanAscending() bool {
+	return q.Ascending != (q.Before != nil)
+}
+
+// Cursor devuelve el cursor desde el que arranca la consulta, o nil para la primera página.
+func (q TransactionQuery) Cursor() *TransactionCursor {
+	if q.Before != nil {
+		return q.Before
+	}
+	return q.After
+}
+
+// Precedes indica si a va estrictamente antes que b en el orden del listado.
+func (q TransactionQuery) Precedes(a, b Transaction) bool {
+	if !q.Ascending {
+		a, b = b, a
+	}
+	return a.Timestamp.Before(b.Timestamp) ||
+		(a.Timestamp.Equal(b.Timestamp) && strings.Compare(a.ID.String(), b.ID.String()) < 0)
+}
+
+// TransactionPage es una página del historial con los cursores para moverse. Un cursor nil significa que no hay más.
+type TransactionPage struct {
+	Transactions []Transaction
+	Next         *TransactionCursor
+	Prev         *TransactionCursor
+}
+
+// NewTransactionPage arma la página a partir de lo que devolvió el repositorio:
+// hasta Limit+1 filas en el orden de ScanAscending; la fila extra solo sirve para saber si hay más.
+func NewTransactionPage(q TransactionQuery, rows []Transaction) *TransactionPage {
+	hasMore := len(rows) > q.Limit
+	if hasMore {
+		rows = rows[:q.Limit]
+	}
+	if q.Before != nil {
+		for i, j := 0, len(rows)-1; i < j; i, j = i+1, j-1 {
+			rows[i], rows[j] = rows[j], rows[i]
+		}
+	}
+
+	page := &TransactionPage{Transactions: rows}
+	if len(rows) == 0 {
+		return page
+	}
+	// Si venimos de la página siguiente sabemos que existe; lo mismo con la anterior.
+	if hasMore || q.Before != nil {
+		page.Next = CursorFor(rows[len(rows)-1])
+	}
+	if (q.Before != nil && hasMore) || q.After != nil {
+		page.Prev = CursorFor(rows[0])
+	}
+	return page
+}
+
+// BuildHistoryPage agrupa los swaps de una página del historial. Si una pata quedó en otra página,
+// partners trae su pareja: el swap se muestra completo en la página de la pata que aparece primero
+// según el filtro y el orden de la consulta, y se omite en la otra para que no salga dos veces.
+func BuildHistoryPage(q TransactionQuery, page []Transaction, partners []Transaction) []HistoryEntry {
+	inPage := make(map[uuid.UUID]bool, len(page))
+	for _, tx := range page {
+		inPage[tx.ID] = true
+	}
+
+	extra := make([]Transaction, 0, len(partners))
+	shownElsewhere := make(map[uuid.UUID]bool)
+	for _, partner := range partners {
+		if inPage[partner.ID] || partner.SwapID == nil {
+			continue
+		}
+		for _, tx := range page {
+			if tx.SwapID != nil && *tx.SwapID == *partner.SwapID && q.Filter.Matches(partner) && q.Precedes(partner, tx) {
+				shownElsewhere[*partner.SwapID] = true
+			}
+		}
+		extra = append(extra, partner)
+	}
+
+	visible := make([]Transaction, 0, len(page))
+	for _, tx := range page {
+		if tx.SwapID == nil || !shownElsewhere[*tx.SwapID] {
+			visible = append(visible, tx)
+		}
+	}
+	return buildHistory(visible, extra)
+}
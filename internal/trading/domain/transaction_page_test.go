@@ -0,0 +1,134 @@
+package domain
+
+import (
+	"encoding/base64"
+	"errors"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestTransactionCursorEncoding(t *testing.T) {
+	cursor := &TransactionCursor{Timestamp: time.Date(2024, 3, 1, 12, 30, 0, 123456000, time.UTC), ID: uuid.New()}
+	decoded, err := DecodeTransactionCursor(cursor.Encode())
+	if err != nil {
+		t.Fatalf("DecodeTransactionCursor: %v", err)
+	}
+	if !decoded.Timestamp.Equal(cursor.Timestamp) || decoded.ID != cursor.ID {
+		t.Fatalf("cursor = %+v, se esperaba %+v", decoded, cursor)
+	}
+
+	encode := func(raw string) string { return base64.RawURLEncoding.EncodeToString([]byte(raw)) }
+	invalid := []string{"", "no es base64!", encode("1709296200000000"), encode("abc_" + uuid.NewString()), encode("1709296200000000_abc")}
+	for _, value := range invalid {
+		if _, err := DecodeTransactionCursor(value); !errors.Is(err, ErrInvalidCursor) {
+			t.Fatalf("DecodeTransactionCursor(%q) = %v, se esperaba ErrInvalidCursor", value, err)
+		}
+	}
+}
+
+// scan hace lo que el repositorio en SQL: recorre en el sentido de ScanAscending desde el cursor y trae hasta Limit+1 filas.
+func scan(all []Transaction, q TransactionQuery) []Transaction {
+	order := TransactionQuery{Ascending: q.ScanAscending()}
+	rows := append([]Transaction(nil), all...)
+	sort.Slice(rows, func(i, j int) bool { return order.Precedes(rows[i], rows[j]) })
+
+	var result []Transaction
+	for _, tx := range rows {
+		if cursor := q.Cursor(); cursor != nil && !order.Precedes(Transaction{Timestamp: cursor.Timestamp, ID: cursor.ID}, tx) {
+			continue
+		}
+		if result = append(result, tx); len(result) > q.Limit {
+			break
+		}
+	}
+	return result
+}
+
+// roundTrip pasa el cursor por su forma de texto, como hace el cliente.
+func roundTrip(t *testing.T, cursor *TransactionCursor) *TransactionCursor {
+	t.Helper()
+	decoded, err := DecodeTransactionCursor(cursor.Encode())
+	if err != nil {
+		t.Fatalf("DecodeTransactionCursor: %v", err)
+	}
+	return decoded
+}
+
+func TestTransactionPaging(t *testing.T) {
+	base := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	var all []Transaction
+	// Siete transacciones, tres con el mismo timestamp para que desempate el ID.
+	for _, minute := range []int{0, 1, 2, 2, 2, 3, 4} {
+		all = append(all, Transaction{ID: uuid.New(), Timestamp: base.Add(time.Duration(minute) * time.Minute)})
+	}
+
+	for _, tt := range []struct {
+		name      string
+		ascending bool
+		limit     int
+	}{
+		{name: "ascendente de a 2", ascending: true, limit: 2},
+		{name: "descendente de a 3", ascending: false, limit: 3},
+		{name: "una sola página", ascending: true, limit: 10},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			order := TransactionQuery{Ascending: tt.ascending}
+			expected := append([]Transaction(nil), all...)
+			sort.Slice(expected, func(i, j int) bool { return order.Precedes(expected[i], expected[j]) })
+
+			// Hacia adelante con Next hasta el final.
+			var pages [][]Transaction
+			q := TransactionQuery{Ascending: tt.ascending, Limit: tt.limit}
+			for {
+				page := NewTransactionPage(q, scan(all, q))
+				if len(pages) == 0 && page.Prev != nil {
+					t.Fatal("la primera página tiene cursor anterior")
+				}
+				if len(pages) > 0 && page.Prev == nil {
+					t.Fatalf("la página %d no tiene cursor anterior", len(pages)+1)
+				}
+				pages = append(pages, page.Transactions)
+				if page.Next == nil {
+					break
+				}
+				q = TransactionQuery{Ascending: tt.ascending, Limit: tt.limit, After: roundTrip(t, page.Next)}
+			}
+			var walked []Transaction
+			for _, page := range pages {
+				walked = append(walked, page...)
+			}
+			if len(walked) != len(expected) {
+				t.Fatalf("se recorrieron %d transacciones, se esperaban %d", len(walked), len(expected))
+			}
+			for i := range expected {
+				if walked[i].ID != expected[i].ID {
+					t.Fatalf("posición %d fuera de orden", i)
+				}
+			}
+
+			// Y de vuelta con Prev desde la última página: se obtienen las mismas páginas.
+			last := pages[len(pages)-1]
+			cursor := CursorFor(last[0])
+			for i := len(pages) - 2; i >= 0; i-- {
+				q := TransactionQuery{Ascending: tt.ascending, Limit: tt.limit, Before: roundTrip(t, cursor)}
+				page := NewTransactionPage(q, scan(all, q))
+				if len(page.Transactions) != len(pages[i]) || page.Transactions[0].ID != pages[i][0].ID {
+					t.Fatalf("volviendo a la página %d se obtuvo otra página", i+1)
+				}
+				if page.Next == nil {
+					t.Fatalf("la página %d, vista desde la siguiente, no tiene cursor siguiente", i+1)
+				}
+				if (i == 0) != (page.Prev == nil) {
+					t.Fatalf("página %d: cursor anterior %v", i+1, page.Prev)
+				}
+				cursor = page.Prev
+				if cursor == nil {
+					break
+				}
+			}
+		})
+	}
+}
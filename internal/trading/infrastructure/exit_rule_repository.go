@@ -0,0 +1,100 @@
+package infrastructure
+
+import (
+	"cryptoproject/internal/trading/domain"
+	"errors"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// GormExitRuleRepository implementa la interfaz ExitRuleRepository usando GORM.
+type GormExitRuleRepository struct {
+	DB *gorm.DB
+}
+
+// NewExitRuleRepository crea una nueva instancia de GormExitRuleRepository.
+func NewExitRuleRepository(db *gorm.DB) domain.ExitRuleRepository {
+	return &GormExitRuleRepository{DB: db}
+}
+
+// Save guarda una regla nueva y su evento inicial en la misma transacción.
+func (r *GormExitRuleRepository) Save(rule *domain.ExitRule, event *domain.ExitRuleEvent) error {
+	return r.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(rule).Error; err != nil {
+			return err
+		}
+		return tx.Create(event).Error
+	})
+}
+
+// Update persiste el nuevo estado de la regla junto con el evento que lo explica.
+func (r *GormExitRuleRepository) Update(rule *domain.ExitRule, event *domain.ExitRuleEvent) error {
+	return r.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Save(rule).Error; err != nil {
+			return err
+		}
+		return tx.Create(event).Error
+	})
+}
+
+// FindByID busca una regla por su ID.
+func (r *GormExitRuleRepository) FindByID(id uuid.UUID) (*domain.ExitRule, error) {
+	var rule domain.ExitRule
+	if err := r.DB.First(&rule, "id = ?", id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("regla no encontrada")
+		}
+		return nil, err
+	}
+	return &rule, nil
+}
+
+// FindByIDForUpdate busca una regla y bloquea su fila para que el monitor y la cancelación no se pisen.
+func (r *GormExitRuleRepository) FindByIDForUpdate(id uuid.UUID) (*domain.ExitRule, error) {
+	var rule domain.ExitRule
+	if err := r.DB.Clauses(clause.Locking{Strength: "UPDATE"}).First(&rule, "id = ?", id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("regla no encontrada")
+		}
+		return nil, err
+	}
+	return &rule, nil
+}
+
+// FindByUserID devuelve las reglas de un usuario, opcionalmente filtradas por estado.
+func (r *GormExitRuleRepository) FindByUserID(userID uuid.UUID, status string) ([]domain.ExitRule, error) {
+	var rules []domain.ExitRule
+	query := r.DB.Where("user_id = ?", userID)
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+	if err := query.Order("created_at DESC").Find(&rules).Error; err != nil {
+		return nil, err
+	}
+	return rules, nil
+}
+
+// FindActive devuelve todas las reglas que todavía hay que vigilar.
+func (r *GormExitRuleRepository) FindActive() ([]domain.ExitRule, error) {
+	var rules []domain.ExitRule
+	if err := r.DB.Where("status = ?", domain.ExitRuleStatusActive).Order("created_at ASC").Find(&rules).Error; err != nil {
+		return nil, err
+	}
+	return rules, nil
+}
+
+// FindEvents devuelve el historial de estados de una regla en orden cronológico.
+func (r *GormExitRuleRepository) FindEvents(ruleID uuid.UUID) ([]domain.ExitRuleEvent, error) {
+	var events []domain.ExitRuleEvent
+	if err := r.DB.Where("rule_id = ?", ruleID).Order("created_at ASC").Find(&events).Error; err != nil {
+		return nil, err
+	}
+	return events, nil
+}
+
+// WithTx devuelve una copia del repositorio que trabaja dentro de la transacción dada.
+func (r *GormExitRuleRepository) WithTx(tx *gorm.DB) domain.ExitRuleRepository {
+	return &GormExitRuleRepository{DB: tx}
+}
@@ -0,0 +1,65 @@
+package infrastructure
+
+import (
+	"cryptoproject/internal/trading/domain"
+	"errors"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// GormHoldingRepository implementa la interfaz HoldingRepository usando GORM.
+type GormHoldingRepository struct {
+	DB *gorm.DB
+}
+
+// NewHoldingRepository crea una nueva instancia de GormHoldingRepository.
+func NewHoldingRepository(db *gorm.DB) domain.HoldingRepository {
+	return &GormHoldingRepository{DB: db}
+}
+
+// FindByUserID devuelve las tenencias de un usuario ordenadas por moneda.
+func (r *GormHoldingRepository) FindByUserID(userID uuid.UUID) ([]domain.Holding, error) {
+	var holdings []domain.Holding
+	if err := r.DB.Where("user_id = ?", userID).Order("coin ASC").Find(&holdings).Error; err != nil {
+		return nil, err
+	}
+	return holdings, nil
+}
+
+// FindForUpdate busca la tenencia y bloquea su fila. Si todavía no existe devuelve una en cero.
+func (r *GormHoldingRepository) FindForUpdate(userID uuid.UUID, coin string) (*domain.Holding, error) {
+	var holding domain.Holding
+	err := r.DB.Clauses(clause.Locking{Strength: "UPDATE"}).
+		First(&holding, "user_id = ? AND coin = ?", userID, coin).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return &domain.Holding{UserID: userID, Coin: coin}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &holding, nil
+}
+
+// Save inserta o actualiza la tenencia por (user_id, coin).
+func (r *GormHoldingRepository) Save(holding *domain.Holding) error {
+	return r.DB.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "user_id"}, {Name: "coin"}},
+		DoUpdates: clause.AssignmentColumns([]string{"amount", "cost_basis", "updated_at"}),
+	}).Create(holding).Error
+}
+
+// FindUserIDs devuelve los usuarios que tienen al menos una tenencia guardada.
+func (r *GormHoldingRepository) FindUserIDs() ([]uuid.UUID, error) {
+	var userIDs []uuid.UUID
+	if err := r.DB.Model(&domain.Holding{}).Distinct("user_id").Pluck("user_id", &userIDs).Error; err != nil {
+		return nil, err
+	}
+	return userIDs, nil
+}
+
+// WithTx devuelve una copia del repositorio que trabaja dentro de la transacción dada.
+func (r *GormHoldingRepository) WithTx(tx *gorm.DB) domain.HoldingRepository {
+	return &GormHoldingRepository{DB: tx}
+}
@@ -0,0 +1,81 @@
+package infrastructure
+
+import (
+	"cryptoproject/internal/trading/domain"
+	"errors"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// GormOrderRepository implementa la interfaz OrderRepository usando GORM.
+type GormOrderRepository struct {
+	DB *gorm.DB
+}
+
+// NewOrderRepository crea una nueva instancia de GormOrderRepository.
+func NewOrderRepository(db *gorm.DB) domain.OrderRepository {
+	return &GormOrderRepository{DB: db}
+}
+
+// Save guarda una nueva orden en la base de datos.
+func (r *GormOrderRepository) Save(order *domain.Order) error {
+	return r.DB.Create(order).Error
+}
+
+// Update persiste los cambios de estado de una orden.
+func (r *GormOrderRepository) Update(order *domain.Order) error {
+	return r.DB.Save(order).Error
+}
+
+// FindByID busca una orden por su ID.
+func (r *GormOrderRepository) FindByID(id uuid.UUID) (*domain.Order, error) {
+	var order domain.Order
+	if err := r.DB.First(&order, "id = ?", id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("orden no encontrada")
+		}
+		return nil, err
+	}
+	return &order, nil
+}
+
+// FindByIDForUpdate busca una orden y bloquea su fila para que el matcher y la cancelación no se pisen.
+func (r *GormOrderRepository) FindByIDForUpdate(id uuid.UUID) (*domain.Order, error) {
+	var order domain.Order
+	if err := r.DB.Clauses(clause.Locking{Strength: "UPDATE"}).First(&order, "id = ?", id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("orden no encontrada")
+		}
+		return nil, err
+	}
+	return &order, nil
+}
+
+// FindByUserID devuelve las órdenes de un usuario, opcionalmente filtradas por estado.
+func (r *GormOrderRepository) FindByUserID(userID uuid.UUID, status string) ([]domain.Order, error) {
+	var orders []domain.Order
+	query := r.DB.Where("user_id = ?", userID)
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+	if err := query.Order("created_at DESC").Find(&orders).Error; err != nil {
+		return nil, err
+	}
+	return orders, nil
+}
+
+// FindOpen devuelve todas las órdenes abiertas, las más antiguas primero para respetar prioridad.
+func (r *GormOrderRepository) FindOpen() ([]domain.Order, error) {
+	var orders []domain.Order
+	if err := r.DB.Where("status = ?", domain.OrderStatusOpen).Order("created_at ASC").Find(&orders).Error; err != nil {
+		return nil, err
+	}
+	return orders, nil
+}
+
+// WithTx devuelve una copia del repositorio que trabaja dentro de la transacción dada.
+func (r *GormOrderRepository) WithTx(tx *gorm.DB) domain.OrderRepository {
+	return &GormOrderRepository{DB: tx}
+}
@@ -0,0 +1,47 @@
+package infrastructure
+
+import (
+	"cryptoproject/internal/trading/domain"
+	"errors"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// GormQuoteRepository implementa la interfaz QuoteRepository usando GORM.
+type GormQuoteRepository struct {
+	DB *gorm.DB
+}
+
+// NewQuoteRepository crea una nueva instancia de GormQuoteRepository.
+func NewQuoteRepository(db *gorm.DB) domain.QuoteRepository {
+	return &GormQuoteRepository{DB: db}
+}
+
+// Save guarda una nueva cotización.
+func (r *GormQuoteRepository) Save(quote *domain.Quote) error {
+	return r.DB.Create(quote).Error
+}
+
+// Update persiste el cambio de estado de una cotización.
+func (r *GormQuoteRepository) Update(quote *domain.Quote) error {
+	return r.DB.Save(quote).Error
+}
+
+// FindByIDForUpdate busca una cotización y bloquea su fila, así dos ejecuciones simultáneas no la usan dos veces.
+func (r *GormQuoteRepository) FindByIDForUpdate(id uuid.UUID) (*domain.Quote, error) {
+	var quote domain.Quote
+	if err := r.DB.Clauses(clause.Locking{Strength: "UPDATE"}).First(&quote, "id = ?", id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("cotización no encontrada")
+		}
+		return nil, err
+	}
+	return &quote, nil
+}
+
+// WithTx devuelve una copia del repositorio que trabaja dentro de la transacción dada.
+func (r *GormQuoteRepository) WithTx(tx *gorm.DB) domain.QuoteRepository {
+	return &GormQuoteRepository{DB: tx}
+}
@@ -0,0 +1,99 @@
+package infrastructure
+
+import (
+	"cryptoproject/internal/trading/domain"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// GormRecurringOrderRepository implementa la interfaz RecurringOrderRepository usando GORM.
+type GormRecurringOrderRepository struct {
+	DB *gorm.DB
+}
+
+// NewRecurringOrderRepository crea una nueva instancia de GormRecurringOrderRepository.
+func NewRecurringOrderRepository(db *gorm.DB) domain.RecurringOrderRepository {
+	return &GormRecurringOrderRepository{DB: db}
+}
+
+// Save guarda una nueva compra recurrente.
+func (r *GormRecurringOrderRepository) Save(order *domain.RecurringOrder) error {
+	return r.DB.Create(order).Error
+}
+
+// Update persiste los cambios de una compra recurrente.
+func (r *GormRecurringOrderRepository) Update(order *domain.RecurringOrder) error {
+	return r.DB.Save(order).Error
+}
+
+// FindByID busca una compra recurrente por su ID.
+func (r *GormRecurringOrderRepository) FindByID(id uuid.UUID) (*domain.RecurringOrder, error) {
+	return r.find(r.DB, id)
+}
+
+// FindByIDForUpdate busca una compra recurrente y bloquea su fila para no pisar una ejecución en curso.
+func (r *GormRecurringOrderRepository) FindByIDForUpdate(id uuid.UUID) (*domain.RecurringOrder, error) {
+	return r.find(r.DB.Clauses(clause.Locking{Strength: "UPDATE"}), id)
+}
+
+func (r *GormRecurringOrderRepository) find(db *gorm.DB, id uuid.UUID) (*domain.RecurringOrder, error) {
+	var order domain.RecurringOrder
+	if err := db.First(&order, "id = ?", id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("compra recurrente no encontrada")
+		}
+		return nil, err
+	}
+	return &order, nil
+}
+
+// FindByUserID devuelve las compras recurrentes de un usuario, las más nuevas primero.
+func (r *GormRecurringOrderRepository) FindByUserID(userID uuid.UUID) ([]domain.RecurringOrder, error) {
+	var orders []domain.RecurringOrder
+	if err := r.DB.Where("user_id = ?", userID).Order("created_at DESC").Find(&orders).Error; err != nil {
+		return nil, err
+	}
+	return orders, nil
+}
+
+// ClaimDue busca la compra vencida más atrasada que no esté tomada. SKIP LOCKED evita esperar a la réplica
+// que la está marcando en este momento; claimed_until, a la que ya la marcó y está pidiendo el precio.
+func (r *GormRecurringOrderRepository) ClaimDue(now time.Time) (*domain.RecurringOrder, error) {
+	var order domain.RecurringOrder
+	err := r.DB.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+		Where("status = ? AND next_run_at <= ?", domain.RecurringStatusActive, now).
+		Where("claimed_until IS NULL OR claimed_until <= ?", now).
+		Order("next_run_at ASC").
+		First(&order).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &order, nil
+}
+
+// SaveRun registra una ejecución.
+func (r *GormRecurringOrderRepository) SaveRun(run *domain.RecurringRun) error {
+	return r.DB.Create(run).Error
+}
+
+// FindRuns devuelve las últimas ejecuciones de una compra recurrente.
+func (r *GormRecurringOrderRepository) FindRuns(orderID uuid.UUID, limit int) ([]domain.RecurringRun, error) {
+	var runs []domain.RecurringRun
+	err := r.DB.Where("recurring_order_id = ?", orderID).Order("created_at DESC").Limit(limit).Find(&runs).Error
+	if err != nil {
+		return nil, err
+	}
+	return runs, nil
+}
+
+// WithTx devuelve una copia del repositorio que trabaja dentro de la transacción dada.
+func (r *GormRecurringOrderRepository) WithTx(tx *gorm.DB) domain.RecurringOrderRepository {
+	return &GormRecurringOrderRepository{DB: tx}
+}
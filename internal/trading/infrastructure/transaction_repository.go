@@ -3,8 +3,10 @@ package infrastructure
 import (
 	"cryptoproject/internal/trading/domain"
 	"errors"
+	"time"
 
 	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
 	"gorm.io/gorm"
 )
 
@@ -26,10 +28,10 @@ func (r *GormTransactionRepository) Save(transaction *domain.Transaction) error
 	return nil
 }
 
-// FindByUserID recupera las transacciones realizadas por un usuario específico.
+// FindByUserID recupera las transacciones realizadas por un usuario específico, de la más vieja a la más nueva.
 func (r *GormTransactionRepository) FindByUserID(userID uuid.UUID) ([]domain.Transaction, error) {
 	var transactions []domain.Transaction
-	if err := r.DB.Where("user_id = ?", userID).Find(&transactions).Error; err != nil {
+	if err := r.DB.Where("user_id = ?", userID).Order("timestamp ASC, id ASC").Find(&transactions).Error; err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			return nil, nil
 		}
@@ -38,6 +40,108 @@ func (r *GormTransactionRepository) FindByUserID(userID uuid.UUID) ([]domain.Tra
 	return transactions, nil
 }
 
+// FindPage busca una página del historial con paginación por cursor (keyset) sobre (timestamp, id),
+// así el costo no crece con el número de página como con OFFSET.
+func (r *GormTransactionRepository) FindPage(userID uuid.UUID, q domain.TransactionQuery) ([]domain.Transaction, error) {
+	query := r.DB.Where("user_id = ?", userID)
+
+	f := q.Filter
+	if f.Coin != "" {
+		query = query.Where("coin = ?", f.Coin)
+	}
+	if f.Side != "" {
+		query = query.Where("side = ?", f.Side)
+	}
+	if f.From != nil {
+		query = query.Where("timestamp >= ?", *f.From)
+	}
+	if f.To != nil {
+		query = query.Where("timestamp < ?", *f.To)
+	}
+	if f.MinAmount != nil {
+		query = query.Where("amount >= ?", *f.MinAmount)
+	}
+	if f.MaxAmount != nil {
+		query = query.Where("amount <= ?", *f.MaxAmount)
+	}
+
+	direction, comparison := "DESC", "<"
+	if q.ScanAscending() {
+		direction, comparison = "ASC", ">"
+	}
+	if cursor := q.Cursor(); cursor != nil {
+		query = query.Where("(timestamp, id) "+comparison+" (?, ?)", cursor.Timestamp, cursor.ID)
+	}
+
+	var transactions []domain.Transaction
+	err := query.Order("timestamp " + direction + ", id " + direction).Limit(q.Limit + 1).Find(&transactions).Error
+	if err != nil {
+		return nil, err
+	}
+	return transactions, nil
+}
+
+// FindBySwapIDs devuelve las patas de los swaps dados.
+func (r *GormTransactionRepository) FindBySwapIDs(userID uuid.UUID, swapIDs []uuid.UUID) ([]domain.Transaction, error) {
+	if len(swapIDs) == 0 {
+		return nil, nil
+	}
+	var transactions []domain.Transaction
+	if err := r.DB.Where("user_id = ? AND swap_id IN ?", userID, swapIDs).Find(&transactions).Error; err != nil {
+		return nil, err
+	}
+	return transactions, nil
+}
+
+// StreamByUserID lee las transacciones fila por fila con un cursor de la base de datos.
+func (r *GormTransactionRepository) StreamByUserID(userID uuid.UUID, fn func(domain.Transaction) error) error {
+	rows, err := r.DB.Model(&domain.Transaction{}).
+		Where("user_id = ?", userID).
+		Order("timestamp ASC, id ASC").
+		Rows()
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var transaction domain.Transaction
+		if err := r.DB.ScanRows(rows, &transaction); err != nil {
+			return err
+		}
+		if err := fn(transaction); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+// FindUserIDs devuelve los usuarios que tienen al menos una transacción.
+func (r *GormTransactionRepository) FindUserIDs() ([]uuid.UUID, error) {
+	var userIDs []uuid.UUID
+	if err := r.DB.Model(&domain.Transaction{}).Distinct("user_id").Pluck("user_id", &userIDs).Error; err != nil {
+		return nil, err
+	}
+	return userIDs, nil
+}
+
+// SumVolumeSince suma amount * price de las transacciones del usuario desde la fecha dada.
+func (r *GormTransactionRepository) SumVolumeSince(userID uuid.UUID, since time.Time) (decimal.Decimal, error) {
+	var volume decimal.NullDecimal
+	err := r.DB.Model(&domain.Transaction{}).
+		Select("SUM(amount * price)").
+		Where("user_id = ? AND timestamp >= ?", userID, since).
+		Row().
+		Scan(&volume)
+	if err != nil {
+		return decimal.Zero, err
+	}
+	if !volume.Valid {
+		return decimal.Zero, nil
+	}
+	return volume.Decimal, nil
+}
+
 // FindAll devuelve todas las transacciones (opcional para extensibilidad futura).
 func (r *GormTransactionRepository) FindAll() ([]domain.Transaction, error) {
 	var transactions []domain.Transaction
@@ -48,17 +152,17 @@ func (r *GormTransactionRepository) FindAll() ([]domain.Transaction, error) {
 }
 
 // CalculateCryptoHoldings calcula el balance total por criptomoneda para un usuario.
-func (r *GormTransactionRepository) CalculateCryptoHoldings(userID uuid.UUID) (map[string]float64, error) {
+func (r *GormTransactionRepository) CalculateCryptoHoldings(userID uuid.UUID) (map[string]decimal.Decimal, error) {
 	var transactions []domain.Transaction
 	if err := r.DB.Where("user_id = ?", userID).Find(&transactions).Error; err != nil {
 		return nil, err
 	}
 
-	// Mapa para acumular las cantidades de cada criptomoneda
-	cryptoHoldings := make(map[string]float64)
-	for _, transaction := range transactions {
-		cryptoHoldings[transaction.Coin] += transaction.Amount
-	}
+	// Las ventas restan, así que reconstruimos las posiciones en vez de sumar a ciegas.
+	return domain.HoldingsFromPositions(domain.BuildPositions(transactions)), nil
+}
 
-	return cryptoHoldings, nil
+// WithTx devuelve una copia del repositorio que trabaja dentro de la transacción dada.
+func (r *GormTransactionRepository) WithTx(tx *gorm.DB) domain.TransactionRepository {
+	return &GormTransactionRepository{DB: tx}
 }
@@ -2,6 +2,9 @@ package config
 
 import (
 	"log"
+	"os"
+	"strconv"
+	"time"
 
 	"github.com/joho/godotenv"
 )
@@ -12,3 +15,53 @@ func LoadConfig() {
 		log.Println("Advertencia: No se pudo cargar el archivo .env, usando variables de entorno")
 	}
 }
+
+// GetEnv devuelve una variable de entorno o el valor por defecto si no está definida.
+func GetEnv(key, fallback string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return fallback
+}
+
+// GetDuration lee una duración (ej. "15s", "1m"). Si no se puede parsear, usa el valor por defecto.
+func GetDuration(key string, fallback time.Duration) time.Duration {
+	value := os.Getenv(key)
+	if value == "" {
+		return fallback
+	}
+	duration, err := time.ParseDuration(value)
+	if err != nil {
+		log.Printf("Advertencia: %s inválido (%q), usando %s", key, value, fallback)
+		return fallback
+	}
+	return duration
+}
+
+// GetFloat lee un número decimal. Si no se puede parsear, usa el valor por defecto.
+func GetFloat(key string, fallback float64) float64 {
+	value := os.Getenv(key)
+	if value == "" {
+		return fallback
+	}
+	number, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		log.Printf("Advertencia: %s inválido (%q), usando %v", key, value, fallback)
+		return fallback
+	}
+	return number
+}
+
+// GetInt lee un entero. Si no se puede parsear, usa el valor por defecto.
+func GetInt(key string, fallback int) int {
+	value := os.Getenv(key)
+	if value == "" {
+		return fallback
+	}
+	number, err := strconv.Atoi(value)
+	if err != nil {
+		log.Printf("Advertencia: %s inválido (%q), usando %d", key, value, fallback)
+		return fallback
+	}
+	return number
+}
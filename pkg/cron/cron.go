@@ -0,0 +1,169 @@
+package cron
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Schedule es una expresión cron de cinco campos ya parseada: minuto, hora, día del mes, mes y día de la semana.
+// Soporta "*", listas ("1,15"), rangos ("1-5"), pasos ("*/15", "0-30/10") y los atajos
+// @hourly, @daily, @weekly y @monthly. Todo se evalúa en UTC.
+type Schedule struct {
+	minute, hour, dom, month, dow uint64
+	domAny, dowAny                bool
+}
+
+// descriptors son los atajos que se aceptan en lugar de los cinco campos.
+var descriptors = map[string]string{
+	"@hourly":  "0 * * * *",
+	"@daily":   "0 0 * * *",
+	"@weekly":  "0 0 * * 0",
+	"@monthly": "0 0 1 * *",
+}
+
+// field describe los valores válidos de un campo.
+type field struct {
+	name     string
+	min, max int
+}
+
+var fields = []field{
+	{"minuto", 0, 59},
+	{"hora", 0, 23},
+	{"día del mes", 1, 31},
+	{"mes", 1, 12},
+	{"día de la semana", 0, 6},
+}
+
+// Parse valida y parsea una expresión cron.
+func Parse(expr string) (*Schedule, error) {
+	expr = strings.TrimSpace(expr)
+	if replacement, ok := descriptors[strings.ToLower(expr)]; ok {
+		expr = replacement
+	}
+
+	parts := strings.Fields(expr)
+	if len(parts) != len(fields) {
+		return nil, fmt.Errorf("la expresión cron debe tener %d campos: %q", len(fields), expr)
+	}
+
+	masks := make([]uint64, len(fields))
+	for i, part := range parts {
+		mask, err := parseField(part, fields[i])
+		if err != nil {
+			return nil, err
+		}
+		masks[i] = mask
+	}
+
+	return &Schedule{
+		minute: masks[0],
+		hour:   masks[1],
+		dom:    masks[2],
+		month:  masks[3],
+		dow:    masks[4],
+		domAny: parts[2] == "*",
+		dowAny: parts[4] == "*",
+	}, nil
+}
+
+// parseField convierte un campo en una máscara de bits con los valores permitidos.
+func parseField(value string, f field) (uint64, error) {
+	var mask uint64
+	for _, item := range strings.Split(value, ",") {
+		rangePart, step := item, 1
+		if i := strings.Index(item, "/"); i >= 0 {
+			rangePart = item[:i]
+			var err error
+			step, err = strconv.Atoi(item[i+1:])
+			if err != nil || step <= 0 {
+				return 0, fmt.Errorf("paso inválido en el campo %s: %q", f.name, item)
+			}
+		}
+
+		start, end := f.min, f.max
+		if rangePart != "*" {
+			bounds := strings.SplitN(rangePart, "-", 2)
+			var err error
+			start, err = strconv.Atoi(bounds[0])
+			if err != nil {
+				return 0, fmt.Errorf("valor inválido en el campo %s: %q", f.name, item)
+			}
+			end = start
+			if len(bounds) == 2 {
+				end, err = strconv.Atoi(bounds[1])
+				if err != nil {
+					return 0, fmt.Errorf("valor inválido en el campo %s: %q", f.name, item)
+				}
+			} else if step > 1 {
+				end = f.max // "5/15" significa desde 5 hasta el final, cada 15.
+			}
+		}
+		// El domingo también se puede escribir como 7. En un rango que termina en 7 solo entra si el paso cae
+		// en él: "1-7/2" incluye el domingo, pero "2-7/2" son martes, jueves y sábado.
+		if f.name == "día de la semana" && end == 7 {
+			if start == 7 {
+				start, end = 0, 0
+			} else {
+				end = 6
+				if (7-start)%step == 0 {
+					mask |= 1
+				}
+			}
+		}
+		if start < f.min || end > f.max || start > end {
+			return 0, fmt.Errorf("valor fuera de rango en el campo %s: %q", f.name, item)
+		}
+
+		for v := start; v <= end; v += step {
+			mask |= 1 << uint(v)
+		}
+	}
+	return mask, nil
+}
+
+// Next devuelve el primer minuto estrictamente posterior a after que cumple la expresión.
+// Si no encuentra ninguno en cinco años (ej. "0 0 30 2 *") devuelve el tiempo cero.
+func (s *Schedule) Next(after time.Time) time.Time {
+	t := after.UTC().Truncate(time.Minute).Add(time.Minute)
+	limit := t.AddDate(5, 0, 0)
+
+	for t.Before(limit) {
+		if s.month&(1<<uint(t.Month())) == 0 {
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, time.UTC)
+			continue
+		}
+		if !s.dayMatches(t) {
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, time.UTC)
+			continue
+		}
+		if s.hour&(1<<uint(t.Hour())) == 0 {
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, time.UTC)
+			continue
+		}
+		if s.minute&(1<<uint(t.Minute())) == 0 {
+			t = t.Add(time.Minute)
+			continue
+		}
+		return t
+	}
+	return time.Time{}
+}
+
+// dayMatches aplica la regla clásica de cron: si se restringen día del mes y día de la semana, basta con que cumpla uno.
+func (s *Schedule) dayMatches(t time.Time) bool {
+	domMatch := s.dom&(1<<uint(t.Day())) != 0
+	dowMatch := s.dow&(1<<uint(t.Weekday())) != 0
+	switch {
+	case s.domAny && s.dowAny:
+		return true
+	case s.domAny:
+		return dowMatch
+	case s.dowAny:
+		return domMatch
+	default:
+		return domMatch || dowMatch
+	}
+}
@@ -0,0 +1,100 @@
+package cron
+
+import (
+	"testing"
+	"time"
+)
+
+// bits arma la máscara con los valores dados.
+func bits(values ...int) uint64 {
+	var mask uint64
+	for _, v := range values {
+		mask |= 1 << uint(v)
+	}
+	return mask
+}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		expr   string
+		minute uint64
+		dom    uint64
+		dow    uint64
+		err    bool
+	}{
+		{expr: "*/15 * * * *", minute: bits(0, 15, 30, 45)},
+		{expr: "0-30/10 * * * *", minute: bits(0, 10, 20, 30)},
+		{expr: "5/15 * * * *", minute: bits(5, 20, 35, 50)},
+		{expr: "0,30 * 1,15 * *", minute: bits(0, 30), dom: bits(1, 15)},
+		{expr: "0 * 10-12 * *", minute: bits(0), dom: bits(10, 11, 12)},
+		{expr: "0 * * * 7", minute: bits(0), dow: bits(0)},
+		{expr: "0 * * * 5-7", minute: bits(0), dow: bits(5, 6, 0)},
+		{expr: "0 * * * 1-7/2", minute: bits(0), dow: bits(1, 3, 5, 0)},
+		{expr: "0 * * * 2-7/2", minute: bits(0), dow: bits(2, 4, 6)},
+		{expr: "0 * * * 0-7/2", minute: bits(0), dow: bits(0, 2, 4, 6)},
+		{expr: "@weekly", minute: bits(0), dow: bits(0)},
+		{expr: "60 * * * *", err: true},
+		{expr: "* * 0 * *", err: true},
+		{expr: "* * * * 8", err: true},
+		{expr: "*/0 * * * *", err: true},
+		{expr: "5-1 * * * *", err: true},
+		{expr: "a * * * *", err: true},
+		{expr: "* * * *", err: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			schedule, err := Parse(tt.expr)
+			if tt.err {
+				if err == nil {
+					t.Fatalf("Parse(%q) no falló", tt.expr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Parse(%q): %v", tt.expr, err)
+			}
+			if schedule.minute != tt.minute {
+				t.Fatalf("minutos = %b, se esperaba %b", schedule.minute, tt.minute)
+			}
+			if tt.dom != 0 && schedule.dom != tt.dom {
+				t.Fatalf("días del mes = %b, se esperaba %b", schedule.dom, tt.dom)
+			}
+			if tt.dow != 0 && schedule.dow != tt.dow {
+				t.Fatalf("días de la semana = %b, se esperaba %b", schedule.dow, tt.dow)
+			}
+		})
+	}
+}
+
+func TestNext(t *testing.T) {
+	at := func(y int, m time.Month, d, h, min int) time.Time { return time.Date(y, m, d, h, min, 0, 0, time.UTC) }
+	tests := []struct {
+		name  string
+		expr  string
+		after time.Time
+		want  time.Time
+	}{
+		{name: "estrictamente posterior", expr: "@daily", after: at(2024, 1, 1, 0, 0), want: at(2024, 1, 2, 0, 0)},
+		{name: "fin de año", expr: "@hourly", after: at(2023, 12, 31, 23, 59), want: at(2024, 1, 1, 0, 0)},
+		{name: "primero del mes siguiente", expr: "@monthly", after: at(2024, 1, 31, 10, 0), want: at(2024, 2, 1, 0, 0)},
+		{name: "saltea meses de 30 días", expr: "0 0 31 * *", after: at(2024, 4, 15, 0, 0), want: at(2024, 5, 31, 0, 0)},
+		{name: "29 de febrero", expr: "0 0 29 2 *", after: at(2023, 3, 1, 0, 0), want: at(2024, 2, 29, 0, 0)},
+		{name: "días hábiles", expr: "30 9 * * 1-5", after: at(2024, 3, 1, 10, 0), want: at(2024, 3, 4, 9, 30)},
+		{name: "domingo como 7", expr: "0 12 * * 7", after: at(2024, 3, 1, 0, 0), want: at(2024, 3, 3, 12, 0)},
+		{name: "el paso no cae en el domingo", expr: "0 0 * * 2-7/2", after: at(2024, 3, 2, 12, 0), want: at(2024, 3, 5, 0, 0)},
+		{name: "día del mes o de la semana", expr: "0 0 13 * 5", after: at(2024, 9, 1, 0, 0), want: at(2024, 9, 6, 0, 0)},
+		{name: "solo día del mes", expr: "0 0 13 * *", after: at(2024, 9, 1, 0, 0), want: at(2024, 9, 13, 0, 0)},
+		{name: "fecha imposible", expr: "0 0 30 2 *", after: at(2024, 1, 1, 0, 0), want: time.Time{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			schedule, err := Parse(tt.expr)
+			if err != nil {
+				t.Fatalf("Parse(%q): %v", tt.expr, err)
+			}
+			if got := schedule.Next(tt.after); !got.Equal(tt.want) {
+				t.Fatalf("Next(%v) = %v, se esperaba %v", tt.after, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,37 @@
+package database
+
+import (
+	"database/sql"
+
+	"gorm.io/gorm"
+)
+
+// UnitOfWork ejecuta un bloque de operaciones dentro de una única transacción de base de datos.
+// Los repositorios se unen a la transacción con su método WithTx(tx), así todo se confirma o se revierte junto.
+type UnitOfWork interface {
+	Do(fn func(tx *gorm.DB) error) error
+	// Read ejecuta fn en una transacción de solo lectura REPEATABLE READ: todas las consultas ven la misma foto
+	// de la base, aunque otra transacción confirme cambios en el medio.
+	Read(fn func(tx *gorm.DB) error) error
+}
+
+// GormUnitOfWork implementa UnitOfWork sobre *gorm.DB.
+type GormUnitOfWork struct {
+	DB *gorm.DB
+}
+
+// NewUnitOfWork crea una nueva instancia de GormUnitOfWork.
+func NewUnitOfWork(db *gorm.DB) UnitOfWork {
+	return &GormUnitOfWork{DB: db}
+}
+
+// Do abre una transacción, ejecuta fn y hace commit si fn no devuelve error.
+// Si fn devuelve error (o entra en pánico) se hace rollback de todo lo escrito.
+func (u *GormUnitOfWork) Do(fn func(tx *gorm.DB) error) error {
+	return u.DB.Transaction(fn)
+}
+
+// Read abre la transacción de solo lectura con aislamiento REPEATABLE READ y ejecuta fn.
+func (u *GormUnitOfWork) Read(fn func(tx *gorm.DB) error) error {
+	return u.DB.Transaction(fn, &sql.TxOptions{Isolation: sql.LevelRepeatableRead, ReadOnly: true})
+}
@@ -0,0 +1,39 @@
+// Package dates centraliza los formatos de fecha que aceptan los query params de la API.
+package dates
+
+import (
+	"fmt"
+	"time"
+)
+
+// dayFormat es el formato corto que usan los endpoints desde el principio (dd-mm-yyyy).
+const dayFormat = "02-01-2006"
+
+// Parse convierte una fecha en dd-mm-yyyy o RFC3339. Las fechas dd-mm-yyyy se toman en UTC a las 00:00.
+func Parse(date string) (time.Time, error) {
+	parsed, _, err := parse(date)
+	return parsed, err
+}
+
+// ParseEnd es como Parse pero para el final de un rango exclusivo: una fecha dd-mm-yyyy
+// devuelve las 00:00 del día siguiente para que el día completo quede incluido.
+func ParseEnd(date string) (time.Time, error) {
+	parsed, wholeDay, err := parse(date)
+	if err != nil {
+		return time.Time{}, err
+	}
+	if wholeDay {
+		parsed = parsed.AddDate(0, 0, 1)
+	}
+	return parsed, nil
+}
+
+func parse(date string) (time.Time, bool, error) {
+	if parsed, err := time.Parse(dayFormat, date); err == nil {
+		return parsed, true, nil
+	}
+	if parsed, err := time.Parse(time.RFC3339, date); err == nil {
+		return parsed, false, nil
+	}
+	return time.Time{}, false, fmt.Errorf("la fecha no cumple con los formatos válidos: dd-mm-yyyy o RFC3339")
+}